@@ -25,6 +25,8 @@ func main() {
 	msg.FromUserID = uint64(math.Round(rand.Float64() * 1000000))
 	msg.FromUserID = 1
 	msg.Type = model.MessageTypeAuth
+	// 认证帧携带登录接口返回的访问令牌
+	msg.Data = []byte(os.Getenv("WSIM_TOKEN"))
 	data := model.Encode(msg)
 	fmt.Printf("发送认证消息，数据长度: %d, 内容: %+v\n", len(data), msg)
 	// n, err := conn.Write(data)
@@ -46,6 +48,11 @@ func main() {
 				fmt.Println("Failed to read from server: ", err)
 				return
 			}
+			// 服务端既有纯文本回复，也有编码后的帧；长度恰好吻合时按帧打印
+			if frame := model.Decode(buf[:n]); n >= model.HeaderLen && model.HeaderLen+len(frame.Data) == n {
+				fmt.Printf("[type=%d] %d -> %d: %s\n", frame.Type, frame.FromUserID, frame.ToUserID, string(frame.Data))
+				continue
+			}
 			fmt.Println(string(buf[:n]))
 		}
 	}()
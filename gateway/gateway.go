@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"wsim/gateway/model"
+	"wsim/pkg/postgresql"
 
 	"github.com/cloudwego/netpoll"
 )
@@ -17,10 +18,36 @@ func main() {
 		onRequest,
 		netpoll.WithOnPrepare(onPrepare),
 		netpoll.WithOnConnect(onConnect),
+		netpoll.WithOnDisconnect(onDisconnect),
 		netpoll.WithReadTimeout(time.Second*30),
 	)
 	// connManager := netpoll.NewConnectionManager()
 	model.NewUsers()
+	postgresql.InitPostgreSQL()
+	if err := model.InitBlockList(context.Background(), postgresql.GetDB()); err != nil {
+		log.Fatalf("初始化拉黑名单失败: %v", err)
+	}
+	if err := model.InitSessions(context.Background(), postgresql.GetDB()); err != nil {
+		log.Fatalf("初始化会话校验失败: %v", err)
+	}
+	if err := model.InitProfileEvents(context.Background(), postgresql.GetDB()); err != nil {
+		log.Fatalf("初始化资料推送失败: %v", err)
+	}
+	if err := model.InitPresence(context.Background(), postgresql.GetDB()); err != nil {
+		log.Fatalf("初始化在线状态失败: %v", err)
+	}
+	if err := model.InitFanout(context.Background(), postgresql.GetDB()); err != nil {
+		log.Fatalf("初始化网关间投递失败: %v", err)
+	}
+	if err := model.InitChat(postgresql.GetDB()); err != nil {
+		log.Fatalf("初始化消息存储失败: %v", err)
+	}
+	if err := model.InitCalls(context.Background(), postgresql.GetDB()); err != nil {
+		log.Fatalf("初始化通话信令失败: %v", err)
+	}
+	if err := model.InitNotify(context.Background(), postgresql.GetDB()); err != nil {
+		log.Fatalf("初始化离线推送失败: %v", err)
+	}
 	// 目前不需要多网关机制
 	// model.InitSend()
 	// 修改为监听所有接口，支持外部连接
@@ -78,79 +105,111 @@ func onRequest(ctx context.Context, conn netpoll.Connection) error {
 	switch msg.Type {
 	case model.MessageTypeAuth:
 		if !auth.IsAuth {
-			// 读取首包
+			// 读取首包：Data 为用户服务签发的访问令牌，身份以令牌为准
 			fmt.Printf("收到登陆请求: %+v\n", msg)
-			data := fmt.Sprintf("%d 登陆成功", msg.FromUserID)
+			claims, err := model.Authenticate(ctx, string(msg.Data))
+			if err != nil || (msg.FromUserID != 0 && msg.FromUserID != uint64(claims.UserID)) {
+				fmt.Println("auth error: ", err)
+				conn.Writer().WriteString("认证失败")
+				conn.Writer().Flush()
+				conn.Close()
+				return nil
+			}
+			data := fmt.Sprintf("%d 登陆成功", claims.UserID)
 
 			// 回复客户端
 			conn.Writer().WriteString(data)
 			conn.Writer().Flush()
 			auth.IsAuth = true
-			auth.UserID = msg.FromUserID
+			auth.UserID = uint64(claims.UserID)
 			ctx = context.WithValue(ctx, "auth", auth)
-
+			model.TrackSession(conn, claims)
 		}
-		// 保存该用户登陆状态
-		// 首先判断用户是否存在
-		if model.Users[msg.FromUserID] == nil {
-			// 不存在需要创建用户
-			model.Users[msg.FromUserID] = &model.User{
-				UserID: msg.FromUserID,
-				Conn:   conn,
-				IsAuth: true,
-			}
-			conn.Writer().WriteString("用户登陆成功")
-			conn.Writer().Flush()
-			return nil
-		}
-		// 首先判断是不是已经登陆
-		if model.Users[msg.FromUserID].IsAuth {
+		// 保存该用户登陆状态：同一用户可多端登录，每条连接对应一个设备
+		if !model.AddConn(auth.UserID, conn) {
+			// 该连接已登记过
 			conn.Writer().WriteString("用户已登陆")
 			conn.Writer().Flush()
 			return nil
 		}
+		model.PresenceConnect(ctx, auth)
+		conn.Writer().WriteString("用户登陆成功")
+		conn.Writer().Flush()
+		return nil
 
-		model.Users[msg.FromUserID] = &model.User{
-			UserID: msg.FromUserID,
-			Conn:   conn,
-			IsAuth: true,
+	case model.MessageTypeText, model.MessageTypeImage, model.MessageTypeVoice, model.MessageTypeVideo,
+		model.MessageTypeFile, model.MessageTypeLocation, model.MessageTypeContact, model.MessageTypeSticker,
+		model.MessageTypeReply, model.MessageTypeCompose:
+		// 未认证连接上的聊天消息一律丢弃，富消息须经 sendChat 校验内容与媒体引用；
+		// 网关间的投递走 Fanout，不经过客户端连接
+		if !auth.IsAuth {
+			return nil
 		}
-
-	case model.MessageTypeText:
-		fmt.Println("收到文本消息: ", msg)
-		data := fmt.Sprintf("%d 发送了消息: %s", msg.FromUserID, string(msg.Data))
-
-		// 如果消息是发给其他用户的，则需要转发给其他用户
-		if msg.ToUserID != 0 {
-			if receiver, ok := model.Users[msg.ToUserID]; ok {
-				n, err := receiver.Conn.Writer().WriteString(string(data))
-				if err != nil {
-					fmt.Println("write string error: ", err)
-					conn.Writer().WriteString("write string error")
-					conn.Writer().Flush()
-					return err
-				}
-				fmt.Println("write string success: ", n)
-				receiver.Conn.Writer().Flush()
-			} else {
-				// 如果接收者不存在，则需要转发给gateway
-				fmt.Println("receiver not found, forwarding to gateway")
-				model.SendMessage(msg)
-				// fmt.Println("receiver not found")
-				// conn.Writer().WriteString("receiver not found")
-				// conn.Writer().Flush()
-				return nil
-			}
+		fmt.Println("收到聊天消息: ", msg)
+		return sendChat(ctx, conn, auth, msg)
+	case model.MessageTypeSignal:
+		// 信号是瞬时的：不回执、不存储、不重试，任何一步失败都直接丢弃
+		if !auth.IsAuth || msg.ToUserID == 0 || !model.ValidSignal(msg.Data) {
+			return nil
+		}
+		// 发送方以连接认证的身份为准，限流与拉黑都按它判断
+		msg.FromUserID = auth.UserID
+		if !model.AllowSignal(msg.FromUserID) || model.IsBlocked(msg.ToUserID, msg.FromUserID) {
+			return nil
+		}
+		// 与文本消息一样投递到接收方在各网关的全部在线设备
+		model.Fanout(ctx, []uint64{msg.ToUserID}, msg)
+		return nil
+	case model.MessageTypeReadReceipt:
+		if !auth.IsAuth {
+			return nil
+		}
+		if err := model.HandleReadReceipt(ctx, auth, msg.Data); err != nil {
+			fmt.Println("read receipt error: ", err)
+		}
+		return nil
+	case model.MessageTypeMessageOp:
+		if !auth.IsAuth {
+			return nil
+		}
+		if err := model.HandleMessageOp(ctx, auth, msg.Data); err != nil {
+			fmt.Println("message op error: ", err)
+			model.WriteResult(conn, msg, model.ResultRejected)
+		}
+		return nil
+	case model.MessageTypeReaction:
+		if !auth.IsAuth {
+			return nil
+		}
+		if err := model.HandleReaction(ctx, auth, msg.Data); err != nil {
+			fmt.Println("reaction error: ", err)
+			model.WriteResult(conn, msg, model.ResultRejected)
 		}
 		return nil
-	case model.MessageTypeImage:
-		fmt.Printf("收到: %s\n", string(msg.Data))
+	case model.MessageTypeCall:
+		if !auth.IsAuth {
+			return nil
+		}
+		if err := model.HandleCall(ctx, auth, conn, msg.Data); err != nil {
+			fmt.Println("call signal error: ", err)
+			model.WriteResult(conn, msg, model.ResultRejected)
+		}
 		return nil
-	case model.MessageTypeVoice:
-		fmt.Printf("收到: %s\n", string(msg.Data))
+	case model.MessageTypePresenceSubscribe:
+		if !auth.IsAuth {
+			return nil
+		}
+		if err := model.SubscribePresence(ctx, auth, conn, msg.Data); err != nil {
+			fmt.Println("subscribe presence error: ", err)
+		}
 		return nil
-	case model.MessageTypeVideo:
-		fmt.Printf("收到: %s\n", string(msg.Data))
+	case model.MessageTypePresence:
+		if !auth.IsAuth {
+			return nil
+		}
+		if err := model.ReportPresence(ctx, auth, msg.Data); err != nil {
+			fmt.Println("report presence error: ", err)
+		}
 		return nil
 	}
 
@@ -170,3 +229,62 @@ func onConnect(ctx context.Context, conn netpoll.Connection) context.Context {
 	fmt.Println("onConnect: ", auth)
 	return context.WithValue(ctx, "auth", auth)
 }
+
+func onDisconnect(ctx context.Context, conn netpoll.Connection) {
+	auth, ok := ctx.Value("auth").(*model.Auth)
+	if !ok || !auth.IsAuth {
+		return
+	}
+	fmt.Println("onDisconnect: ", auth)
+	model.UntrackSession(conn)
+	model.RemoveConn(auth.UserID, conn)
+	model.PresenceDisconnect(context.Background(), auth, conn)
+}
+
+// sendChat 存储并投递一条客户端发来的聊天消息
+func sendChat(ctx context.Context, conn netpoll.Connection, auth *model.Auth, msg model.Message) error {
+	if msg.ToUserID == 0 {
+		return nil
+	}
+	// 发送方以连接认证的身份为准
+	msg.FromUserID = auth.UserID
+	// 发送方已被接收方拉黑：丢弃，不转发也不存储；回复中性的"未送达"，不暴露拉黑
+	if model.IsBlocked(msg.ToUserID, msg.FromUserID) {
+		model.WriteResult(conn, msg, model.ResultNotDelivered)
+		return nil
+	}
+	draft, err := model.DraftFromFrame(msg)
+	if err != nil {
+		fmt.Println("parse message error: ", err)
+		model.WriteResult(conn, msg, model.ResultRejected)
+		return nil
+	}
+	out, stored, err := model.StoreDirect(ctx, msg, draft)
+	if err != nil {
+		fmt.Println("store message error: ", err)
+		model.WriteResult(conn, msg, model.ResultNotDelivered)
+		return nil
+	}
+	model.WriteResultDetail(conn, msg, model.Result{
+		Status:         model.ResultSent,
+		MessageID:      stored.MessageID,
+		ConversationID: stored.ConversationID,
+		Seq:            stored.Seq,
+	})
+
+	out, notify := model.ApplyNotifyPrefs(ctx, msg.ToUserID, stored, out)
+	if stored.ThreadRootID != 0 {
+		// 话题内回复不作为顶层消息投递，只推话题摘要；离线提醒与顶层消息一致
+		if err := model.PushThreadUpdate(ctx, stored); err != nil {
+			fmt.Println("push thread update error: ", err)
+		}
+	} else {
+		// 投递到接收方在各网关的全部在线设备
+		model.Fanout(ctx, []uint64{msg.ToUserID}, out)
+	}
+	// 哪里都不在线且需要提醒时发离线推送
+	if notify {
+		model.NotifyOffline(ctx, msg.ToUserID, stored)
+	}
+	return nil
+}
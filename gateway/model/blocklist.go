@@ -0,0 +1,140 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"wsim/pkg/pubsub"
+	"wsim/user/api/user/infra/repository"
+
+	"gorm.io/gorm"
+)
+
+// blockListIdle 缓存项多久未被查询即清理
+const blockListIdle = 10 * time.Minute
+
+// blockCache 拉黑名单本地缓存：接收方 -> 被其拉黑的发送方集合。
+// 首次查询时从库加载，用户服务变更后通过 pubsub 通知失效，长时间未查询的项定期清理。
+// gen 每次失效自增，避免加载期间到达的失效通知被旧数据覆盖。
+type blockCache struct {
+	mu    sync.Mutex
+	gen   uint64
+	m     map[uint64]*blockEntry
+	swept time.Time
+	load  func(userID uint64) (map[uint64]struct{}, error)
+	now   func() time.Time
+}
+
+type blockEntry struct {
+	set  map[uint64]struct{}
+	used time.Time
+}
+
+func newBlockCache(load func(userID uint64) (map[uint64]struct{}, error)) *blockCache {
+	return &blockCache{m: make(map[uint64]*blockEntry), swept: time.Now(), load: load, now: time.Now}
+}
+
+var (
+	blockList *blockCache
+	blockRepo *repository.PostgresBlockRepository
+)
+
+// InitBlockList 初始化拉黑名单缓存并订阅变更通知
+func InitBlockList(ctx context.Context, db *gorm.DB) error {
+	repo, err := repository.NewPostgresBlockRepository(db)
+	if err != nil {
+		return err
+	}
+	blockRepo = repo
+	blockList = newBlockCache(func(userID uint64) (map[uint64]struct{}, error) {
+		bs, err := blockRepo.ListBlocked(context.Background(), uint(userID))
+		if err != nil {
+			return nil, err
+		}
+		set := make(map[uint64]struct{}, len(bs))
+		for _, b := range bs {
+			set[uint64(b.BlockedID)] = struct{}{}
+		}
+		return set, nil
+	})
+	go pubsub.Subscribe(ctx, pubsub.ChannelBlockList, func(payload string) {
+		userID, err := strconv.ParseUint(payload, 10, 64)
+		if err != nil {
+			return
+		}
+		blockList.invalidate(userID)
+	}, func() {
+		// 断线期间可能漏掉通知，重连后整体失效
+		blockList.reset()
+	})
+	return nil
+}
+
+// IsBlocked receiverID 是否拉黑了 senderID；查询失败时放行，不影响正常投递
+func IsBlocked(receiverID, senderID uint64) bool {
+	if blockList == nil {
+		return false
+	}
+	blocked, err := blockList.isBlocked(receiverID, senderID)
+	if err != nil {
+		fmt.Println("load block list error: ", err)
+		return false
+	}
+	return blocked
+}
+
+func (c *blockCache) isBlocked(receiverID, senderID uint64) (bool, error) {
+	now := c.now()
+	c.mu.Lock()
+	c.sweep(now)
+	e, ok := c.m[receiverID]
+	if ok {
+		e.used = now
+	}
+	gen := c.gen
+	c.mu.Unlock()
+	if !ok {
+		set, err := c.load(receiverID)
+		if err != nil {
+			return false, err
+		}
+		e = &blockEntry{set: set, used: now}
+		c.mu.Lock()
+		if c.gen == gen {
+			c.m[receiverID] = e
+		}
+		c.mu.Unlock()
+	}
+	_, blocked := e.set[senderID]
+	return blocked, nil
+}
+
+func (c *blockCache) invalidate(userID uint64) {
+	c.mu.Lock()
+	c.gen++
+	delete(c.m, userID)
+	c.mu.Unlock()
+}
+
+func (c *blockCache) reset() {
+	c.mu.Lock()
+	c.gen++
+	c.m = make(map[uint64]*blockEntry)
+	c.mu.Unlock()
+}
+
+// sweep 定期清理闲置项，避免缓存随查询过的用户无限增长；调用方持有锁
+func (c *blockCache) sweep(now time.Time) {
+	if now.Sub(c.swept) < time.Minute {
+		return
+	}
+	c.swept = now
+	for id, e := range c.m {
+		if now.Sub(e.used) > blockListIdle {
+			delete(c.m, id)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeBlocks 接收方 -> 被拉黑的发送方，记录每个用户的加载次数
+type fakeBlocks struct {
+	blocked map[uint64][]uint64
+	loads   map[uint64]int
+}
+
+func (f *fakeBlocks) load(userID uint64) (map[uint64]struct{}, error) {
+	f.loads[userID]++
+	set := make(map[uint64]struct{})
+	for _, id := range f.blocked[userID] {
+		set[id] = struct{}{}
+	}
+	return set, nil
+}
+
+func TestBlockCache(t *testing.T) {
+	clock := time.Now()
+	f := &fakeBlocks{blocked: map[uint64][]uint64{1: {2}}, loads: make(map[uint64]int)}
+	c := newBlockCache(f.load)
+	c.now = func() time.Time { return clock }
+	check := func(receiver, sender uint64, want bool) {
+		t.Helper()
+		if got, err := c.isBlocked(receiver, sender); err != nil || got != want {
+			t.Fatalf("isBlocked(%d, %d) = %v %v, want %v", receiver, sender, got, err, want)
+		}
+	}
+
+	// 只拦接收方拉黑的发送方，方向相反不受影响
+	check(1, 2, true)
+	check(1, 3, false)
+	check(2, 1, false)
+	if f.loads[1] != 1 {
+		t.Fatalf("loads: %v", f.loads)
+	}
+
+	// 失效后重新加载，看到最新名单
+	f.blocked[1] = []uint64{3}
+	check(1, 2, true)
+	c.invalidate(1)
+	check(1, 2, false)
+	check(1, 3, true)
+	if f.loads[1] != 2 {
+		t.Fatalf("loads after invalidate: %v", f.loads)
+	}
+	c.reset()
+	check(2, 1, false)
+	if f.loads[2] != 2 {
+		t.Fatalf("loads after reset: %v", f.loads)
+	}
+
+	// 闲置项被清理，常用项保留
+	clock = clock.Add(blockListIdle / 2)
+	check(1, 3, true)
+	clock = clock.Add(blockListIdle/2 + time.Minute)
+	check(1, 3, true)
+	if _, ok := c.m[2]; ok || len(c.m) != 1 {
+		t.Fatalf("idle entry not swept: %v", c.m)
+	}
+}
@@ -0,0 +1,122 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"wsim/user/api/call/dto"
+	"wsim/user/api/call/infra/repository"
+	"wsim/user/api/call/usecase"
+
+	"github.com/cloudwego/netpoll"
+	"gorm.io/gorm"
+)
+
+// 振铃超时扫描间隔
+const callSweepInterval = time.Second
+
+var callSvc *usecase.CallService
+
+// InitCalls 初始化通话信令并启动振铃超时扫描；须在 InitChat 之后调用（通话记录写入消息历史）
+func InitCalls(ctx context.Context, db *gorm.DB) error {
+	if messageSvc == nil {
+		return errors.New("chat not initialized")
+	}
+	repo, err := repository.NewPostgresCallRepository(db)
+	if err != nil {
+		return err
+	}
+	callSvc = usecase.NewCallService(repo, messageSvc)
+	go func() {
+		ticker := time.NewTicker(callSweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				notices, err := callSvc.ExpireDue(ctx)
+				if err != nil {
+					fmt.Println("call timeout error: ", err)
+				}
+				deliverCallNotices(ctx, notices)
+			}
+		}
+	}()
+	return nil
+}
+
+// HandleCall 处理客户端上报的通话动作。invite 成功后把带 call_id 的帧回给发起连接，
+// 其余状态变化推给全部参与者的在线设备，SDP/ICE 只转给目标参与者
+func HandleCall(ctx context.Context, auth *Auth, conn netpoll.Connection, data []byte) error {
+	var req dto.CallSignal
+	if err := json.Unmarshal(data, &req); err != nil {
+		return err
+	}
+	uid := uint(auth.UserID)
+	var (
+		notices []usecase.Notice
+		err     error
+	)
+	switch req.Action {
+	case usecase.ActionInvite:
+		var invitees []uint
+		for _, id := range req.Invitees {
+			// 拉黑了主叫的被叫不参与通话，也不会留下通话记录
+			if !IsBlocked(uint64(id), auth.UserID) {
+				invitees = append(invitees, id)
+			}
+		}
+		if len(invitees) == 0 {
+			return errors.New("no reachable invitees")
+		}
+		call, ns, err := callSvc.Invite(ctx, uid, req.ConversationID, req.Media, invitees)
+		if err != nil {
+			return err
+		}
+		payload, _ := json.Marshal(dto.FromNotice(usecase.Notice{Action: usecase.ActionInvite, From: uid, Call: call}))
+		if err := WriteFrame(conn, Message{FromUserID: auth.UserID, Type: MessageTypeCall, Data: payload}); err != nil {
+			fmt.Println("write call frame error: ", err)
+		}
+		notices = ns
+	case usecase.ActionRinging:
+		notices, err = callSvc.Ring(ctx, uid, req.CallID)
+	case usecase.ActionAccept:
+		notices, err = callSvc.Accept(ctx, uid, req.CallID)
+	case usecase.ActionReject:
+		notices, err = callSvc.Reject(ctx, uid, req.CallID)
+	case usecase.ActionHangup:
+		notices, err = callSvc.Hangup(ctx, uid, req.CallID)
+	case usecase.ActionOffer, usecase.ActionAnswer, usecase.ActionICE:
+		var n *usecase.Notice
+		n, err = callSvc.Relay(ctx, uid, req.CallID, req.To, req.Action, req.SDP, req.Candidate)
+		if n != nil {
+			notices = []usecase.Notice{*n}
+		}
+	default:
+		return fmt.Errorf("unknown call action: %q", req.Action)
+	}
+	if err != nil {
+		return err
+	}
+	deliverCallNotices(ctx, notices)
+	return nil
+}
+
+func deliverCallNotices(ctx context.Context, notices []usecase.Notice) {
+	for _, n := range notices {
+		payload, _ := json.Marshal(dto.FromNotice(n))
+		ids := make([]uint64, 0, len(n.To))
+		for _, id := range n.To {
+			ids = append(ids, uint64(id))
+		}
+		Fanout(ctx, ids, Message{
+			FromUserID: uint64(n.From),
+			Type:       MessageTypeCall,
+			Data:       payload,
+		})
+	}
+}
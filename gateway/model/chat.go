@@ -0,0 +1,241 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	mediarepo "wsim/user/api/media/infra/repository"
+	mediausecase "wsim/user/api/media/usecase"
+	"wsim/user/api/message/domain"
+	"wsim/user/api/message/dto"
+	"wsim/user/api/message/infra/repository"
+	"wsim/user/api/message/usecase"
+
+	"gorm.io/gorm"
+)
+
+var messageSvc *usecase.MessageService
+
+// InitChat 初始化消息存储；须在 InitPresence 之后调用（展开 @online）
+func InitChat(db *gorm.DB) error {
+	repo, err := repository.NewPostgresMessageRepository(db)
+	if err != nil {
+		return err
+	}
+	reactions, err := repository.NewPostgresReactionRepository(db)
+	if err != nil {
+		return err
+	}
+	media, err := mediarepo.NewPostgresMediaRepository(db)
+	if err != nil {
+		return err
+	}
+	messageSvc = usecase.NewMessageService(repo, repo, reactions, mediausecase.NewAccess(media, repo), presenceSvc)
+	return nil
+}
+
+// StoreDirect 存储一条单聊消息，返回投递给接收方的帧（Data 为 dto.Message JSON）及存储结果
+func StoreDirect(ctx context.Context, msg Message, draft domain.Draft) (Message, *dto.Message, error) {
+	m, err := messageSvc.SendDirect(ctx, uint(msg.FromUserID), uint(msg.ToUserID), draft)
+	if err != nil {
+		return Message{}, nil, err
+	}
+	stored := dto.FromMessage(m)
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return Message{}, nil, err
+	}
+	return Message{
+		FromUserID: msg.FromUserID,
+		ToUserID:   msg.ToUserID,
+		Type:       MessageType(draft.Type),
+		Data:       data,
+	}, &stored, nil
+}
+
+// DraftFromFrame 把客户端发来的聊天帧转为待存储的消息
+func DraftFromFrame(msg Message) (domain.Draft, error) {
+	if msg.Type == MessageTypeReply || msg.Type == MessageTypeCompose {
+		return ParseReply(msg.Data)
+	}
+	return domain.Draft{Type: msg.Type.Int(), Content: string(msg.Data)}, nil
+}
+
+// ParseReply 解析回复帧，得到待存储的消息
+func ParseReply(data []byte) (domain.Draft, error) {
+	var req dto.Reply
+	if err := json.Unmarshal(data, &req); err != nil {
+		return domain.Draft{}, err
+	}
+	if req.Type == 0 {
+		req.Type = MessageTypeText.Int()
+	}
+	if len(req.Payload) > 0 {
+		req.Content = string(req.Payload)
+	}
+	if !IsChatType(MessageType(req.Type)) {
+		return domain.Draft{}, fmt.Errorf("unsupported reply type: %d", req.Type)
+	}
+	return domain.Draft{
+		Type:      req.Type,
+		Content:   req.Content,
+		ReplyToID: req.ReplyTo,
+		InThread:  req.InThread,
+		Mentions:  dto.ToMentions(req.Mentions),
+	}, nil
+}
+
+// IsChatType 可作为聊天内容存储、回复的帧类型：文本及各类富消息
+func IsChatType(t MessageType) bool {
+	return domain.IsContentType(t.Int())
+}
+
+// PushThreadUpdate 话题有新回复：给会话全部成员推摘要，而不是把回复当作顶层消息投递
+func PushThreadUpdate(ctx context.Context, reply *dto.Message) error {
+	root, err := messageSvc.ThreadRoot(ctx, reply.ThreadRootID)
+	if err != nil {
+		return err
+	}
+	members, err := messageSvc.Members(ctx, reply.ConversationID)
+	if err != nil {
+		return err
+	}
+	payload, _ := json.Marshal(dto.ThreadUpdate{
+		ConversationID: reply.ConversationID,
+		RootID:         root.ID,
+		ReplyCount:     root.ThreadReplyCount,
+		Reply:          *reply,
+	})
+	ids := make([]uint64, 0, len(members))
+	for _, m := range members {
+		ids = append(ids, uint64(m.UserID))
+	}
+	Fanout(ctx, ids, Message{
+		FromUserID: uint64(reply.SenderID),
+		Type:       MessageTypeThreadUpdate,
+		Data:       payload,
+	})
+	return nil
+}
+
+// HandleReadReceipt 推进已读游标，并把回执转发给会话全部成员的在线设备
+// （对方据此展示已读，本人其他设备据此同步未读数）
+func HandleReadReceipt(ctx context.Context, auth *Auth, data []byte) error {
+	var req dto.ReadReceipt
+	if err := json.Unmarshal(data, &req); err != nil {
+		return err
+	}
+	readSeq, advanced, members, err := messageSvc.MarkRead(ctx, uint(auth.UserID), req.ConversationID, req.Seq)
+	if err != nil || !advanced {
+		return err
+	}
+	payload, _ := json.Marshal(dto.ReadReceipt{
+		ConversationID: req.ConversationID,
+		UserID:         uint(auth.UserID),
+		Seq:            readSeq,
+	})
+	ids := make([]uint64, 0, len(members))
+	for _, m := range members {
+		ids = append(ids, uint64(m.UserID))
+	}
+	Fanout(ctx, ids, Message{
+		FromUserID: auth.UserID,
+		Type:       MessageTypeReadReceipt,
+		Data:       payload,
+	})
+	return nil
+}
+
+// HandleMessageOp 处理撤回/编辑/删除控制帧：校验权限并落库后转发。
+// 撤回、编辑转发给会话全部成员；仅自己删除只同步到本人的其他设备
+func HandleMessageOp(ctx context.Context, auth *Auth, data []byte) error {
+	var req dto.MessageOp
+	if err := json.Unmarshal(data, &req); err != nil {
+		return err
+	}
+	uid := uint(auth.UserID)
+	var (
+		m       *domain.Message
+		members []domain.Member
+		err     error
+	)
+	switch domain.MessageOp(req.Op) {
+	case domain.OpRecall:
+		m, members, err = messageSvc.Recall(ctx, uid, req.MessageID)
+	case domain.OpEdit:
+		m, members, err = messageSvc.Edit(ctx, uid, req.MessageID, req.Content)
+	case domain.OpDelete:
+		m, err = messageSvc.DeleteForMe(ctx, uid, req.MessageID)
+		members = []domain.Member{{UserID: uid}}
+	default:
+		return fmt.Errorf("unknown message op: %q", req.Op)
+	}
+	if err != nil {
+		return err
+	}
+
+	op := dto.MessageOp{
+		Op:             req.Op,
+		MessageID:      m.ID,
+		ConversationID: m.ConversationID,
+		Seq:            m.Seq,
+		OperatorID:     uid,
+	}
+	switch domain.MessageOp(req.Op) {
+	case domain.OpRecall:
+		op.At = m.RecalledAt
+	case domain.OpEdit:
+		op.Content = m.Content
+		op.At = m.EditedAt
+	}
+	payload, _ := json.Marshal(op)
+	ids := make([]uint64, 0, len(members))
+	for _, mb := range members {
+		ids = append(ids, uint64(mb.UserID))
+	}
+	Fanout(ctx, ids, Message{
+		FromUserID: auth.UserID,
+		Type:       MessageTypeMessageOp,
+		Data:       payload,
+	})
+	return nil
+}
+
+// HandleReaction 添加/取消表情回应，变化后把增量推给会话全部成员
+func HandleReaction(ctx context.Context, auth *Auth, data []byte) error {
+	var req dto.ReactionEvent
+	if err := json.Unmarshal(data, &req); err != nil {
+		return err
+	}
+	var add bool
+	switch req.Action {
+	case "add":
+		add = true
+	case "remove":
+	default:
+		return fmt.Errorf("unknown reaction action: %q", req.Action)
+	}
+	res, err := messageSvc.React(ctx, uint(auth.UserID), req.MessageID, req.Emoji, add)
+	if err != nil || !res.Changed {
+		return err
+	}
+	payload, _ := json.Marshal(dto.ReactionEvent{
+		MessageID:      req.MessageID,
+		Emoji:          req.Emoji,
+		Action:         req.Action,
+		ConversationID: res.Message.ConversationID,
+		UserID:         uint(auth.UserID),
+		Count:          res.Count,
+	})
+	ids := make([]uint64, 0, len(res.Members))
+	for _, m := range res.Members {
+		ids = append(ids, uint64(m.UserID))
+	}
+	Fanout(ctx, ids, Message{
+		FromUserID: auth.UserID,
+		Type:       MessageTypeReaction,
+		Data:       payload,
+	})
+	return nil
+}
@@ -0,0 +1,121 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"wsim/pkg/pubsub"
+
+	"gorm.io/gorm"
+)
+
+// fanoutPayload 网关间投递的事件帧；Data 经 JSON 编码为 base64，整体需小于 NOTIFY 的 8000 字节上限
+type fanoutPayload struct {
+	Origin     string      `json:"origin"`
+	UserIDs    []uint64    `json:"user_ids"`
+	FromUserID uint64      `json:"from_user_id"`
+	Type       MessageType `json:"type"`
+	Data       []byte      `json:"data"`
+	// SpoolID 非 0 时帧过大，内容暂存在 fanout_spool 表，接收方按 ID 读取
+	SpoolID uint64 `json:"spool_id,omitempty"`
+}
+
+// NOTIFY payload 上限 8000 字节，留出余量
+const maxFanoutPayload = 7900
+
+// 暂存的大帧保留多久；各网关收到通知后立即读取，过期即可清理
+const fanoutSpoolTTL = time.Minute
+
+// FanoutSpoolModel 超过 NOTIFY 上限的事件帧（如通话 SDP）
+type FanoutSpoolModel struct {
+	ID        uint64    `gorm:"primaryKey"`
+	Payload   []byte    `gorm:"not null"`
+	CreatedAt time.Time `gorm:"not null;index"`
+}
+
+func (FanoutSpoolModel) TableName() string { return "fanout_spool" }
+
+var fanoutDB *gorm.DB
+
+// InitFanout 订阅其他网关发来的事件帧
+func InitFanout(ctx context.Context, db *gorm.DB) error {
+	if err := db.AutoMigrate(&FanoutSpoolModel{}); err != nil {
+		return err
+	}
+	fanoutDB = db
+	go pubsub.Subscribe(ctx, pubsub.ChannelDeliver, func(payload string) {
+		var p fanoutPayload
+		if err := json.Unmarshal([]byte(payload), &p); err != nil {
+			return
+		}
+		if p.Origin == GatewayID {
+			// 本网关发出的，已在本地推送过
+			return
+		}
+		if p.SpoolID != 0 {
+			var m FanoutSpoolModel
+			tx := db.WithContext(ctx).Where("id = ?", p.SpoolID).Limit(1).Find(&m)
+			if tx.Error != nil || tx.RowsAffected == 0 || json.Unmarshal(m.Payload, &p) != nil {
+				fmt.Println("fanout spool read error: ", p.SpoolID, tx.Error)
+				return
+			}
+		}
+		pushLocal(p.UserIDs, Message{FromUserID: p.FromUserID, Type: p.Type, Data: p.Data})
+	}, nil)
+	return nil
+}
+
+// Fanout 把帧投递给一组用户的全部在线设备（跨网关），聊天消息与回执、通知、通话信令等事件帧都经此投递。
+func Fanout(ctx context.Context, userIDs []uint64, msg Message) {
+	if len(userIDs) == 0 {
+		return
+	}
+	pushLocal(userIDs, msg)
+	if fanoutDB == nil {
+		return
+	}
+	data, _ := json.Marshal(fanoutPayload{
+		Origin:     GatewayID,
+		UserIDs:    userIDs,
+		FromUserID: msg.FromUserID,
+		Type:       msg.Type,
+		Data:       msg.Data,
+	})
+	if len(data) > maxFanoutPayload {
+		// 过大的帧先暂存到表里，通知里只带 ID
+		data, err := spoolFanout(ctx, data)
+		if err != nil {
+			fmt.Println("fanout spool error: ", err)
+			return
+		}
+		if err := pubsub.Publish(ctx, fanoutDB, pubsub.ChannelDeliver, string(data)); err != nil {
+			fmt.Println("fanout publish error: ", err)
+		}
+		return
+	}
+	if err := pubsub.Publish(ctx, fanoutDB, pubsub.ChannelDeliver, string(data)); err != nil {
+		fmt.Println("fanout publish error: ", err)
+	}
+}
+
+// spoolFanout 暂存大帧并顺带清理过期的暂存，返回只带 ID 的通知
+func spoolFanout(ctx context.Context, payload []byte) ([]byte, error) {
+	now := time.Now()
+	m := &FanoutSpoolModel{Payload: payload, CreatedAt: now}
+	if err := fanoutDB.WithContext(ctx).Create(m).Error; err != nil {
+		return nil, err
+	}
+	if err := fanoutDB.WithContext(ctx).Where("created_at < ?", now.Add(-fanoutSpoolTTL)).Delete(&FanoutSpoolModel{}).Error; err != nil {
+		fmt.Println("fanout spool cleanup error: ", err)
+	}
+	return json.Marshal(fanoutPayload{Origin: GatewayID, SpoolID: m.ID})
+}
+
+func pushLocal(userIDs []uint64, msg Message) {
+	for _, id := range userIDs {
+		msg.ToUserID = id
+		PushToUser(id, msg)
+	}
+}
@@ -24,6 +24,36 @@ const (
 	MessageTypeFile  MessageType = 5
 	MessageTypePing  MessageType = 6
 	MessageTypePong  MessageType = 7
+	// 服务端 -> 客户端：消息投递结果
+	MessageTypeResult MessageType = 8
+	// 服务端 -> 客户端：联系人资料变更，FromUserID 为资料所属用户
+	MessageTypeProfile MessageType = 9
+	// 客户端 -> 服务端：订阅一组用户的在线状态（整体替换上次的订阅）
+	MessageTypePresenceSubscribe MessageType = 10
+	// 双向：服务端推送被订阅用户的状态变化；客户端上报本设备状态（online/away）
+	MessageTypePresence MessageType = 11
+	// 瞬时信号（正在输入、正在录音等）：只投递在线接收方，不存储不重试
+	MessageTypeSignal MessageType = 12
+	// 双向：客户端上报会话已读位置；服务端转发给会话其他成员及本人其他设备
+	MessageTypeReadReceipt MessageType = 13
+	// 双向：撤回/编辑/仅自己删除；服务端校验权限后转发
+	MessageTypeMessageOp MessageType = 14
+	// 客户端 -> 服务端：回复/引用，Data 包裹一条任意聊天类型的消息
+	MessageTypeReply MessageType = 15
+	// 服务端 -> 客户端：话题有新回复
+	MessageTypeThreadUpdate MessageType = 16
+	// 双向：添加/取消表情回应
+	MessageTypeReaction MessageType = 17
+	// 富消息：Data 为带版本号的 JSON（见 message/domain/payload.go），与图片、语音、视频、文件同样存储投递
+	MessageTypeLocation MessageType = 18
+	MessageTypeContact  MessageType = 19
+	MessageTypeSticker  MessageType = 20
+	// 双向：语音/视频通话信令，Data 为 JSON（见 call/dto.CallSignal）；状态由服务端维护，结束时写入通话记录
+	MessageTypeCall MessageType = 21
+	// 服务端 -> 客户端：通知设置变更（静音、仅 @ 提醒、免打扰），同步到用户的全部在线设备
+	MessageTypeNotifyPrefs MessageType = 22
+	// 客户端 -> 服务端：带 @ 实体的聊天消息，Data 与回复帧相同（dto.Reply，reply_to 可为 0）
+	MessageTypeCompose MessageType = 23
 )
 
 func (m MessageType) Int() int {
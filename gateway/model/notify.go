@@ -0,0 +1,165 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"wsim/pkg/pubsub"
+	"wsim/user/api/message/domain"
+	"wsim/user/api/message/dto"
+	presencedomain "wsim/user/api/presence/domain"
+	pushevent "wsim/user/api/push/infra/event"
+	"wsim/user/api/push/infra/provider"
+	pushrepo "wsim/user/api/push/infra/repository"
+	pushusecase "wsim/user/api/push/usecase"
+	"wsim/user/api/user/infra/repository"
+
+	"gorm.io/gorm"
+)
+
+// 推送正文最多保留的字符数
+const previewLen = 100
+
+var (
+	pushDispatcher *pushusecase.Dispatcher
+	prefSvc        *pushusecase.PreferenceService
+	profileRepo    *repository.PostgresUserRepository
+)
+
+// InitNotify 初始化通知设置与离线推送（未配置任何推送服务时不推送），并订阅设置变更同步给用户的在线设备。
+// 须在 InitChat、InitPresence 之后调用
+func InitNotify(ctx context.Context, db *gorm.DB) error {
+	providers, err := provider.NewFromEnv()
+	if err != nil {
+		return err
+	}
+	devices, err := pushrepo.NewPostgresDeviceRepository(db)
+	if err != nil {
+		return err
+	}
+	prefs, err := pushrepo.NewPostgresPreferenceRepository(db)
+	if err != nil {
+		return err
+	}
+	users, err := repository.NewPostgresUserRepository(db)
+	if err != nil {
+		return err
+	}
+	profileRepo = users
+	prefSvc = pushusecase.NewPreferenceService(prefs, messageSvc, pushevent.NewPgNotifier(db))
+	pushDispatcher = pushusecase.NewDispatcher(devices, providers, messageSvc)
+	pushDispatcher.Start(ctx, 4)
+	go pubsub.Subscribe(ctx, pubsub.ChannelNotifyPrefs, func(payload string) {
+		var p struct {
+			UserID uint64 `json:"user_id"`
+		}
+		if err := json.Unmarshal([]byte(payload), &p); err != nil || p.UserID == 0 {
+			return
+		}
+		PushToUser(p.UserID, Message{ToUserID: p.UserID, Type: MessageTypeNotifyPrefs, Data: []byte(payload)})
+	}, nil)
+	return nil
+}
+
+// ApplyNotifyPrefs 按接收方的通知设置决定是否提醒（被 @ 时即使静音也提醒）；不提醒时把投递帧标记为静默。
+// 返回（可能改写后的）投递帧与是否提醒
+func ApplyNotifyPrefs(ctx context.Context, userID uint64, m *dto.Message, out Message) (Message, bool) {
+	notify, err := prefSvc.ShouldNotify(ctx, uint(userID), m.ConversationID, mentioned(ctx, userID, m))
+	if err != nil {
+		// 查不到设置时按默认提醒
+		fmt.Println("query notification preference error: ", err)
+		return out, true
+	}
+	if notify {
+		return out, true
+	}
+	silent := *m
+	silent.Silent = true
+	data, err := json.Marshal(silent)
+	if err != nil {
+		return out, false
+	}
+	out.Data = data
+	return out, false
+}
+
+// mentioned 消息是否 @ 了 userID；@online 只对当前在线的用户算数
+func mentioned(ctx context.Context, userID uint64, m *dto.Message) bool {
+	if len(m.Mentions) == 0 {
+		return false
+	}
+	ms := dto.ToMentions(m.Mentions)
+	if domain.MentionsUser(ms, uint(userID), false) {
+		return true
+	}
+	if !domain.MentionsUser(ms, uint(userID), true) {
+		return false
+	}
+	ps, err := presenceSvc.Query(ctx, 0, []uint{uint(userID)})
+	return err == nil && len(ps) == 1 && ps[0].State == presencedomain.StateOnline
+}
+
+// NotifyOffline 接收方在所有网关都没有在线连接时，给其设备发推送；调用方须先经 ApplyNotifyPrefs 确认需要提醒
+func NotifyOffline(ctx context.Context, userID uint64, m *dto.Message) {
+	if pushDispatcher == nil || !pushDispatcher.Enabled() {
+		return
+	}
+	ps, err := presenceSvc.Query(ctx, 0, []uint{uint(userID)})
+	if err != nil {
+		fmt.Println("query presence error: ", err)
+		return
+	}
+	if len(ps) == 0 || ps[0].State != presencedomain.StateOffline {
+		return
+	}
+	title := fmt.Sprintf("用户 %d", m.SenderID)
+	if p, err := profileRepo.GetProfile(ctx, m.SenderID); err == nil {
+		if p.DisplayName != "" {
+			title = p.DisplayName
+		} else if p.Username != "" {
+			title = p.Username
+		}
+	}
+	pushDispatcher.Enqueue(pushusecase.Event{
+		UserID:         uint(userID),
+		ConversationID: m.ConversationID,
+		MessageID:      m.MessageID,
+		SenderID:       m.SenderID,
+		Title:          title,
+		Body:           preview(m),
+	})
+}
+
+// preview 推送正文：文本截断，富消息只给类型提示，不把内容交给第三方推送服务
+func preview(m *dto.Message) string {
+	switch m.Type {
+	case domain.TypeText:
+		r := []rune(m.Content)
+		if len(r) > previewLen {
+			return string(r[:previewLen]) + "…"
+		}
+		return m.Content
+	case domain.TypeImage:
+		return "[图片]"
+	case domain.TypeVoice:
+		return "[语音]"
+	case domain.TypeVideo:
+		return "[视频]"
+	case domain.TypeFile:
+		var f domain.FilePayload
+		if json.Unmarshal(m.Payload, &f) == nil && f.Name != "" {
+			return "[文件] " + f.Name
+		}
+		return "[文件]"
+	case domain.TypeLocation:
+		return "[位置]"
+	case domain.TypeContact:
+		return "[名片]"
+	case domain.TypeSticker:
+		return "[表情]"
+	case domain.TypeCall:
+		return "[通话]"
+	}
+	return "[新消息]"
+}
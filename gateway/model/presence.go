@@ -0,0 +1,210 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"wsim/pkg/pubsub"
+	"wsim/user/api/presence/domain"
+	"wsim/user/api/presence/dto"
+	"wsim/user/api/presence/infra/event"
+	"wsim/user/api/presence/infra/repository"
+	"wsim/user/api/presence/usecase"
+
+	"github.com/cloudwego/netpoll"
+	"gorm.io/gorm"
+)
+
+// GatewayID 本网关标识，多网关部署时需通过 GATEWAY_ID 区分
+var GatewayID string
+
+// 单条连接最多订阅的用户数
+const maxPresenceSubscriptions = 500
+
+// 网关心跳间隔，需明显小于 usecase.SessionTTL
+var presenceHeartbeat = 30 * time.Second
+
+var presenceSvc *usecase.PresenceService
+
+// 订阅关系：被订阅用户 -> 订阅连接 -> 订阅者 userID；byConn 用于重订阅/断线时清理
+var presenceSubs = struct {
+	sync.RWMutex
+	byTarget map[uint64]map[netpoll.Connection]uint64
+	byConn   map[netpoll.Connection][]uint64
+}{
+	byTarget: make(map[uint64]map[netpoll.Connection]uint64),
+	byConn:   make(map[netpoll.Connection][]uint64),
+}
+
+// PresenceSubscribeRequest MessageTypePresenceSubscribe 帧的 Data
+type PresenceSubscribeRequest struct {
+	UserIDs []uint64 `json:"user_ids"`
+}
+
+// PresenceReport 客户端上报本设备状态时 MessageTypePresence 帧的 Data
+type PresenceReport struct {
+	State string `json:"state"`
+}
+
+// InitPresence 清理本网关上次运行遗留的会话，启动心跳并订阅状态变化；须在 InitBlockList 之后调用
+func InitPresence(ctx context.Context, db *gorm.DB) error {
+	GatewayID = os.Getenv("GATEWAY_ID")
+	if GatewayID == "" {
+		host, _ := os.Hostname()
+		GatewayID = host
+	}
+	repo, err := repository.NewPostgresPresenceRepository(db)
+	if err != nil {
+		return err
+	}
+	presenceSvc = usecase.NewPresenceService(repo, event.NewPgNotifier(db), blockRepo)
+	if err := presenceSvc.ResetGateway(ctx, GatewayID); err != nil {
+		return err
+	}
+	go func() {
+		ticker := time.NewTicker(presenceHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := presenceSvc.Heartbeat(ctx, GatewayID); err != nil {
+					fmt.Println("presence heartbeat error: ", err)
+				}
+			}
+		}
+	}()
+	go pubsub.Subscribe(ctx, pubsub.ChannelPresence, onPresenceChanged, nil)
+	return nil
+}
+
+// SessionID 一条连接在全局唯一的会话 ID
+func SessionID(auth *Auth) string {
+	return GatewayID + "/" + auth.RemoteAddr
+}
+
+// PresenceConnect 连接认证成功后上报在线
+func PresenceConnect(ctx context.Context, auth *Auth) {
+	if presenceSvc == nil {
+		return
+	}
+	if err := presenceSvc.Connect(ctx, SessionID(auth), uint(auth.UserID), GatewayID); err != nil {
+		fmt.Println("presence connect error: ", err)
+	}
+}
+
+// PresenceDisconnect 连接断开后上报离线并清理该连接的订阅
+func PresenceDisconnect(ctx context.Context, auth *Auth, conn netpoll.Connection) {
+	unsubscribePresence(conn)
+	if presenceSvc == nil {
+		return
+	}
+	if err := presenceSvc.Disconnect(ctx, SessionID(auth), uint(auth.UserID)); err != nil {
+		fmt.Println("presence disconnect error: ", err)
+	}
+}
+
+// ReportPresence 处理客户端上报的本设备状态
+func ReportPresence(ctx context.Context, auth *Auth, data []byte) error {
+	var req PresenceReport
+	if err := json.Unmarshal(data, &req); err != nil {
+		return err
+	}
+	if presenceSvc == nil {
+		return nil
+	}
+	return presenceSvc.SetState(ctx, SessionID(auth), uint(auth.UserID), GatewayID, domain.State(req.State))
+}
+
+// SubscribePresence 整体替换该连接的订阅，并立即回推一次当前状态
+func SubscribePresence(ctx context.Context, auth *Auth, conn netpoll.Connection, data []byte) error {
+	var req PresenceSubscribeRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return err
+	}
+	if len(req.UserIDs) > maxPresenceSubscriptions {
+		req.UserIDs = req.UserIDs[:maxPresenceSubscriptions]
+	}
+	unsubscribePresence(conn)
+	presenceSubs.Lock()
+	for _, target := range req.UserIDs {
+		subs, ok := presenceSubs.byTarget[target]
+		if !ok {
+			subs = make(map[netpoll.Connection]uint64)
+			presenceSubs.byTarget[target] = subs
+		}
+		subs[conn] = auth.UserID
+	}
+	presenceSubs.byConn[conn] = req.UserIDs
+	presenceSubs.Unlock()
+
+	if presenceSvc == nil || len(req.UserIDs) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(req.UserIDs))
+	for _, id := range req.UserIDs {
+		ids = append(ids, uint(id))
+	}
+	// 订阅者被对方拉黑时始终看到离线，不暴露拉黑
+	ps, err := presenceSvc.Query(ctx, uint(auth.UserID), ids)
+	if err != nil {
+		return err
+	}
+	for _, p := range ps {
+		payload, _ := json.Marshal(dto.FromDomain(p))
+		WriteFrame(conn, Message{
+			FromUserID: uint64(p.UserID),
+			ToUserID:   auth.UserID,
+			Type:       MessageTypePresence,
+			Data:       payload,
+		})
+	}
+	return nil
+}
+
+func unsubscribePresence(conn netpoll.Connection) {
+	presenceSubs.Lock()
+	defer presenceSubs.Unlock()
+	for _, target := range presenceSubs.byConn[conn] {
+		if subs, ok := presenceSubs.byTarget[target]; ok {
+			delete(subs, conn)
+			if len(subs) == 0 {
+				delete(presenceSubs.byTarget, target)
+			}
+		}
+	}
+	delete(presenceSubs.byConn, conn)
+}
+
+func onPresenceChanged(payload string) {
+	var p dto.Presence
+	if err := json.Unmarshal([]byte(payload), &p); err != nil || p.UserID == 0 {
+		return
+	}
+	target := uint64(p.UserID)
+	presenceSubs.RLock()
+	subs := make(map[netpoll.Connection]uint64, len(presenceSubs.byTarget[target]))
+	for conn, subscriber := range presenceSubs.byTarget[target] {
+		subs[conn] = subscriber
+	}
+	presenceSubs.RUnlock()
+
+	for conn, subscriber := range subs {
+		if IsBlocked(target, subscriber) {
+			continue
+		}
+		if err := WriteFrame(conn, Message{
+			FromUserID: target,
+			ToUserID:   subscriber,
+			Type:       MessageTypePresence,
+			Data:       []byte(payload),
+		}); err != nil {
+			fmt.Println("push presence error: ", err)
+		}
+	}
+}
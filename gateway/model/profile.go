@@ -0,0 +1,46 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"wsim/pkg/pubsub"
+	"wsim/user/api/user/infra/repository"
+
+	"gorm.io/gorm"
+)
+
+// InitProfileEvents 订阅资料变更，推送给本网关上把该用户加为联系人的在线用户（被其拉黑的除外）
+func InitProfileEvents(ctx context.Context, db *gorm.DB) error {
+	contacts, err := repository.NewPostgresContactRepository(db)
+	if err != nil {
+		return err
+	}
+	go pubsub.Subscribe(ctx, pubsub.ChannelProfile, func(payload string) {
+		var p struct {
+			UserID uint `json:"user_id"`
+		}
+		if err := json.Unmarshal([]byte(payload), &p); err != nil || p.UserID == 0 {
+			return
+		}
+		watchers, err := contacts.ListWatchers(ctx, p.UserID)
+		if err != nil {
+			fmt.Println("list watchers error: ", err)
+			return
+		}
+		msg := Message{
+			FromUserID: uint64(p.UserID),
+			Type:       MessageTypeProfile,
+			Data:       []byte(payload),
+		}
+		for _, w := range watchers {
+			if IsBlocked(uint64(p.UserID), uint64(w)) {
+				continue
+			}
+			msg.ToUserID = uint64(w)
+			PushToUser(uint64(w), msg)
+		}
+	}, nil)
+	return nil
+}
@@ -0,0 +1,16 @@
+package model
+
+import "fmt"
+
+// PushToUser 把一帧推给用户在本网关上的全部设备；一个设备都没推到返回 false
+func PushToUser(userID uint64, msg Message) bool {
+	pushed := false
+	for _, conn := range UserConns(userID) {
+		if err := WriteFrame(conn, msg); err != nil {
+			fmt.Println("push frame error: ", err)
+			continue
+		}
+		pushed = true
+	}
+	return pushed
+}
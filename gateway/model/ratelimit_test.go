@@ -0,0 +1,67 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	clock := time.Now()
+	l := NewRateLimiter(2, 5)
+	l.now = func() time.Time { return clock }
+
+	// 突发用完即拒绝，其他 key 互不影响
+	for i := 0; i < 5; i++ {
+		if !l.Allow(1) {
+			t.Fatalf("burst %d rejected", i)
+		}
+	}
+	if l.Allow(1) {
+		t.Fatal("allowed beyond burst")
+	}
+	if !l.Allow(2) {
+		t.Fatal("other key rejected")
+	}
+
+	// 每秒补 2 个
+	clock = clock.Add(500 * time.Millisecond)
+	if !l.Allow(1) || l.Allow(1) {
+		t.Fatal("refill after 0.5s should allow exactly one")
+	}
+	// 长时间空闲最多回满到 burst
+	clock = clock.Add(time.Hour)
+	for i := 0; i < 5; i++ {
+		if !l.Allow(1) {
+			t.Fatalf("refilled burst %d rejected", i)
+		}
+	}
+	if l.Allow(1) {
+		t.Fatal("refill exceeded burst")
+	}
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	clock := time.Now()
+	l := NewRateLimiter(2, 5)
+	l.now = func() time.Time { return clock }
+	l.Allow(1)
+	clock = clock.Add(2 * time.Minute)
+	l.Allow(2)
+	if _, ok := l.buckets[1]; ok {
+		t.Fatal("idle full bucket not swept")
+	}
+}
+
+func TestValidSignal(t *testing.T) {
+	for data, want := range map[string]bool{
+		`{"kind":"typing"}`:          true,
+		`{"kind":"recording_voice"}`: true,
+		`{"kind":"cancel"}`:          true,
+		`{"kind":"dance"}`:           false,
+		`not json`:                   false,
+	} {
+		if got := ValidSignal([]byte(data)); got != want {
+			t.Errorf("%s: got %v", data, got)
+		}
+	}
+}
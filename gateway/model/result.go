@@ -0,0 +1,49 @@
+package model
+
+import (
+	"encoding/json"
+
+	"github.com/cloudwego/netpoll"
+)
+
+// 投递结果状态
+const (
+	// ResultSent 已存储；接收方不在线时上线后从历史拉取
+	ResultSent = "sent"
+	// ResultNotDelivered 未送达；被拉黑等原因统一返回该状态，不向发送方暴露具体原因
+	ResultNotDelivered = "not_delivered"
+	// ResultRejected 控制帧未通过校验（无权限、超时等）
+	ResultRejected = "rejected"
+)
+
+// Result MessageTypeResult 帧的 Data
+type Result struct {
+	Status         string `json:"status"`
+	MessageID      uint64 `json:"message_id,omitempty"`
+	ConversationID string `json:"conversation_id,omitempty"`
+	Seq            uint64 `json:"seq,omitempty"`
+}
+
+// WriteFrame 编码并写出一帧
+func WriteFrame(conn netpoll.Connection, msg Message) error {
+	if _, err := conn.Writer().WriteBinary(Encode(msg)); err != nil {
+		return err
+	}
+	return conn.Writer().Flush()
+}
+
+// WriteResult 给发送方回一帧投递结果；ToUserID 填原消息的接收方，便于客户端对应会话
+func WriteResult(conn netpoll.Connection, msg Message, status string) error {
+	return WriteResultDetail(conn, msg, Result{Status: status})
+}
+
+// WriteResultDetail 同 WriteResult，附带存储后的消息 ID 等信息
+func WriteResultDetail(conn netpoll.Connection, msg Message, res Result) error {
+	data, _ := json.Marshal(res)
+	return WriteFrame(conn, Message{
+		FromUserID: 0,
+		ToUserID:   msg.ToUserID,
+		Type:       MessageTypeResult,
+		Data:       data,
+	})
+}
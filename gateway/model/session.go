@@ -0,0 +1,110 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"wsim/pkg/pubsub"
+	"wsim/user/api/user/domain"
+	"wsim/user/api/user/dto"
+	"wsim/user/api/user/infra/repository"
+	"wsim/user/api/user/infra/token"
+	"wsim/user/api/user/usecase"
+
+	"github.com/cloudwego/netpoll"
+	"gorm.io/gorm"
+)
+
+var sessionSvc *usecase.SessionService
+
+// 已认证连接及其令牌身份，会话被吊销时据此找到要断开的连接
+var sessions = struct {
+	sync.Mutex
+	byConn map[netpoll.Connection]*domain.TokenClaims
+}{byConn: make(map[netpoll.Connection]*domain.TokenClaims)}
+
+// InitSessions 初始化令牌校验并订阅会话吊销事件
+func InitSessions(ctx context.Context, db *gorm.DB) error {
+	repo, err := repository.NewPostgresRevocationRepository(db)
+	if err != nil {
+		return err
+	}
+	// 网关只持有公钥：从用户服务的 JWKS 接口获取
+	verifier, err := token.NewJWKSVerifier(ctx, token.JWKSURLFromEnv())
+	if err != nil {
+		return err
+	}
+	sessionSvc = usecase.NewSessionService(verifier, repo)
+	go pubsub.Subscribe(ctx, pubsub.ChannelSessions, func(payload string) {
+		var ev dto.SessionRevoked
+		if err := json.Unmarshal([]byte(payload), &ev); err != nil {
+			return
+		}
+		for _, conn := range revokedConns(ev) {
+			closeSession(conn)
+		}
+	}, func() {
+		// 断线期间可能漏掉吊销事件，重连后逐条复查
+		recheckSessions(ctx)
+	})
+	return nil
+}
+
+// Authenticate 校验认证帧携带的访问令牌
+func Authenticate(ctx context.Context, tk string) (*domain.TokenClaims, error) {
+	return sessionSvc.Authenticate(ctx, tk)
+}
+
+// TrackSession 登记已认证连接的令牌身份
+func TrackSession(conn netpoll.Connection, claims *domain.TokenClaims) {
+	sessions.Lock()
+	sessions.byConn[conn] = claims
+	sessions.Unlock()
+}
+
+// UntrackSession 连接断开时注销
+func UntrackSession(conn netpoll.Connection) {
+	sessions.Lock()
+	delete(sessions.byConn, conn)
+	sessions.Unlock()
+}
+
+func revokedConns(ev dto.SessionRevoked) []netpoll.Connection {
+	sessions.Lock()
+	defer sessions.Unlock()
+	var out []netpoll.Connection
+	for conn, c := range sessions.byConn {
+		if c.UserID != ev.UserID {
+			continue
+		}
+		if (ev.TokenID != "" && c.ID == ev.TokenID) || (ev.TokenID == "" && c.Epoch < ev.Epoch) {
+			out = append(out, conn)
+		}
+	}
+	return out
+}
+
+func recheckSessions(ctx context.Context) {
+	sessions.Lock()
+	snapshot := make(map[netpoll.Connection]*domain.TokenClaims, len(sessions.byConn))
+	for conn, c := range sessions.byConn {
+		snapshot[conn] = c
+	}
+	sessions.Unlock()
+	for conn, c := range snapshot {
+		if err := sessionSvc.Check(ctx, c); err == domain.ErrTokenRevoked {
+			closeSession(conn)
+		} else if err != nil {
+			fmt.Println("recheck session error: ", err)
+		}
+	}
+}
+
+// closeSession 告知客户端会话已失效后断开；后续清理由断线回调完成
+func closeSession(conn netpoll.Connection) {
+	conn.Writer().WriteString("会话已失效，请重新登录")
+	conn.Writer().Flush()
+	conn.Close()
+}
@@ -0,0 +1,38 @@
+package model
+
+import "encoding/json"
+
+// 瞬时信号类型
+const (
+	SignalTyping         = "typing"
+	SignalRecordingVoice = "recording_voice"
+	SignalUploadingMedia = "uploading_media"
+	// SignalCancel 结束之前的状态（停止输入等）
+	SignalCancel = "cancel"
+)
+
+// Signal MessageTypeSignal 帧的 Data
+type Signal struct {
+	Kind string `json:"kind"`
+}
+
+// 每个发送方每秒 2 个，突发 5 个；超出直接丢弃
+var signalLimiter = NewRateLimiter(2, 5)
+
+// ValidSignal 校验信号内容，未知类型丢弃
+func ValidSignal(data []byte) bool {
+	var s Signal
+	if err := json.Unmarshal(data, &s); err != nil {
+		return false
+	}
+	switch s.Kind {
+	case SignalTyping, SignalRecordingVoice, SignalUploadingMedia, SignalCancel:
+		return true
+	}
+	return false
+}
+
+// AllowSignal 按发送方限流
+func AllowSignal(senderID uint64) bool {
+	return signalLimiter.Allow(senderID)
+}
@@ -1,34 +1,121 @@
 package model
 
-import "github.com/cloudwego/netpoll"
+import (
+	"sync"
+
+	"github.com/cloudwego/netpoll"
+)
 
 type User struct {
 	UserID uint64             `json:"user_id"`
-	Conn   netpoll.Connection `json:"conn"`
+	Conn   netpoll.Connection `json:"conn"` // 最近一次登录的连接
 	IsAuth bool               `json:"is_auth"`
+	// Conns 该用户在本网关上的全部连接，每条连接对应一个设备
+	Conns []netpoll.Connection `json:"-"`
 }
 
 var Users map[uint64]*User
 
+// usersMu 保护 Users：除连接回调外，pubsub 等后台 goroutine 也会读取在线用户
+var usersMu sync.RWMutex
+
 func NewUsers() {
 	Users = make(map[uint64]*User)
 }
 
-func (u *User) Get(userID uint64) *User {
+// GetUser 取本网关上的在线用户，不存在返回 nil
+func GetUser(userID uint64) *User {
+	usersMu.RLock()
+	defer usersMu.RUnlock()
 	return Users[userID]
 }
 
-func (u *User) Set(userID uint64, user *User) {
+func SetUser(userID uint64, user *User) {
+	usersMu.Lock()
+	defer usersMu.Unlock()
 	Users[userID] = user
 }
 
-func (u *User) Delete(userID uint64) {
+func DeleteUser(userID uint64) {
+	usersMu.Lock()
+	defer usersMu.Unlock()
 	delete(Users, userID)
 }
 
+// AddConn 为用户登记一条已认证连接（多端登录）；该连接已登记过返回 false
+func AddConn(userID uint64, conn netpoll.Connection) bool {
+	usersMu.Lock()
+	defer usersMu.Unlock()
+	u, ok := Users[userID]
+	if !ok {
+		u = &User{UserID: userID}
+		Users[userID] = u
+	}
+	for _, c := range u.Conns {
+		if c == conn {
+			return false
+		}
+	}
+	u.Conns = append(u.Conns, conn)
+	u.Conn = conn
+	u.IsAuth = true
+	return true
+}
+
+// RemoveConn 连接断开时注销；返回该用户在本网关剩余的连接数，为 0 时移除用户
+func RemoveConn(userID uint64, conn netpoll.Connection) int {
+	usersMu.Lock()
+	defer usersMu.Unlock()
+	u, ok := Users[userID]
+	if !ok {
+		return 0
+	}
+	conns := u.Conns[:0]
+	for _, c := range u.Conns {
+		if c != conn {
+			conns = append(conns, c)
+		}
+	}
+	u.Conns = conns
+	if len(conns) == 0 {
+		delete(Users, userID)
+		return 0
+	}
+	if u.Conn == conn {
+		u.Conn = conns[len(conns)-1]
+	}
+	return len(conns)
+}
+
+// UserConns 返回用户在本网关上全部连接的快照
+func UserConns(userID uint64) []netpoll.Connection {
+	usersMu.RLock()
+	defer usersMu.RUnlock()
+	u, ok := Users[userID]
+	if !ok || !u.IsAuth {
+		return nil
+	}
+	if len(u.Conns) == 0 && u.Conn != nil {
+		return []netpoll.Connection{u.Conn}
+	}
+	return append([]netpoll.Connection(nil), u.Conns...)
+}
+
+func (u *User) Get(userID uint64) *User {
+	return GetUser(userID)
+}
+
+func (u *User) Set(userID uint64, user *User) {
+	SetUser(userID, user)
+}
+
+func (u *User) Delete(userID uint64) {
+	DeleteUser(userID)
+}
+
 // 获取用户是否登陆状态
 func (u *User) AuthStatus(userID uint64) bool {
-	if user, ok := Users[userID]; ok {
+	if user := GetUser(userID); user != nil {
 		return user.IsAuth
 	}
 	return false
@@ -36,6 +123,8 @@ func (u *User) AuthStatus(userID uint64) bool {
 
 // 设置用户登陆状态
 func (u *User) SetAuth(userID uint64, isAuth bool) {
+	usersMu.Lock()
+	defer usersMu.Unlock()
 	if user, ok := Users[userID]; ok {
 		user.IsAuth = isAuth
 	} else {
@@ -47,7 +136,7 @@ func (u *User) SetAuth(userID uint64, isAuth bool) {
 }
 
 func (u *User) GetConn(userID uint64) netpoll.Connection {
-	if user, ok := Users[userID]; ok {
+	if user := GetUser(userID); user != nil {
 		return user.Conn
 	}
 	return nil
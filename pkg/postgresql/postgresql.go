@@ -29,15 +29,7 @@ func InitPostgreSQL() {
 	if err != nil {
 		log.Fatal("Failed to load .env file: ", err)
 	}
-	host := os.Getenv("host")
-	port := os.Getenv("port")
-	user := os.Getenv("user")
-	password := os.Getenv("password")
-	dbname := os.Getenv("dbname")
-	sslmode := os.Getenv("sslmode")
-	timezone := os.Getenv("timezone")
-
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s timezone=%s", host, port, user, password, dbname, sslmode, timezone)
+	dsn := DSN()
 	if dsn == "" {
 		log.Fatal("PG_DSN is not set")
 	}
@@ -86,6 +78,19 @@ func InitPostgreSQL() {
 	sqlDB.SetConnMaxIdleTime(connMaxIdleTime)
 }
 
+// DSN 按环境变量拼接连接串；除 gorm 外，LISTEN/NOTIFY 等需要独占连接的场景也复用它
+func DSN() string {
+	host := os.Getenv("host")
+	port := os.Getenv("port")
+	user := os.Getenv("user")
+	password := os.Getenv("password")
+	dbname := os.Getenv("dbname")
+	sslmode := os.Getenv("sslmode")
+	timezone := os.Getenv("timezone")
+
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s timezone=%s", host, port, user, password, dbname, sslmode, timezone)
+}
+
 func envInt(key string, def, min, max int) int {
 	v := strings.TrimSpace(os.Getenv(key))
 	if v == "" {
@@ -0,0 +1,77 @@
+package pubsub
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"wsim/pkg/postgresql"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+// 基于 PostgreSQL LISTEN/NOTIFY 的轻量广播：
+// 用户服务写库后 Publish，网关等进程 Subscribe 后刷新本地缓存。
+// NOTIFY 不落盘，订阅方断线期间的通知会丢失，因此订阅方重连后应整体失效缓存。
+
+// 频道名：发布方与订阅方共用
+const (
+	// ChannelBlockList 拉黑关系变更，payload 为拉黑发起方 userID
+	ChannelBlockList = "im_block_changed"
+	// ChannelProfile 用户资料变更，payload 为资料 JSON（含 user_id）
+	ChannelProfile = "im_profile_changed"
+	// ChannelPresence 用户聚合在线状态变化，payload 为状态 JSON（含 user_id）
+	ChannelPresence = "im_presence_changed"
+	// ChannelDeliver 网关间投递小型事件帧（已读回执等），payload 见 gateway/model/fanout.go
+	ChannelDeliver = "im_deliver"
+	// ChannelNotifyPrefs 通知设置变更，payload 为设置 JSON（含 user_id），由网关同步到用户的在线设备
+	ChannelNotifyPrefs = "im_notify_prefs_changed"
+	// ChannelSessions 会话被注销/吊销，payload 为吊销事件 JSON（含 user_id），网关据此断开连接
+	ChannelSessions = "im_sessions_revoked"
+)
+
+// 重连间隔
+var reconnectInterval = 3 * time.Second
+
+// Publish 向 channel 广播 payload（payload 需小于 8000 字节）
+func Publish(ctx context.Context, db *gorm.DB, channel, payload string) error {
+	return db.WithContext(ctx).Exec("SELECT pg_notify(?, ?)", channel, payload).Error
+}
+
+// Subscribe 在独立连接上 LISTEN channel，每条通知回调一次 handler。
+// onReconnect 在每次（重新）建立监听后回调，可为 nil。
+// 阻塞直到 ctx 结束，一般放到 goroutine 中运行。
+func Subscribe(ctx context.Context, channel string, handler func(payload string), onReconnect func()) {
+	for ctx.Err() == nil {
+		if err := listen(ctx, channel, handler, onReconnect); err != nil && ctx.Err() == nil {
+			log.Printf("pubsub listen %s failed: %v", channel, err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(reconnectInterval):
+			}
+		}
+	}
+}
+
+func listen(ctx context.Context, channel string, handler func(payload string), onReconnect func()) error {
+	conn, err := pgx.Connect(ctx, postgresql.DSN())
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return err
+	}
+	if onReconnect != nil {
+		onReconnect()
+	}
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		handler(n.Payload)
+	}
+}
@@ -0,0 +1,276 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrCallNotFound 通话不存在
+	ErrCallNotFound = errors.New("call not found")
+	// ErrNotParticipant 不是该通话的参与者
+	ErrNotParticipant = errors.New("not a call participant")
+	// ErrCallState 当前状态下不允许该操作
+	ErrCallState = errors.New("invalid call state")
+)
+
+type CallState string
+
+const (
+	CallRinging CallState = "ringing"
+	CallActive  CallState = "active"
+	CallEnded   CallState = "ended"
+)
+
+type ParticipantState string
+
+const (
+	PartInvited  ParticipantState = "invited"
+	PartRinging  ParticipantState = "ringing"
+	PartJoined   ParticipantState = "joined"
+	PartRejected ParticipantState = "rejected"
+	PartBusy     ParticipantState = "busy"
+	PartMissed   ParticipantState = "missed"
+	PartLeft     ParticipantState = "left"
+)
+
+// EndReason 通话结束原因
+type EndReason string
+
+const (
+	EndCompleted EndReason = "completed"
+	EndCanceled  EndReason = "canceled"
+	EndRejected  EndReason = "rejected"
+	EndMissed    EndReason = "missed"
+	EndBusy      EndReason = "busy"
+)
+
+const (
+	MediaAudio = "audio"
+	MediaVideo = "video"
+)
+
+type Participant struct {
+	UserID   uint
+	State    ParticipantState
+	JoinedAt time.Time
+	LeftAt   time.Time
+}
+
+// Pending 已邀请、尚未应答
+func (p *Participant) Pending() bool {
+	return p.State == PartInvited || p.State == PartRinging
+}
+
+// Call 一次通话。状态机：ringing -> active -> ended，或 ringing -> ended（取消/拒绝/未接/忙线）
+type Call struct {
+	ID string
+	// ConversationID 为空表示临时发起的通话，记录写入主叫与各被叫的单聊
+	ConversationID string
+	CallerID       uint
+	Media          string
+	State          CallState
+	EndReason      EndReason
+	CreatedAt      time.Time
+	// RingDeadline 超过该时间仍未应答的被叫记为未接
+	RingDeadline time.Time
+	AnsweredAt   time.Time
+	EndedAt      time.Time
+	Participants []Participant
+}
+
+// NewCall 创建振铃中的通话；busy 中的被叫直接记为忙线，全部忙线时通话立即结束
+func NewCall(id, conversationID string, callerID uint, media string, invitees []uint, busy map[uint]bool, now, deadline time.Time) *Call {
+	c := &Call{
+		ID:             id,
+		ConversationID: conversationID,
+		CallerID:       callerID,
+		Media:          media,
+		State:          CallRinging,
+		CreatedAt:      now,
+		RingDeadline:   deadline,
+		Participants:   []Participant{{UserID: callerID, State: PartJoined, JoinedAt: now}},
+	}
+	for _, id := range invitees {
+		state := PartInvited
+		if busy[id] {
+			state = PartBusy
+		}
+		c.Participants = append(c.Participants, Participant{UserID: id, State: state})
+	}
+	c.settle(now)
+	return c
+}
+
+func (c *Call) Participant(userID uint) *Participant {
+	for i := range c.Participants {
+		if c.Participants[i].UserID == userID {
+			return &c.Participants[i]
+		}
+	}
+	return nil
+}
+
+// Ring 被叫设备已开始振铃
+func (c *Call) Ring(userID uint) error {
+	p, err := c.member(userID)
+	if err != nil {
+		return err
+	}
+	if c.State == CallEnded || p.State != PartInvited {
+		return ErrCallState
+	}
+	p.State = PartRinging
+	return nil
+}
+
+// Accept 被叫接听；第一个接听的人让通话进入 active
+func (c *Call) Accept(userID uint, now time.Time) error {
+	p, err := c.member(userID)
+	if err != nil {
+		return err
+	}
+	if c.State == CallEnded || !p.Pending() {
+		return ErrCallState
+	}
+	p.State, p.JoinedAt = PartJoined, now
+	if c.State == CallRinging {
+		c.State, c.AnsweredAt = CallActive, now
+	}
+	return nil
+}
+
+// Reject 被叫拒接
+func (c *Call) Reject(userID uint, now time.Time) error {
+	p, err := c.member(userID)
+	if err != nil {
+		return err
+	}
+	if c.State == CallEnded || !p.Pending() {
+		return ErrCallState
+	}
+	p.State = PartRejected
+	c.settle(now)
+	return nil
+}
+
+// Hangup 挂断：主叫在振铃中挂断即取消；通话中的人挂断即离开，少于两人时通话结束；
+// 被叫在振铃中挂断视同拒接
+func (c *Call) Hangup(userID uint, now time.Time) error {
+	p, err := c.member(userID)
+	if err != nil {
+		return err
+	}
+	if c.State == CallEnded {
+		return ErrCallState
+	}
+	switch {
+	case userID == c.CallerID && c.State == CallRinging:
+		c.end(EndCanceled, now)
+		return nil
+	case p.State == PartJoined:
+		p.State, p.LeftAt = PartLeft, now
+	case p.Pending():
+		p.State = PartRejected
+	default:
+		return ErrCallState
+	}
+	c.settle(now)
+	return nil
+}
+
+// Expire 振铃超时：未应答的被叫记为未接；返回是否有变化
+func (c *Call) Expire(now time.Time) bool {
+	if c.State == CallEnded || now.Before(c.RingDeadline) {
+		return false
+	}
+	changed := false
+	for i := range c.Participants {
+		if c.Participants[i].Pending() {
+			c.Participants[i].State = PartMissed
+			changed = true
+		}
+	}
+	if changed {
+		c.settle(now)
+	}
+	return changed
+}
+
+// Duration 接通时长，未接通为 0
+func (c *Call) Duration() time.Duration {
+	if c.AnsweredAt.IsZero() || c.EndedAt.IsZero() {
+		return 0
+	}
+	return c.EndedAt.Sub(c.AnsweredAt)
+}
+
+func (c *Call) member(userID uint) (*Participant, error) {
+	p := c.Participant(userID)
+	if p == nil {
+		return nil, ErrNotParticipant
+	}
+	return p, nil
+}
+
+func (c *Call) pending() bool {
+	for i := range c.Participants {
+		if c.Participants[i].Pending() {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Call) joined() int {
+	n := 0
+	for i := range c.Participants {
+		if c.Participants[i].State == PartJoined {
+			n++
+		}
+	}
+	return n
+}
+
+// settle 根据参与者状态判断通话是否该结束
+func (c *Call) settle(now time.Time) {
+	switch c.State {
+	case CallRinging:
+		if c.pending() {
+			return
+		}
+		if c.Participant(c.CallerID).State != PartJoined {
+			c.end(EndCanceled, now)
+			return
+		}
+		reason := EndMissed
+		for i := range c.Participants {
+			switch c.Participants[i].State {
+			case PartRejected:
+				reason = EndRejected
+			case PartBusy:
+				if reason == EndMissed {
+					reason = EndBusy
+				}
+			}
+		}
+		c.end(reason, now)
+	case CallActive:
+		if c.joined() < 2 {
+			c.end(EndCompleted, now)
+		}
+	}
+}
+
+func (c *Call) end(reason EndReason, now time.Time) {
+	c.State, c.EndReason, c.EndedAt = CallEnded, reason, now
+	for i := range c.Participants {
+		p := &c.Participants[i]
+		switch {
+		case p.Pending():
+			p.State = PartMissed
+		case p.State == PartJoined:
+			p.State, p.LeftAt = PartLeft, now
+		}
+	}
+}
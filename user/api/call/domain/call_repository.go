@@ -0,0 +1,18 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// CallRepository 通话及参与者；状态变更在行锁内完成，多个网关并发操作同一通话不会互相覆盖
+type CallRepository interface {
+	Create(ctx context.Context, c *Call) error
+	Get(ctx context.Context, id string) (*Call, error)
+	// Update 锁定通话后执行 fn，fn 返回 nil 时保存其对通话与参与者状态的修改
+	Update(ctx context.Context, id string, fn func(c *Call) error) (*Call, error)
+	// Busy userIDs 中正在通话或振铃中的用户
+	Busy(ctx context.Context, userIDs []uint) (map[uint]bool, error)
+	// DueForTimeout 振铃已超时、仍有未应答参与者的通话
+	DueForTimeout(ctx context.Context, now time.Time, limit int) ([]string, error)
+}
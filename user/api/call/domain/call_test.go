@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCallLifecycle(t *testing.T) {
+	now := time.Unix(1000, 0)
+	c := NewCall("c1", "", 1, MediaAudio, []uint{2}, nil, now, now.Add(45*time.Second))
+	if err := c.Ring(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Accept(2, now.Add(5*time.Second)); err != nil || c.State != CallActive {
+		t.Fatalf("accept: %v %s", err, c.State)
+	}
+	if err := c.Reject(2, now); err != ErrCallState {
+		t.Fatalf("reject after accept: %v", err)
+	}
+	if c.Expire(now.Add(time.Minute)) {
+		t.Fatal("answered call should not expire")
+	}
+	if err := c.Hangup(1, now.Add(65*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if c.State != CallEnded || c.EndReason != EndCompleted || c.Duration() != time.Minute {
+		t.Fatalf("got %s %s %v", c.State, c.EndReason, c.Duration())
+	}
+	if err := c.Hangup(2, now); err != ErrCallState {
+		t.Fatalf("hangup after end: %v", err)
+	}
+}
+
+func TestCallEndReasons(t *testing.T) {
+	now := time.Unix(1000, 0)
+	deadline := now.Add(45 * time.Second)
+
+	c := NewCall("c1", "", 1, MediaVideo, []uint{2, 3}, nil, now, deadline)
+	if c.Expire(now.Add(time.Second)) {
+		t.Fatal("expired before deadline")
+	}
+	_ = c.Reject(2, now)
+	if c.State != CallRinging {
+		t.Fatalf("one reject should keep ringing, got %s", c.State)
+	}
+	if !c.Expire(deadline) || c.EndReason != EndRejected || c.Participant(3).State != PartMissed {
+		t.Fatalf("got %s, participant 3 %s", c.EndReason, c.Participant(3).State)
+	}
+
+	c = NewCall("c2", "", 1, MediaAudio, []uint{2}, nil, now, deadline)
+	if !c.Expire(deadline) || c.EndReason != EndMissed {
+		t.Fatalf("timeout: %s", c.EndReason)
+	}
+
+	c = NewCall("c3", "", 1, MediaAudio, []uint{2}, nil, now, deadline)
+	if err := c.Hangup(1, now); err != nil || c.EndReason != EndCanceled || c.Participant(2).State != PartMissed {
+		t.Fatalf("cancel: %v %s", err, c.EndReason)
+	}
+
+	c = NewCall("c4", "", 1, MediaAudio, []uint{2}, map[uint]bool{2: true}, now, deadline)
+	if c.State != CallEnded || c.EndReason != EndBusy {
+		t.Fatalf("busy: %s %s", c.State, c.EndReason)
+	}
+
+	if err := c.Accept(9, now); err != ErrNotParticipant {
+		t.Fatalf("stranger: %v", err)
+	}
+}
+
+func TestGroupCallContinuesUntilOneLeft(t *testing.T) {
+	now := time.Unix(1000, 0)
+	c := NewCall("c1", "g", 1, MediaAudio, []uint{2, 3}, nil, now, now.Add(time.Minute))
+	_ = c.Accept(2, now)
+	_ = c.Accept(3, now)
+	_ = c.Hangup(1, now)
+	if c.State != CallActive {
+		t.Fatalf("caller leaving a 3-way call ended it: %s", c.State)
+	}
+	_ = c.Hangup(2, now)
+	if c.State != CallEnded || c.EndReason != EndCompleted || c.Participant(3).State != PartLeft {
+		t.Fatalf("got %s %s", c.State, c.EndReason)
+	}
+}
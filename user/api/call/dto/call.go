@@ -0,0 +1,67 @@
+package dto
+
+import (
+	"time"
+
+	"wsim/user/api/call/domain"
+	"wsim/user/api/call/usecase"
+	msgdto "wsim/user/api/message/dto"
+)
+
+// CallSignal 通话帧。客户端上报 action 与 call_id（invite 带 conversation_id、media、invitees；
+// offer/answer 带 to、sdp；ice 带 to、candidate）；服务端推送时补全发起人与通话当前状态
+type CallSignal struct {
+	Action         string `json:"action"`
+	CallID         string `json:"call_id,omitempty"`
+	ConversationID string `json:"conversation_id,omitempty"`
+	Media          string `json:"media,omitempty"`
+	Invitees       []uint `json:"invitees,omitempty"`
+	From           uint   `json:"from,omitempty"`
+	To             uint   `json:"to,omitempty"`
+	SDP            string `json:"sdp,omitempty"`
+	Candidate      string `json:"candidate,omitempty"`
+	// 以下仅服务端推送
+	CallerID     uint            `json:"caller_id,omitempty"`
+	State        string          `json:"state,omitempty"`
+	Reason       string          `json:"reason,omitempty"`
+	RingDeadline time.Time       `json:"ring_deadline,omitzero"`
+	Participants []Participant   `json:"participants,omitempty"`
+	Record       *msgdto.Message `json:"record,omitempty"`
+}
+
+type Participant struct {
+	UserID uint   `json:"user_id"`
+	State  string `json:"state"`
+}
+
+// FromNotice 转为推送给客户端的帧；SDP/ICE 转发不带参与者列表
+func FromNotice(n usecase.Notice) CallSignal {
+	c := n.Call
+	out := CallSignal{
+		Action:         n.Action,
+		CallID:         c.ID,
+		ConversationID: c.ConversationID,
+		Media:          c.Media,
+		From:           n.From,
+		SDP:            n.SDP,
+		Candidate:      n.Candidate,
+		CallerID:       c.CallerID,
+		State:          string(c.State),
+		Reason:         string(c.EndReason),
+	}
+	switch n.Action {
+	case usecase.ActionOffer, usecase.ActionAnswer, usecase.ActionICE:
+		return out
+	}
+	if c.State != domain.CallEnded {
+		out.RingDeadline = c.RingDeadline
+	}
+	for _, p := range c.Participants {
+		out.Participants = append(out.Participants, Participant{UserID: p.UserID, State: string(p.State)})
+	}
+	if n.Record != nil {
+		r := msgdto.FromMessage(n.Record)
+		out.Record = &r
+	}
+	return out
+}
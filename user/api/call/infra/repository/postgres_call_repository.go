@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"wsim/user/api/call/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CallModel 通话；state 与 ring_deadline 的联合索引用于扫描振铃超时
+type CallModel struct {
+	ID             string    `gorm:"type:varchar(32);primaryKey"`
+	ConversationID string    `gorm:"type:varchar(64);not null;default:''"`
+	CallerID       uint      `gorm:"not null"`
+	Media          string    `gorm:"type:varchar(8);not null"`
+	State          string    `gorm:"type:varchar(16);not null;index:idx_calls_state_deadline,priority:1"`
+	EndReason      string    `gorm:"type:varchar(16);not null;default:''"`
+	CreatedAt      time.Time `gorm:"not null"`
+	RingDeadline   time.Time `gorm:"not null;index:idx_calls_state_deadline,priority:2"`
+	AnsweredAt     *time.Time
+	EndedAt        *time.Time
+}
+
+func (CallModel) TableName() string { return "calls" }
+
+type ParticipantModel struct {
+	CallID   string `gorm:"type:varchar(32);primaryKey"`
+	UserID   uint   `gorm:"primaryKey;autoIncrement:false;index"`
+	State    string `gorm:"type:varchar(16);not null"`
+	Position int    `gorm:"not null"`
+	JoinedAt *time.Time
+	LeftAt   *time.Time
+}
+
+func (ParticipantModel) TableName() string { return "call_participants" }
+
+type PostgresCallRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresCallRepository(db *gorm.DB) (*PostgresCallRepository, error) {
+	if err := db.AutoMigrate(&CallModel{}, &ParticipantModel{}); err != nil {
+		return nil, err
+	}
+	return &PostgresCallRepository{db: db}, nil
+}
+
+func (r *PostgresCallRepository) Create(ctx context.Context, c *domain.Call) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(fromCall(c)).Error; err != nil {
+			return err
+		}
+		return tx.Create(fromParticipants(c)).Error
+	})
+}
+
+func (r *PostgresCallRepository) Get(ctx context.Context, id string) (*domain.Call, error) {
+	return r.load(r.db.WithContext(ctx), id)
+}
+
+func (r *PostgresCallRepository) Update(ctx context.Context, id string, fn func(c *domain.Call) error) (*domain.Call, error) {
+	var out *domain.Call
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		c, err := r.load(tx.Clauses(clause.Locking{Strength: "UPDATE"}), id)
+		if err != nil {
+			return err
+		}
+		if err := fn(c); err != nil {
+			return err
+		}
+		if err := tx.Select("*").Omit("id", "created_at").Updates(fromCall(c)).Error; err != nil {
+			return err
+		}
+		for _, p := range fromParticipants(c) {
+			if err := tx.Model(&ParticipantModel{}).
+				Where("call_id = ? AND user_id = ?", p.CallID, p.UserID).
+				Updates(map[string]any{"state": p.State, "joined_at": p.JoinedAt, "left_at": p.LeftAt}).Error; err != nil {
+				return err
+			}
+		}
+		out = c
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *PostgresCallRepository) Busy(ctx context.Context, userIDs []uint) (map[uint]bool, error) {
+	busy := make(map[uint]bool)
+	if len(userIDs) == 0 {
+		return busy, nil
+	}
+	var ids []uint
+	err := r.db.WithContext(ctx).Table("call_participants AS p").
+		Joins("JOIN calls c ON c.id = p.call_id").
+		Where("c.state <> ? AND p.state IN ? AND p.user_id IN ?", string(domain.CallEnded),
+			[]string{string(domain.PartInvited), string(domain.PartRinging), string(domain.PartJoined)}, userIDs).
+		Distinct().Pluck("p.user_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		busy[id] = true
+	}
+	return busy, nil
+}
+
+func (r *PostgresCallRepository) DueForTimeout(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	var ids []string
+	err := r.db.WithContext(ctx).Model(&CallModel{}).
+		Where("state <> ? AND ring_deadline <= ?", string(domain.CallEnded), now).
+		Where("EXISTS (SELECT 1 FROM call_participants p WHERE p.call_id = calls.id AND p.state IN ?)",
+			[]string{string(domain.PartInvited), string(domain.PartRinging)}).
+		Order("ring_deadline").Limit(limit).Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *PostgresCallRepository) load(db *gorm.DB, id string) (*domain.Call, error) {
+	var m CallModel
+	tx := db.Where("id = ?", id).Limit(1).Find(&m)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return nil, domain.ErrCallNotFound
+	}
+	var ps []ParticipantModel
+	if err := db.Session(&gorm.Session{NewDB: true}).Where("call_id = ?", id).Order("position").Find(&ps).Error; err != nil {
+		return nil, err
+	}
+	c := &domain.Call{
+		ID:             m.ID,
+		ConversationID: m.ConversationID,
+		CallerID:       m.CallerID,
+		Media:          m.Media,
+		State:          domain.CallState(m.State),
+		EndReason:      domain.EndReason(m.EndReason),
+		CreatedAt:      m.CreatedAt,
+		RingDeadline:   m.RingDeadline,
+		AnsweredAt:     deref(m.AnsweredAt),
+		EndedAt:        deref(m.EndedAt),
+	}
+	for _, p := range ps {
+		c.Participants = append(c.Participants, domain.Participant{
+			UserID:   p.UserID,
+			State:    domain.ParticipantState(p.State),
+			JoinedAt: deref(p.JoinedAt),
+			LeftAt:   deref(p.LeftAt),
+		})
+	}
+	return c, nil
+}
+
+func fromCall(c *domain.Call) *CallModel {
+	return &CallModel{
+		ID:             c.ID,
+		ConversationID: c.ConversationID,
+		CallerID:       c.CallerID,
+		Media:          c.Media,
+		State:          string(c.State),
+		EndReason:      string(c.EndReason),
+		CreatedAt:      c.CreatedAt,
+		RingDeadline:   c.RingDeadline,
+		AnsweredAt:     ptr(c.AnsweredAt),
+		EndedAt:        ptr(c.EndedAt),
+	}
+}
+
+func fromParticipants(c *domain.Call) []ParticipantModel {
+	out := make([]ParticipantModel, 0, len(c.Participants))
+	for i, p := range c.Participants {
+		out = append(out, ParticipantModel{
+			CallID:   c.ID,
+			UserID:   p.UserID,
+			State:    string(p.State),
+			Position: i,
+			JoinedAt: ptr(p.JoinedAt),
+			LeftAt:   ptr(p.LeftAt),
+		})
+	}
+	return out
+}
+
+func ptr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func deref(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
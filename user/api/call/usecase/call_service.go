@@ -0,0 +1,343 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"time"
+
+	"wsim/user/api/call/domain"
+	msgdomain "wsim/user/api/message/domain"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+var (
+	ErrBadRequest = errors.New("bad request")
+	// ErrForbidden 不是会话成员
+	ErrForbidden = errors.New("forbidden")
+	// ErrBusy 主叫自己还在另一通通话中
+	ErrBusy = errors.New("caller busy")
+)
+
+// 信令动作，与网关通话帧的 action 一致
+const (
+	ActionInvite  = "invite"
+	ActionRinging = "ringing"
+	ActionAccept  = "accept"
+	ActionReject  = "reject"
+	ActionHangup  = "hangup"
+	ActionOffer   = "offer"
+	ActionAnswer  = "answer"
+	ActionICE     = "ice"
+	// ActionTimeout 振铃超时，由服务端发出
+	ActionTimeout = "timeout"
+	// ActionEnded 通话结束，附带写入会话历史的通话记录
+	ActionEnded = "ended"
+)
+
+const (
+	// 单次通话参与者上限（含主叫）
+	maxParticipants = 8
+	maxSDPLen       = 16 * 1024
+	maxCandidateLen = 1024
+	// 每轮处理的超时通话数
+	timeoutBatch = 100
+)
+
+// History 会话成员与历史，由消息服务实现
+type History interface {
+	Members(ctx context.Context, conversationID string) ([]msgdomain.Member, error)
+	RecordCall(ctx context.Context, conversationID string, callerID, peerID uint, content string) (*msgdomain.Message, error)
+}
+
+// Notice 一次状态变化需要推给客户端的信令
+type Notice struct {
+	To     []uint
+	Action string
+	From   uint
+	Call   *domain.Call
+	// SDP/Candidate 仅 offer/answer/ice 转发时有值
+	SDP       string
+	Candidate string
+	// Record 仅 ended 有值
+	Record *msgdomain.Message
+}
+
+// CallService 通话信令：状态由服务端维护，客户端只上报动作；SDP/ICE 只在参与者之间转发，不落库
+type CallService struct {
+	calls   domain.CallRepository
+	history History
+	// RingTimeout 振铃多久无人应答记为未接，环境变量 CALL_RING_TIMEOUT 覆盖（默认 45s）
+	RingTimeout time.Duration
+	now         func() time.Time
+}
+
+var errUnchanged = errors.New("unchanged")
+
+func NewCallService(calls domain.CallRepository, history History) *CallService {
+	timeout := 45 * time.Second
+	if v := os.Getenv("CALL_RING_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			timeout = d
+		}
+	}
+	return &CallService{
+		calls:       calls,
+		history:     history,
+		RingTimeout: timeout,
+		now:         time.Now,
+	}
+}
+
+// Invite 发起通话。conversationID 非空时被叫须是该会话成员，通话记录写入该会话；
+// 为空时记录写入主叫与每个被叫的单聊。正在通话中的被叫记为忙线，不会收到邀请
+func (s *CallService) Invite(ctx context.Context, callerID uint, conversationID, media string, invitees []uint) (*domain.Call, []Notice, error) {
+	if callerID == 0 || (media != domain.MediaAudio && media != domain.MediaVideo) {
+		return nil, nil, ErrBadRequest
+	}
+	var ids []uint
+	for _, id := range invitees {
+		if id != 0 && id != callerID && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 || len(ids)+1 > maxParticipants {
+		return nil, nil, ErrBadRequest
+	}
+	if conversationID != "" {
+		members, err := s.history.Members(ctx, conversationID)
+		if err != nil {
+			return nil, nil, err
+		}
+		in := make(map[uint]bool, len(members))
+		for _, m := range members {
+			in[m.UserID] = true
+		}
+		if !in[callerID] {
+			return nil, nil, ErrForbidden
+		}
+		for _, id := range ids {
+			if !in[id] {
+				return nil, nil, ErrBadRequest
+			}
+		}
+	}
+	busy, err := s.calls.Busy(ctx, append([]uint{callerID}, ids...))
+	if err != nil {
+		return nil, nil, err
+	}
+	if busy[callerID] {
+		return nil, nil, ErrBusy
+	}
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, nil, err
+	}
+	now := s.now()
+	c := domain.NewCall(hex.EncodeToString(b[:]), conversationID, callerID, media, ids, busy, now, now.Add(s.RingTimeout))
+	if err := s.calls.Create(ctx, c); err != nil {
+		return nil, nil, err
+	}
+	var to []uint
+	for _, p := range c.Participants {
+		if p.State == domain.PartInvited {
+			to = append(to, p.UserID)
+		}
+	}
+	var notices []Notice
+	if len(to) > 0 {
+		notices = append(notices, Notice{To: to, Action: ActionInvite, From: callerID, Call: c})
+	}
+	if c.State == domain.CallEnded {
+		notices = append(notices, s.finish(ctx, c)...)
+	}
+	return c, notices, nil
+}
+
+// Ring 被叫设备已振铃，通知主叫
+func (s *CallService) Ring(ctx context.Context, userID uint, callID string) ([]Notice, error) {
+	return s.apply(ctx, userID, callID, ActionRinging, func(c *domain.Call, _ time.Time) error {
+		return c.Ring(userID)
+	})
+}
+
+func (s *CallService) Accept(ctx context.Context, userID uint, callID string) ([]Notice, error) {
+	return s.apply(ctx, userID, callID, ActionAccept, func(c *domain.Call, now time.Time) error {
+		return c.Accept(userID, now)
+	})
+}
+
+func (s *CallService) Reject(ctx context.Context, userID uint, callID string) ([]Notice, error) {
+	return s.apply(ctx, userID, callID, ActionReject, func(c *domain.Call, now time.Time) error {
+		return c.Reject(userID, now)
+	})
+}
+
+func (s *CallService) Hangup(ctx context.Context, userID uint, callID string) ([]Notice, error) {
+	return s.apply(ctx, userID, callID, ActionHangup, func(c *domain.Call, now time.Time) error {
+		return c.Hangup(userID, now)
+	})
+}
+
+// Relay 在两个参与者之间转发 SDP（offer/answer）或 ICE 候选；双方都须仍在通话或振铃中
+func (s *CallService) Relay(ctx context.Context, from uint, callID string, to uint, action, sdp, candidate string) (*Notice, error) {
+	switch action {
+	case ActionOffer, ActionAnswer:
+		if sdp == "" || len(sdp) > maxSDPLen {
+			return nil, ErrBadRequest
+		}
+		candidate = ""
+	case ActionICE:
+		if candidate == "" || len(candidate) > maxCandidateLen {
+			return nil, ErrBadRequest
+		}
+		sdp = ""
+	default:
+		return nil, ErrBadRequest
+	}
+	if from == to {
+		return nil, ErrBadRequest
+	}
+	c, err := s.calls.Get(ctx, callID)
+	if err != nil {
+		return nil, err
+	}
+	p, q := c.Participant(from), c.Participant(to)
+	if p == nil || q == nil {
+		return nil, domain.ErrNotParticipant
+	}
+	if c.State == domain.CallEnded || !inCall(p) || !inCall(q) {
+		return nil, domain.ErrCallState
+	}
+	return &Notice{To: []uint{to}, Action: action, From: from, Call: c, SDP: sdp, Candidate: candidate}, nil
+}
+
+// ExpireDue 处理振铃超时的通话，返回需要推送的信令；多个网关同时扫描时由行锁保证只处理一次
+func (s *CallService) ExpireDue(ctx context.Context) ([]Notice, error) {
+	ids, err := s.calls.DueForTimeout(ctx, s.now(), timeoutBatch)
+	if err != nil {
+		return nil, err
+	}
+	var notices []Notice
+	for _, id := range ids {
+		n, err := s.apply(ctx, 0, id, ActionTimeout, func(c *domain.Call, now time.Time) error {
+			if !c.Expire(now) {
+				return errUnchanged
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errUnchanged) {
+			return notices, err
+		}
+		notices = append(notices, n...)
+	}
+	return notices, nil
+}
+
+// apply 在行锁内执行状态变更，把新状态推给全部参与者；通话因此结束时写入通话记录
+func (s *CallService) apply(ctx context.Context, userID uint, callID, action string, fn func(c *domain.Call, now time.Time) error) ([]Notice, error) {
+	now := s.now()
+	c, err := s.calls.Update(ctx, callID, func(c *domain.Call) error {
+		return fn(c, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	notices := []Notice{{To: recipients(c), Action: action, From: userID, Call: c}}
+	if c.State == domain.CallEnded {
+		notices = append(notices, s.finish(ctx, c)...)
+	}
+	return notices, nil
+}
+
+// finish 把通话记录写入会话历史：群组通话写一条，临时通话给主叫与每个被叫各写一条
+func (s *CallService) finish(ctx context.Context, c *domain.Call) []Notice {
+	type record struct {
+		peer    uint
+		payload msgdomain.CallPayload
+		to      []uint
+	}
+	var records []record
+	if c.ConversationID != "" {
+		records = append(records, record{
+			payload: callPayload(c, string(c.EndReason), c.Duration(), joinedUsers(c)),
+			to:      recipients(c),
+		})
+	} else {
+		for _, p := range c.Participants {
+			if p.UserID == c.CallerID {
+				continue
+			}
+			result, duration, joined := string(c.EndReason), time.Duration(0), []uint(nil)
+			switch {
+			case !p.JoinedAt.IsZero():
+				result, duration, joined = string(domain.EndCompleted), p.LeftAt.Sub(p.JoinedAt), []uint{c.CallerID, p.UserID}
+			case p.State == domain.PartRejected:
+				result = string(domain.EndRejected)
+			case p.State == domain.PartBusy:
+				result = string(domain.EndBusy)
+			case c.EndReason != domain.EndCanceled:
+				result = string(domain.EndMissed)
+			}
+			records = append(records, record{
+				peer:    p.UserID,
+				payload: callPayload(c, result, duration, joined),
+				to:      []uint{c.CallerID, p.UserID},
+			})
+		}
+	}
+
+	var notices []Notice
+	for _, r := range records {
+		content, _ := json.Marshal(r.payload)
+		m, err := s.history.RecordCall(ctx, c.ConversationID, c.CallerID, r.peer, string(content))
+		if err != nil {
+			// 记录失败不影响通话本身结束
+			hlog.CtxErrorf(ctx, "record call %s: %v", c.ID, err)
+		}
+		notices = append(notices, Notice{To: r.to, Action: ActionEnded, From: c.CallerID, Call: c, Record: m})
+	}
+	return notices
+}
+
+func callPayload(c *domain.Call, result string, duration time.Duration, joined []uint) msgdomain.CallPayload {
+	return msgdomain.CallPayload{
+		V:            msgdomain.PayloadVersion,
+		CallID:       c.ID,
+		Media:        c.Media,
+		CallerID:     c.CallerID,
+		Result:       result,
+		DurationMs:   duration.Milliseconds(),
+		Participants: joined,
+	}
+}
+
+// recipients 收到状态推送的参与者：忙线的被叫没有收到过邀请，不推
+func recipients(c *domain.Call) []uint {
+	out := make([]uint, 0, len(c.Participants))
+	for _, p := range c.Participants {
+		if p.State != domain.PartBusy {
+			out = append(out, p.UserID)
+		}
+	}
+	return out
+}
+
+func joinedUsers(c *domain.Call) []uint {
+	var out []uint
+	for _, p := range c.Participants {
+		if !p.JoinedAt.IsZero() {
+			out = append(out, p.UserID)
+		}
+	}
+	return out
+}
+
+func inCall(p *domain.Participant) bool {
+	return p.State == domain.PartJoined || p.Pending()
+}
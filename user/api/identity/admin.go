@@ -0,0 +1,44 @@
+package identity
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// ErrForbidden 调用方不是管理员
+var ErrForbidden = errors.New("forbidden")
+
+var (
+	adminsOnce sync.Once
+	admins     map[uint]bool
+)
+
+// IsAdmin 管理员由环境变量 ADMIN_USER_IDS 配置（逗号分隔的用户 ID）
+func IsAdmin(userID uint) bool {
+	adminsOnce.Do(func() {
+		admins = make(map[uint]bool)
+		for _, f := range strings.Split(os.Getenv("ADMIN_USER_IDS"), ",") {
+			if id, err := strconv.ParseUint(strings.TrimSpace(f), 10, 64); err == nil && id != 0 {
+				admins[uint(id)] = true
+			}
+		}
+	})
+	return admins[userID]
+}
+
+// AdminID 取当前调用方 ID，并要求其为管理员
+func AdminID(c *app.RequestContext) (uint, error) {
+	uid, err := UserID(c)
+	if err != nil {
+		return 0, err
+	}
+	if !IsAdmin(uid) {
+		return 0, ErrForbidden
+	}
+	return uid, nil
+}
@@ -0,0 +1,70 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"wsim/user/api/user/domain"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+)
+
+// adminAuth "admin" 为用户 1（管理员），"user" 为用户 7
+type adminAuth struct{}
+
+func (adminAuth) Authenticate(_ context.Context, token string) (*domain.TokenClaims, error) {
+	switch token {
+	case "admin":
+		return &domain.TokenClaims{ID: "t1", UserID: 1}, nil
+	case "user":
+		return &domain.TokenClaims{ID: "t2", UserID: 7}, nil
+	}
+	return nil, domain.ErrTokenInvalid
+}
+
+func TestAdminID(t *testing.T) {
+	t.Setenv("ADMIN_USER_IDS", "1")
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Group("/admin", Middleware(adminAuth{})).POST("/op", func(ctx context.Context, c *app.RequestContext) {
+		uid, err := AdminID(c)
+		if errors.Is(err, ErrForbidden) {
+			c.Status(http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.String(http.StatusOK, strconv.FormatUint(uint64(uid), 10))
+	})
+
+	cases := []struct {
+		name   string
+		header string
+		status int
+	}{
+		{"admin", "Bearer admin", http.StatusOK},
+		{"not admin", "Bearer user", http.StatusForbidden},
+		{"invalid token", "Bearer forged", http.StatusUnauthorized},
+		{"spoofed header ignored", "", http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		headers := []ut.Header{{Key: "X-User-ID", Value: "1"}}
+		if tc.header != "" {
+			headers = append(headers, ut.Header{Key: "Authorization", Value: tc.header})
+		}
+		resp := ut.PerformRequest(engine, http.MethodPost, "/admin/op", nil, headers...).Result()
+		if resp.StatusCode() != tc.status {
+			t.Errorf("%s: status %d, want %d", tc.name, resp.StatusCode(), tc.status)
+		}
+		if tc.status == http.StatusOK && string(resp.Body()) != "1" {
+			t.Errorf("%s: body %q", tc.name, resp.Body())
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package identity
+
+import (
+	"errors"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// ErrUnauthorized 请求未携带有效的调用方身份
+var ErrUnauthorized = errors.New("unauthorized")
+
+// UserID 取当前调用方的用户 ID，来自 Middleware 校验过的访问令牌
+func UserID(c *app.RequestContext) (uint, error) {
+	claims, err := Claims(c)
+	if err != nil {
+		return 0, err
+	}
+	return claims.UserID, nil
+}
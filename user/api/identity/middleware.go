@@ -0,0 +1,76 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"wsim/user/api/user/domain"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+)
+
+// Authenticator 校验访问令牌（签名、有效期与吊销状态），由 usecase.SessionService 实现，网关共用同一实现
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*domain.TokenClaims, error)
+}
+
+type claimsKey struct{}
+
+const claimsKeyName = "identity.claims"
+
+// Middleware 要求请求携带有效的访问令牌：Authorization: Bearer <token>。
+// 通过后身份同时放进请求上下文与 RequestContext，后续 handler 用 UserID/Claims 读取
+func Middleware(auth Authenticator) app.HandlerFunc {
+	return middleware(auth, false)
+}
+
+// QueryTokenMiddleware 同 Middleware，另外接受 ?token=，只用于无法设置请求头的媒体直链。
+// 查询参数会进访问日志、代理与 Referer，不要用在其他路由
+func QueryTokenMiddleware(auth Authenticator) app.HandlerFunc {
+	return middleware(auth, true)
+}
+
+func middleware(auth Authenticator, allowQuery bool) app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		claims, err := auth.Authenticate(ctx, lookupToken(c, allowQuery))
+		if err != nil {
+			status, msg := http.StatusUnauthorized, "unauthorized"
+			if !errors.Is(err, domain.ErrTokenInvalid) && !errors.Is(err, domain.ErrTokenRevoked) {
+				status, msg = http.StatusInternalServerError, err.Error()
+			}
+			c.AbortWithStatusJSON(status, utils.H{"error": msg})
+			return
+		}
+		c.Set(claimsKeyName, claims)
+		c.Next(context.WithValue(ctx, claimsKey{}, claims))
+	}
+}
+
+func lookupToken(c *app.RequestContext, allowQuery bool) string {
+	if v := string(c.GetHeader("Authorization")); len(v) > 7 && strings.EqualFold(v[:7], "Bearer ") {
+		return strings.TrimSpace(v[7:])
+	}
+	if allowQuery {
+		return c.Query("token")
+	}
+	return ""
+}
+
+// Claims 取中间件校验通过的令牌身份
+func Claims(c *app.RequestContext) (*domain.TokenClaims, error) {
+	if v, ok := c.Get(claimsKeyName); ok {
+		if claims, ok := v.(*domain.TokenClaims); ok {
+			return claims, nil
+		}
+	}
+	return nil, ErrUnauthorized
+}
+
+// FromContext 从请求上下文取令牌身份，供不直接持有 RequestContext 的下游使用
+func FromContext(ctx context.Context) (*domain.TokenClaims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*domain.TokenClaims)
+	return claims, ok
+}
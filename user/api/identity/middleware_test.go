@@ -0,0 +1,70 @@
+package identity
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"wsim/user/api/user/domain"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/route"
+)
+
+// fixedAuth 只认 "good"，"revoked" 视为已吊销
+type fixedAuth struct{}
+
+func (fixedAuth) Authenticate(_ context.Context, token string) (*domain.TokenClaims, error) {
+	switch token {
+	case "good":
+		return &domain.TokenClaims{ID: "t1", UserID: 7}, nil
+	case "revoked":
+		return nil, domain.ErrTokenRevoked
+	}
+	return nil, domain.ErrTokenInvalid
+}
+
+func TestMiddleware(t *testing.T) {
+	engine := route.NewEngine(config.NewOptions(nil))
+	me := func(ctx context.Context, c *app.RequestContext) {
+		uid, err := UserID(c)
+		if claims, ok := FromContext(ctx); err != nil || !ok || claims.UserID != uid {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.String(http.StatusOK, strconv.FormatUint(uint64(uid), 10))
+	}
+	engine.Group("", Middleware(fixedAuth{})).GET("/me", me)
+	engine.GET("/media", QueryTokenMiddleware(fixedAuth{}), me)
+
+	cases := []struct {
+		name   string
+		url    string
+		header string
+		status int
+	}{
+		{"bearer", "/me", "Bearer good", http.StatusOK},
+		{"query rejected", "/me?token=good", "", http.StatusUnauthorized},
+		{"query on media", "/media?token=good", "", http.StatusOK},
+		{"bearer on media", "/media", "Bearer good", http.StatusOK},
+		{"missing", "/me", "", http.StatusUnauthorized},
+		{"revoked", "/me", "Bearer revoked", http.StatusUnauthorized},
+		{"spoofed header ignored", "/me", "", http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		headers := []ut.Header{{Key: "X-User-ID", Value: "7"}}
+		if tc.header != "" {
+			headers = append(headers, ut.Header{Key: "Authorization", Value: tc.header})
+		}
+		resp := ut.PerformRequest(engine, http.MethodGet, tc.url, nil, headers...).Result()
+		if resp.StatusCode() != tc.status {
+			t.Errorf("%s: status %d, want %d", tc.name, resp.StatusCode(), tc.status)
+		}
+		if tc.status == http.StatusOK && string(resp.Body()) != "7" {
+			t.Errorf("%s: body %q", tc.name, resp.Body())
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package domain
+
+import (
+	"context"
+	"io"
+)
+
+// BlobStore 媒体二进制存储；key 只含字母、数字、'-'、'_'、'.'，由调用方保证唯一
+type BlobStore interface {
+	// Put 写入 size 字节；同名对象直接覆盖
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Get 调用方负责关闭返回的 ReadCloser；不存在返回 ErrBlobNotFound
+	Get(ctx context.Context, key string) (io.ReadCloser, int64, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	// Delete 不存在时不报错
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,34 @@
+package domain
+
+import "time"
+
+// Media 一份已上传的媒体。ID 为内容的 SHA-256（小写十六进制），相同内容只存一份
+type Media struct {
+	ID   string
+	Size int64
+	MIME string
+	// 仅图片有尺寸
+	Width  int
+	Height int
+	// Status active/quarantined/rejected，空值视为 active
+	Status    string
+	CreatedAt time.Time
+}
+
+// Available 可下载、可在消息中引用
+func (m *Media) Available() bool {
+	return m.Status == "" || m.Status == StatusActive
+}
+
+// IsMediaID 判断是否为合法的媒体 ID
+func IsMediaID(id string) bool {
+	if len(id) != 64 {
+		return false
+	}
+	for _, c := range id {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
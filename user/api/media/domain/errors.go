@@ -0,0 +1,10 @@
+package domain
+
+import "errors"
+
+var (
+	// ErrMediaNotFound 媒体不存在
+	ErrMediaNotFound = errors.New("media not found")
+	// ErrBlobNotFound 存储中没有该对象
+	ErrBlobNotFound = errors.New("blob not found")
+)
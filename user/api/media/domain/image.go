@@ -0,0 +1,33 @@
+package domain
+
+import "errors"
+
+var (
+	// ErrTypeMismatch 内容与声明的类型不符，或图片无法解析
+	ErrTypeMismatch = errors.New("content does not match declared type")
+)
+
+// ImageInfo 图片格式与尺寸；Format 为 jpeg/png/gif
+type ImageInfo struct {
+	Format string
+	Width  int
+	Height int
+}
+
+// Thumbnail 图片缩略图，本身也是一份媒体；Size 为生成时的最长边上限
+type Thumbnail struct {
+	Size    int
+	MediaID string
+	Width   int
+	Height  int
+}
+
+// ImageProcessor 图片解析与处理
+type ImageProcessor interface {
+	// Inspect 只读头部得到格式与尺寸，不是受支持的图片时返回 ErrTypeMismatch
+	Inspect(data []byte) (ImageInfo, error)
+	// StripLocation 去掉 EXIF/XMP 中的地理位置信息，返回处理后的内容及是否有改动
+	StripLocation(format string, data []byte) ([]byte, bool)
+	// Thumbnail 等比缩放到最长边不超过 maxEdge，编码为 JPEG
+	Thumbnail(data []byte, maxEdge int) ([]byte, int, int, error)
+}
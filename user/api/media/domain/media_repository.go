@@ -0,0 +1,16 @@
+package domain
+
+import "context"
+
+// MediaRepository 媒体元数据及上传者
+type MediaRepository interface {
+	// Save 幂等：媒体已存在时只登记上传者，并回填已有记录的 CreatedAt
+	Save(ctx context.Context, m *Media, ownerID uint) error
+	Get(ctx context.Context, id string) (*Media, error)
+	IsOwner(ctx context.Context, id string, userID uint) (bool, error)
+	// SaveThumbnail 登记 sourceID 的缩略图，同一尺寸重复登记时覆盖
+	SaveThumbnail(ctx context.Context, sourceID string, t Thumbnail) error
+	ListThumbnails(ctx context.Context, sourceID string) ([]Thumbnail, error)
+	// ThumbnailSources 以 thumbID 为缩略图的原图
+	ThumbnailSources(ctx context.Context, thumbID string) ([]string, error)
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// 媒体状态
+const (
+	StatusActive = "active"
+	// StatusQuarantined 待管理员复核，期间不可下载、不可在消息中引用
+	StatusQuarantined = "quarantined"
+	// StatusRejected 确认有害，内容已删除；再次上传相同内容直接拒绝
+	StatusRejected = "rejected"
+)
+
+var (
+	// ErrQuarantined 媒体已被隔离
+	ErrQuarantined = errors.New("media quarantined")
+	// ErrRejected 内容被扫描拒绝
+	ErrRejected = errors.New("media rejected")
+	// ErrScanUnavailable 扫描服务不可用，上传失败，由客户端重试
+	ErrScanUnavailable = errors.New("content scanner unavailable")
+)
+
+// Verdict 扫描结论
+type Verdict string
+
+const (
+	VerdictAllow      Verdict = "allow"
+	VerdictQuarantine Verdict = "quarantine"
+	VerdictReject     Verdict = "reject"
+)
+
+type ScanResult struct {
+	Verdict Verdict
+	// Reason 命中的规则或特征名，放行时为空
+	Reason string
+}
+
+// ContentScanner 上传内容扫描，在写入存储前调用
+type ContentScanner interface {
+	Scan(ctx context.Context, r io.Reader) (ScanResult, error)
+}
+
+// ScanReport 被隔离或拒绝的上传，供管理员复核
+type ScanReport struct {
+	MediaID    string
+	OwnerID    uint
+	Verdict    Verdict
+	Reason     string
+	CreatedAt  time.Time
+	ResolvedAt time.Time
+	// Resolution 复核结论：active（放行）或 rejected，未复核为空
+	Resolution string
+}
+
+// ScanReportRepository 扫描报告
+type ScanReportRepository interface {
+	Report(ctx context.Context, r *ScanReport) error
+	// ListReports unresolved 为 true 时只列未复核的，按时间倒序
+	ListReports(ctx context.Context, unresolved bool, limit int) ([]ScanReport, error)
+	// Resolve 把该媒体的未复核报告标记为 resolution，并更新媒体状态；返回被标记的报告
+	Resolve(ctx context.Context, mediaID, resolution string, at time.Time) ([]ScanReport, error)
+}
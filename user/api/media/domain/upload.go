@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrUploadNotFound 上传会话不存在、已完成或已过期被清理
+	ErrUploadNotFound = errors.New("upload not found")
+	// ErrUploadIncomplete 还有分片未上传
+	ErrUploadIncomplete = errors.New("upload incomplete")
+	// ErrChecksumMismatch 分片内容与声明的校验和不符
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+)
+
+// Upload 一次可续传的分片上传。分片按固定大小切分，最后一片可以更小
+type Upload struct {
+	ID        string
+	OwnerID   uint
+	Size      int64
+	ChunkSize int64
+	// MIME 客户端声明的类型，complete 时与内容比对
+	MIME      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ChunkCount 分片总数
+func (u *Upload) ChunkCount() int {
+	return int((u.Size + u.ChunkSize - 1) / u.ChunkSize)
+}
+
+// ChunkLen 第 index 片应有的长度
+func (u *Upload) ChunkLen(index int) int64 {
+	if rest := u.Size - int64(index)*u.ChunkSize; rest < u.ChunkSize {
+		return rest
+	}
+	return u.ChunkSize
+}
+
+// ChunkKey 分片在 BlobStore 中的 key
+func (u *Upload) ChunkKey(index int) string {
+	return ChunkKey(u.ID, index)
+}
+
+func ChunkKey(uploadID string, index int) string {
+	return "upload-" + uploadID + "-" + strconv.Itoa(index)
+}
+
+// Chunk 已收到的分片
+type Chunk struct {
+	Index    int
+	Size     int64
+	Checksum string
+}
+
+// UploadRepository 分片上传会话
+type UploadRepository interface {
+	Create(ctx context.Context, u *Upload) error
+	Get(ctx context.Context, id string) (*Upload, error)
+	// SaveChunk 同一分片重传时覆盖，并刷新上传会话的 UpdatedAt
+	SaveChunk(ctx context.Context, uploadID string, c Chunk) error
+	ListChunks(ctx context.Context, uploadID string) ([]Chunk, error)
+	// Delete 删除上传会话及分片记录
+	Delete(ctx context.Context, id string) error
+	// ListStale UpdatedAt 早于 before 的上传会话
+	ListStale(ctx context.Context, before time.Time, limit int) ([]Upload, error)
+}
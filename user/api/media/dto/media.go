@@ -0,0 +1,69 @@
+package dto
+
+import (
+	"time"
+
+	"wsim/user/api/media/domain"
+)
+
+// Media 上传结果；MediaID 填入图片/语音/视频/文件消息的 media 字段
+type Media struct {
+	MediaID   string    `json:"media_id"`
+	Size      int64     `json:"size"`
+	MIME      string    `json:"mime"`
+	Width     int       `json:"width,omitempty"`
+	Height    int       `json:"height,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// Thumbnails 仅媒体信息接口返回；图片上传后在后台生成，可填入图片消息的 thumbnail 字段
+	Thumbnails []Thumbnail `json:"thumbnails,omitempty"`
+}
+
+type Thumbnail struct {
+	Size    int    `json:"size"`
+	MediaID string `json:"media_id"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+}
+
+func FromMedia(m *domain.Media) Media {
+	return Media{MediaID: m.ID, Size: m.Size, MIME: m.MIME, Width: m.Width, Height: m.Height, CreatedAt: m.CreatedAt}
+}
+
+func FromMediaInfo(m *domain.Media, thumbs []domain.Thumbnail) Media {
+	out := FromMedia(m)
+	for _, t := range thumbs {
+		out.Thumbnails = append(out.Thumbnails, Thumbnail{Size: t.Size, MediaID: t.MediaID, Width: t.Width, Height: t.Height})
+	}
+	return out
+}
+
+// ScanReport 管理员复核用的扫描报告
+type ScanReport struct {
+	MediaID    string     `json:"media_id"`
+	OwnerID    uint       `json:"owner_id"`
+	Verdict    string     `json:"verdict"`
+	Reason     string     `json:"reason"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+	Resolution string     `json:"resolution,omitempty"`
+}
+
+type ScanReportListResponse struct {
+	Reports []ScanReport `json:"reports"`
+}
+
+func FromScanReport(r *domain.ScanReport) ScanReport {
+	out := ScanReport{
+		MediaID:    r.MediaID,
+		OwnerID:    r.OwnerID,
+		Verdict:    string(r.Verdict),
+		Reason:     r.Reason,
+		CreatedAt:  r.CreatedAt,
+		Resolution: r.Resolution,
+	}
+	if !r.ResolvedAt.IsZero() {
+		t := r.ResolvedAt
+		out.ResolvedAt = &t
+	}
+	return out
+}
@@ -0,0 +1,42 @@
+package dto
+
+import (
+	"time"
+
+	"wsim/user/api/media/domain"
+)
+
+type InitUploadRequest struct {
+	Size int64 `json:"size"`
+	// ChunkSize 可选，缺省 4MB，范围 256KB–16MB
+	ChunkSize int64 `json:"chunk_size"`
+	// MIME 可选，声明的文件类型；与内容不符时 complete 失败
+	MIME string `json:"mime"`
+}
+
+// UploadStatus 分片上传状态；Received 为已到达的分片序号，分片 i 的偏移为 i*chunk_size
+type UploadStatus struct {
+	UploadID      string    `json:"upload_id"`
+	Size          int64     `json:"size"`
+	ChunkSize     int64     `json:"chunk_size"`
+	ChunkCount    int       `json:"chunk_count"`
+	Received      []int     `json:"received"`
+	ReceivedBytes int64     `json:"received_bytes"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+func FromUpload(u *domain.Upload, chunks []domain.Chunk, ttl time.Duration) UploadStatus {
+	out := UploadStatus{
+		UploadID:   u.ID,
+		Size:       u.Size,
+		ChunkSize:  u.ChunkSize,
+		ChunkCount: u.ChunkCount(),
+		Received:   make([]int, 0, len(chunks)),
+		ExpiresAt:  u.UpdatedAt.Add(ttl),
+	}
+	for _, c := range chunks {
+		out.Received = append(out.Received, c.Index)
+		out.ReceivedBytes += c.Size
+	}
+	return out
+}
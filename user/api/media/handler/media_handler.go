@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"wsim/user/api/identity"
+	"wsim/user/api/media/domain"
+	"wsim/user/api/media/dto"
+	"wsim/user/api/media/usecase"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+)
+
+type MediaHandler struct {
+	media *usecase.MediaService
+}
+
+func NewMediaHandler(media *usecase.MediaService) *MediaHandler {
+	return &MediaHandler{media: media}
+}
+
+// Upload multipart 表单，文件字段名 file；文件部分的 Content-Type 视为声明类型
+func (h *MediaHandler) Upload(ctx context.Context, c *app.RequestContext) {
+	uid, err := identity.UserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	fh, err := c.FormFile("file")
+	if err != nil {
+		writeErr(c, usecase.ErrBadRequest)
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	defer f.Close()
+	m, err := h.media.Upload(ctx, uid, f, fh.Header.Get("Content-Type"))
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.FromMedia(m))
+}
+
+// Download 只有上传者与引用该媒体的会话成员可以下载
+func (h *MediaHandler) Download(ctx context.Context, c *app.RequestContext) {
+	uid, err := identity.UserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	m, rc, err := h.media.Open(ctx, uid, c.Param("media_id"))
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	// 内容寻址，同一 ID 内容永不变化
+	c.Header("ETag", `"`+m.ID+`"`)
+	c.Header("Cache-Control", "private, max-age=31536000, immutable")
+	c.Header("X-Content-Type-Options", "nosniff")
+	if string(c.GetHeader("If-None-Match")) == `"`+m.ID+`"` {
+		rc.Close()
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.SetContentType(m.MIME)
+	c.SetBodyStream(rc, int(m.Size))
+}
+
+// Info 媒体元数据（尺寸、缩略图）
+func (h *MediaHandler) Info(ctx context.Context, c *app.RequestContext) {
+	uid, err := identity.UserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	m, thumbs, err := h.media.Info(ctx, uid, c.Param("media_id"))
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.FromMediaInfo(m, thumbs))
+}
+
+func writeErr(c *app.RequestContext, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrBadRequest):
+		c.JSON(http.StatusBadRequest, utils.H{"error": "bad request"})
+	case errors.Is(err, identity.ErrUnauthorized):
+		c.JSON(http.StatusUnauthorized, utils.H{"error": "unauthorized"})
+	case errors.Is(err, usecase.ErrForbidden), errors.Is(err, identity.ErrForbidden):
+		c.JSON(http.StatusForbidden, utils.H{"error": "forbidden"})
+	case errors.Is(err, domain.ErrMediaNotFound):
+		c.JSON(http.StatusNotFound, utils.H{"error": "media not found"})
+	case errors.Is(err, domain.ErrUploadNotFound):
+		c.JSON(http.StatusNotFound, utils.H{"error": "upload not found"})
+	case errors.Is(err, domain.ErrUploadIncomplete):
+		c.JSON(http.StatusConflict, utils.H{"error": "upload incomplete"})
+	case errors.Is(err, domain.ErrQuarantined):
+		c.JSON(http.StatusForbidden, utils.H{"error": "media quarantined"})
+	case errors.Is(err, domain.ErrRejected):
+		c.JSON(http.StatusUnprocessableEntity, utils.H{"error": "media rejected"})
+	case errors.Is(err, domain.ErrScanUnavailable):
+		c.JSON(http.StatusServiceUnavailable, utils.H{"error": "content scanner unavailable"})
+	case errors.Is(err, domain.ErrTypeMismatch):
+		c.JSON(http.StatusUnsupportedMediaType, utils.H{"error": "content does not match declared type"})
+	case errors.Is(err, domain.ErrChecksumMismatch):
+		c.JSON(http.StatusUnprocessableEntity, utils.H{"error": "checksum mismatch"})
+	case errors.Is(err, usecase.ErrTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, utils.H{"error": "media too large"})
+	default:
+		c.JSON(http.StatusInternalServerError, utils.H{"error": err.Error()})
+	}
+}
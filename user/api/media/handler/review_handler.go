@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"wsim/user/api/identity"
+	"wsim/user/api/media/dto"
+	"wsim/user/api/media/usecase"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// ReviewHandler 管理员复核被隔离的媒体
+type ReviewHandler struct {
+	media *usecase.MediaService
+}
+
+func NewReviewHandler(media *usecase.MediaService) *ReviewHandler {
+	return &ReviewHandler{media: media}
+}
+
+// Reports GET ?unresolved=true&limit=
+func (h *ReviewHandler) Reports(ctx context.Context, c *app.RequestContext) {
+	if _, err := identity.AdminID(c); err != nil {
+		writeErr(c, err)
+		return
+	}
+	unresolved, _ := strconv.ParseBool(c.Query("unresolved"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	reports, err := h.media.Reports(ctx, unresolved, limit)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	res := dto.ScanReportListResponse{Reports: make([]dto.ScanReport, 0, len(reports))}
+	for i := range reports {
+		res.Reports = append(res.Reports, dto.FromScanReport(&reports[i]))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// Download 复核时下载原始内容，一律作为附件返回
+func (h *ReviewHandler) Download(ctx context.Context, c *app.RequestContext) {
+	if _, err := identity.AdminID(c); err != nil {
+		writeErr(c, err)
+		return
+	}
+	m, rc, err := h.media.OpenForReview(ctx, c.Param("media_id"))
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+m.ID+`"`)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.SetContentType("application/octet-stream")
+	c.SetBodyStream(rc, int(m.Size))
+}
+
+func (h *ReviewHandler) Release(ctx context.Context, c *app.RequestContext) {
+	if _, err := identity.AdminID(c); err != nil {
+		writeErr(c, err)
+		return
+	}
+	if err := h.media.Release(ctx, c.Param("media_id")); err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *ReviewHandler) Reject(ctx context.Context, c *app.RequestContext) {
+	if _, err := identity.AdminID(c); err != nil {
+		writeErr(c, err)
+		return
+	}
+	if err := h.media.Reject(ctx, c.Param("media_id")); err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"wsim/user/api/identity"
+	"wsim/user/api/media/dto"
+	"wsim/user/api/media/usecase"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+type UploadHandler struct {
+	uploads *usecase.UploadService
+}
+
+func NewUploadHandler(uploads *usecase.UploadService) *UploadHandler {
+	return &UploadHandler{uploads: uploads}
+}
+
+// Init POST {size, chunk_size, mime}
+func (h *UploadHandler) Init(ctx context.Context, c *app.RequestContext) {
+	uid, err := identity.UserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	var req dto.InitUploadRequest
+	if err := c.BindJSON(&req); err != nil {
+		writeErr(c, usecase.ErrBadRequest)
+		return
+	}
+	u, err := h.uploads.Init(ctx, uid, req.Size, req.ChunkSize, req.MIME)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, dto.FromUpload(u, nil, h.uploads.TTL))
+}
+
+// PutChunk PUT ?offset=，请求体为分片原始字节，X-Chunk-SHA256 为分片的 SHA-256
+func (h *UploadHandler) PutChunk(ctx context.Context, c *app.RequestContext) {
+	uid, err := identity.UserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	offset, err := strconv.ParseInt(c.Query("offset"), 10, 64)
+	if err != nil {
+		writeErr(c, usecase.ErrBadRequest)
+		return
+	}
+	err = h.uploads.PutChunk(ctx, uid, c.Param("upload_id"), offset, c.Request.Body(), string(c.GetHeader("X-Chunk-SHA256")))
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Status 查询已到达的分片，断线重连后据此续传
+func (h *UploadHandler) Status(ctx context.Context, c *app.RequestContext) {
+	uid, err := identity.UserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	u, chunks, err := h.uploads.Status(ctx, uid, c.Param("upload_id"))
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.FromUpload(u, chunks, h.uploads.TTL))
+}
+
+// Complete 拼装文件，返回与普通上传相同的媒体信息
+func (h *UploadHandler) Complete(ctx context.Context, c *app.RequestContext) {
+	uid, err := identity.UserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	m, err := h.uploads.Complete(ctx, uid, c.Param("upload_id"))
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.FromMedia(m))
+}
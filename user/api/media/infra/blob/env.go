@@ -0,0 +1,31 @@
+package blob
+
+import (
+	"fmt"
+	"os"
+
+	"wsim/user/api/media/domain"
+)
+
+// NewFromEnv 按环境变量选择存储：MEDIA_STORE=local（默认，目录 MEDIA_DIR，默认 data/media）
+// 或 MEDIA_STORE=s3（S3_ENDPOINT、S3_REGION、S3_BUCKET、S3_ACCESS_KEY_ID、S3_SECRET_ACCESS_KEY）
+func NewFromEnv() (domain.BlobStore, error) {
+	switch kind := os.Getenv("MEDIA_STORE"); kind {
+	case "", "local":
+		dir := os.Getenv("MEDIA_DIR")
+		if dir == "" {
+			dir = "data/media"
+		}
+		return NewLocalStore(dir)
+	case "s3":
+		return NewS3Store(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		})
+	default:
+		return nil, fmt.Errorf("unknown MEDIA_STORE: %q", kind)
+	}
+}
@@ -0,0 +1,133 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"wsim/user/api/media/domain"
+)
+
+// fakeS3 本地的 S3 替身：内存保存对象，并按服务端视角重新计算 SigV4 签名
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	secret  string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !f.verify(r) {
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		b, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = b
+	case http.MethodGet, http.MethodHead:
+		b, ok := f.objects[r.URL.Path]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Write(b)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (f *fakeS3) verify(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	i := strings.Index(auth, "Signature=")
+	j := strings.Index(auth, "Credential=")
+	if i < 0 || j < 0 {
+		return false
+	}
+	scope := strings.SplitN(strings.SplitN(auth[j+len("Credential="):], ",", 2)[0], "/", 2)[1]
+	parts := strings.Split(scope, "/")
+	amzDate := r.Header.Get("x-amz-date")
+	payload := r.Header.Get("x-amz-content-sha256")
+	canonical := r.Method + "\n" + r.URL.EscapedPath() + "\n" + r.URL.Query().Encode() + "\n" +
+		"host:" + r.Host + "\nx-amz-content-sha256:" + payload + "\nx-amz-date:" + amzDate + "\n\n" +
+		"host;x-amz-content-sha256;x-amz-date\n" + payload
+	sum := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+	key := []byte("AWS4" + f.secret)
+	for _, p := range parts {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(p))
+		key = h.Sum(nil)
+	}
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(toSign))
+	return hmac.Equal([]byte(hex.EncodeToString(h.Sum(nil))), []byte(auth[i+len("Signature="):]))
+}
+
+func newTestS3(t *testing.T, secret string) *S3Store {
+	t.Helper()
+	srv := httptest.NewServer(&fakeS3{objects: map[string][]byte{}, secret: "server-secret"})
+	t.Cleanup(srv.Close)
+	s, err := NewS3Store(S3Config{Endpoint: srv.URL, Bucket: "media", AccessKey: "ak", SecretKey: secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestS3StoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	s := newTestS3(t, "server-secret")
+	body := []byte("hello media")
+
+	if ok, err := s.Exists(ctx, "abc123"); err != nil || ok {
+		t.Fatalf("exists before put = %v, %v", ok, err)
+	}
+	if err := s.Put(ctx, "abc123", bytes.NewReader(body), int64(len(body))); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if ok, err := s.Exists(ctx, "abc123"); err != nil || !ok {
+		t.Fatalf("exists after put = %v, %v", ok, err)
+	}
+	rc, size, err := s.Get(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	if !bytes.Equal(got, body) || size != int64(len(body)) {
+		t.Fatalf("get = %q (%d), want %q", got, size, body)
+	}
+	if err := s.Delete(ctx, "abc123"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, _, err := s.Get(ctx, "abc123"); !errors.Is(err, domain.ErrBlobNotFound) {
+		t.Fatalf("get after delete err = %v, want ErrBlobNotFound", err)
+	}
+}
+
+func TestS3StoreBadSignature(t *testing.T) {
+	s := newTestS3(t, "wrong-secret")
+	err := s.Put(context.Background(), "abc123", strings.NewReader("x"), 1)
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("put with wrong secret err = %v, want 403", err)
+	}
+}
+
+func TestS3StoreRejectsPathKeys(t *testing.T) {
+	s := newTestS3(t, "server-secret")
+	if err := s.Put(context.Background(), "../etc/passwd", strings.NewReader("x"), 1); err == nil {
+		t.Fatal("put with path traversal key succeeded")
+	}
+}
@@ -0,0 +1,123 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"wsim/user/api/media/domain"
+)
+
+// Processor 基于标准库的图片处理，支持 JPEG、PNG、GIF（GIF 取第一帧）
+type Processor struct {
+	// JPEGQuality 缩略图质量，默认 80
+	JPEGQuality int
+}
+
+func NewProcessor() *Processor {
+	return &Processor{JPEGQuality: 80}
+}
+
+func (p *Processor) Inspect(data []byte) (domain.ImageInfo, error) {
+	r := bytes.NewReader(data)
+	var (
+		cfg image.Config
+		err error
+	)
+	format := ""
+	switch {
+	case bytes.HasPrefix(data, []byte("\xff\xd8\xff")):
+		format = "jpeg"
+		cfg, err = jpeg.DecodeConfig(r)
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		format = "png"
+		cfg, err = png.DecodeConfig(r)
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		format = "gif"
+		cfg, err = gif.DecodeConfig(r)
+	default:
+		return domain.ImageInfo{}, domain.ErrTypeMismatch
+	}
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 {
+		return domain.ImageInfo{}, domain.ErrTypeMismatch
+	}
+	return domain.ImageInfo{Format: format, Width: cfg.Width, Height: cfg.Height}, nil
+}
+
+func (p *Processor) StripLocation(format string, data []byte) ([]byte, bool) {
+	switch format {
+	case "jpeg":
+		return stripJPEG(data)
+	case "png":
+		return stripPNG(data)
+	}
+	return data, false
+}
+
+func (p *Processor) Thumbnail(data []byte, maxEdge int) ([]byte, int, int, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, domain.ErrTypeMismatch
+	}
+	b := src.Bounds()
+	w, h := fit(b.Dx(), b.Dy(), maxEdge)
+
+	// 透明区域铺白底，JPEG 不支持透明
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Over)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, boxResize(rgba, w, h), &jpeg.Options{Quality: p.JPEGQuality}); err != nil {
+		return nil, 0, 0, err
+	}
+	return buf.Bytes(), w, h, nil
+}
+
+// fit 等比缩放到最长边不超过 maxEdge，不放大
+func fit(w, h, maxEdge int) (int, int) {
+	if w <= maxEdge && h <= maxEdge {
+		return w, h
+	}
+	if w >= h {
+		return maxEdge, max(1, h*maxEdge/w)
+	}
+	return max(1, w*maxEdge/h), maxEdge
+}
+
+// boxResize 区域平均缩小：每个目标像素取其覆盖的源像素均值，缩小时不产生锯齿
+func boxResize(src *image.RGBA, w, h int) *image.RGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	if sw == w && sh == h {
+		return src
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := y*sh/h, max((y+1)*sh/h, y*sh/h+1)
+		for x := 0; x < w; x++ {
+			x0, x1 := x*sw/w, max((x+1)*sw/w, x*sw/w+1)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					px := row[sx*4 : sx*4+4]
+					r += uint64(px[0])
+					g += uint64(px[1])
+					b += uint64(px[2])
+					a += uint64(px[3])
+					n++
+				}
+			}
+			i := y*dst.Stride + x*4
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,153 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+var (
+	exifHeader = []byte("Exif\x00\x00")
+	xmpHeader  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+)
+
+// stripJPEG 清空 EXIF 中的 GPS IFD（保留方向等其他标签），并去掉可能含位置的 XMP 段
+func stripJPEG(data []byte) ([]byte, bool) {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return data, false
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	changed := false
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xff {
+			break
+		}
+		marker := data[i+1]
+		// SOS 之后是压缩数据，原样保留
+		if marker == 0xda {
+			break
+		}
+		n := int(binary.BigEndian.Uint16(data[i+2:]))
+		if n < 2 || i+2+n > len(data) {
+			break
+		}
+		seg := data[i : i+2+n]
+		payload := seg[4:]
+		switch {
+		case marker == 0xe1 && bytes.HasPrefix(payload, xmpHeader):
+			changed = true
+		case marker == 0xe1 && bytes.HasPrefix(payload, exifHeader):
+			seg = append([]byte(nil), seg...)
+			if scrubGPS(seg[4+len(exifHeader):]) {
+				changed = true
+			}
+			out = append(out, seg...)
+		default:
+			out = append(out, seg...)
+		}
+		i += 2 + n
+	}
+	if !changed {
+		return data, false
+	}
+	return append(out, data[i:]...), true
+}
+
+// scrubGPS 在 TIFF 结构内原地清零 GPS IFD 及其引用的数据，长度不变，其他偏移不受影响
+func scrubGPS(t []byte) bool {
+	if len(t) < 8 {
+		return false
+	}
+	var bo binary.ByteOrder
+	switch string(t[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return false
+	}
+	ifd0 := int(bo.Uint32(t[4:]))
+	if ifd0 < 8 || ifd0+2 > len(t) {
+		return false
+	}
+	n := int(bo.Uint16(t[ifd0:]))
+	for k := 0; k < n; k++ {
+		e := ifd0 + 2 + 12*k
+		if e+12 > len(t) {
+			return false
+		}
+		if bo.Uint16(t[e:]) == 0x8825 {
+			return zeroIFD(t, int(bo.Uint32(t[e+8:])), bo)
+		}
+	}
+	return false
+}
+
+func zeroIFD(t []byte, off int, bo binary.ByteOrder) bool {
+	if off < 8 || off+2 > len(t) {
+		return false
+	}
+	n := int(bo.Uint16(t[off:]))
+	end := off + 2 + 12*n + 4
+	if end > len(t) {
+		return false
+	}
+	for k := 0; k < n; k++ {
+		e := off + 2 + 12*k
+		size := typeSize(bo.Uint16(t[e+2:])) * int(bo.Uint32(t[e+4:]))
+		if size > 4 {
+			vo := int(bo.Uint32(t[e+8:]))
+			if vo >= 0 && size <= len(t) && vo <= len(t)-size {
+				clear(t[vo : vo+size])
+			}
+		}
+	}
+	// 条目数置 0，下一 IFD 指针也随之清零
+	clear(t[off:end])
+	return true
+}
+
+func typeSize(typ uint16) int {
+	switch typ {
+	case 3, 8: // SHORT, SSHORT
+		return 2
+	case 4, 9, 11: // LONG, SLONG, FLOAT
+		return 4
+	case 5, 10, 12: // RATIONAL, SRATIONAL, DOUBLE
+		return 8
+	}
+	return 1
+}
+
+// stripPNG 去掉 eXIf 块与 XMP 文本块
+func stripPNG(data []byte) ([]byte, bool) {
+	const sigLen = 8
+	if len(data) < sigLen {
+		return data, false
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:sigLen]...)
+	changed := false
+	i := sigLen
+	for i+12 <= len(data) {
+		n := int(binary.BigEndian.Uint32(data[i:]))
+		if n < 0 || i+12+n > len(data) {
+			break
+		}
+		typ := string(data[i+4 : i+8])
+		body := data[i+8 : i+8+n]
+		chunk := data[i : i+12+n]
+		i += 12 + n
+		if typ == "eXIf" || (typ == "iTXt" && bytes.HasPrefix(body, []byte("XML:com.adobe.xmp\x00"))) {
+			changed = true
+			continue
+		}
+		out = append(out, chunk...)
+	}
+	if !changed {
+		return data, false
+	}
+	return append(out, data[i:]...), true
+}
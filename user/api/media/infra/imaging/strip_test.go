@@ -0,0 +1,70 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+// exifWithGPS 构造一个含 GPS IFD 的 APP1 段：IFD0 只有 GPSInfo 指针，GPS IFD 有一个纬度 RATIONAL×3
+func exifWithGPS() []byte {
+	le := binary.LittleEndian
+	t := make([]byte, 0, 128)
+	t = append(t, 'I', 'I', 42, 0)
+	t = le.AppendUint32(t, 8)
+	// IFD0 @8：1 个条目 + next
+	t = le.AppendUint16(t, 1)
+	t = le.AppendUint16(t, 0x8825)
+	t = le.AppendUint16(t, 4)
+	t = le.AppendUint32(t, 1)
+	t = le.AppendUint32(t, 26) // GPS IFD 偏移
+	t = le.AppendUint32(t, 0)
+	// GPS IFD @26：GPSLatitude RATIONAL×3，值在 @44
+	t = le.AppendUint16(t, 1)
+	t = le.AppendUint16(t, 2)
+	t = le.AppendUint16(t, 5)
+	t = le.AppendUint32(t, 3)
+	t = le.AppendUint32(t, 44)
+	t = le.AppendUint32(t, 0)
+	for _, v := range []uint32{39, 1, 54, 1, 1234, 100} {
+		t = le.AppendUint32(t, v)
+	}
+	payload := append([]byte("Exif\x00\x00"), t...)
+	seg := []byte{0xff, 0xe1}
+	seg = binary.BigEndian.AppendUint16(seg, uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+func TestStripJPEGLocation(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	raw := buf.Bytes()
+	withExif := append(append(append([]byte(nil), raw[:2]...), exifWithGPS()...), raw[2:]...)
+	lat := binary.LittleEndian.AppendUint32(nil, 1234)
+	if !bytes.Contains(withExif, lat) {
+		t.Fatal("fixture missing latitude")
+	}
+
+	p := NewProcessor()
+	out, changed := p.StripLocation("jpeg", withExif)
+	if !changed {
+		t.Fatal("expected location to be stripped")
+	}
+	if bytes.Contains(out, lat) {
+		t.Fatal("latitude still present")
+	}
+	if len(out) != len(withExif) {
+		t.Fatalf("exif segment length changed: %d -> %d", len(withExif), len(out))
+	}
+	info, err := p.Inspect(out)
+	if err != nil || info.Width != 8 || info.Height != 8 {
+		t.Fatalf("inspect after strip = %+v, %v", info, err)
+	}
+	if _, changed := p.StripLocation("jpeg", raw); changed {
+		t.Fatal("jpeg without exif reported as changed")
+	}
+}
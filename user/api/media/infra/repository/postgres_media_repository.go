@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"wsim/user/api/media/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MediaModel 媒体元数据，主键即内容哈希
+type MediaModel struct {
+	ID        string    `gorm:"type:char(64);primaryKey"`
+	Size      int64     `gorm:"not null"`
+	MIME      string    `gorm:"column:mime;type:varchar(128);not null"`
+	Width     int       `gorm:"not null;default:0"`
+	Height    int       `gorm:"not null;default:0"`
+	Status    string    `gorm:"type:varchar(16);not null;default:'active'"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+func (MediaModel) TableName() string { return "media" }
+
+// ThumbnailModel 原图与缩略图的对应关系；thumb_id 索引用于缩略图的访问鉴权
+type ThumbnailModel struct {
+	SourceID string `gorm:"type:char(64);primaryKey"`
+	Size     int    `gorm:"primaryKey;autoIncrement:false"`
+	ThumbID  string `gorm:"type:char(64);not null;index"`
+	Width    int    `gorm:"not null"`
+	Height   int    `gorm:"not null"`
+}
+
+func (ThumbnailModel) TableName() string { return "media_thumbnails" }
+
+// OwnerModel 上传过该内容的用户；同一内容可被多人上传
+type OwnerModel struct {
+	MediaID   string    `gorm:"type:char(64);primaryKey"`
+	UserID    uint      `gorm:"primaryKey;autoIncrement:false"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+func (OwnerModel) TableName() string { return "media_owners" }
+
+type PostgresMediaRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresMediaRepository(db *gorm.DB) (*PostgresMediaRepository, error) {
+	if err := db.AutoMigrate(&MediaModel{}, &OwnerModel{}, &ThumbnailModel{}); err != nil {
+		return nil, err
+	}
+	return &PostgresMediaRepository{db: db}, nil
+}
+
+func (r *PostgresMediaRepository) Save(ctx context.Context, m *domain.Media, ownerID uint) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		status := m.Status
+		if status == "" {
+			status = domain.StatusActive
+		}
+		mm := &MediaModel{ID: m.ID, Size: m.Size, MIME: m.MIME, Width: m.Width, Height: m.Height, Status: status, CreatedAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(mm).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id = ?", m.ID).Take(mm).Error; err != nil {
+			return err
+		}
+		m.CreatedAt, m.Status = mm.CreatedAt, mm.Status
+		owner := &OwnerModel{MediaID: m.ID, UserID: ownerID, CreatedAt: now}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(owner).Error
+	})
+}
+
+func (r *PostgresMediaRepository) Get(ctx context.Context, id string) (*domain.Media, error) {
+	var m MediaModel
+	tx := r.db.WithContext(ctx).Where("id = ?", id).Limit(1).Find(&m)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return nil, domain.ErrMediaNotFound
+	}
+	return &domain.Media{
+		ID:        m.ID,
+		Size:      m.Size,
+		MIME:      m.MIME,
+		Width:     m.Width,
+		Height:    m.Height,
+		Status:    m.Status,
+		CreatedAt: m.CreatedAt,
+	}, nil
+}
+
+func (r *PostgresMediaRepository) IsOwner(ctx context.Context, id string, userID uint) (bool, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&OwnerModel{}).
+		Where("media_id = ? AND user_id = ?", id, userID).
+		Count(&n).Error
+	return n > 0, err
+}
+
+func (r *PostgresMediaRepository) SaveThumbnail(ctx context.Context, sourceID string, t domain.Thumbnail) error {
+	m := &ThumbnailModel{SourceID: sourceID, Size: t.Size, ThumbID: t.MediaID, Width: t.Width, Height: t.Height}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(m).Error
+}
+
+func (r *PostgresMediaRepository) ListThumbnails(ctx context.Context, sourceID string) ([]domain.Thumbnail, error) {
+	var ms []ThumbnailModel
+	if err := r.db.WithContext(ctx).Where("source_id = ?", sourceID).Order("size").Find(&ms).Error; err != nil {
+		return nil, err
+	}
+	out := make([]domain.Thumbnail, 0, len(ms))
+	for _, m := range ms {
+		out = append(out, domain.Thumbnail{Size: m.Size, MediaID: m.ThumbID, Width: m.Width, Height: m.Height})
+	}
+	return out, nil
+}
+
+func (r *PostgresMediaRepository) ThumbnailSources(ctx context.Context, thumbID string) ([]string, error) {
+	var ids []string
+	err := r.db.WithContext(ctx).Model(&ThumbnailModel{}).
+		Where("thumb_id = ?", thumbID).
+		Distinct().Pluck("source_id", &ids).Error
+	return ids, err
+}
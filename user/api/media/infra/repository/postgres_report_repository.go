@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"wsim/user/api/media/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReportModel 扫描报告：被隔离或拒绝的上传
+type ReportModel struct {
+	ID         uint64    `gorm:"primaryKey"`
+	MediaID    string    `gorm:"type:char(64);not null;index"`
+	OwnerID    uint      `gorm:"not null"`
+	Verdict    string    `gorm:"type:varchar(16);not null"`
+	Reason     string    `gorm:"type:varchar(255);not null;default:''"`
+	CreatedAt  time.Time `gorm:"not null;index"`
+	ResolvedAt *time.Time
+	Resolution string `gorm:"type:varchar(16);not null;default:''"`
+}
+
+func (ReportModel) TableName() string { return "media_scan_reports" }
+
+type PostgresScanReportRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresScanReportRepository(db *gorm.DB) (*PostgresScanReportRepository, error) {
+	if err := db.AutoMigrate(&ReportModel{}); err != nil {
+		return nil, err
+	}
+	return &PostgresScanReportRepository{db: db}, nil
+}
+
+func (r *PostgresScanReportRepository) Report(ctx context.Context, rep *domain.ScanReport) error {
+	m := &ReportModel{
+		MediaID:   rep.MediaID,
+		OwnerID:   rep.OwnerID,
+		Verdict:   string(rep.Verdict),
+		Reason:    truncate(rep.Reason, 255),
+		CreatedAt: time.Now(),
+	}
+	if err := r.db.WithContext(ctx).Create(m).Error; err != nil {
+		return err
+	}
+	rep.CreatedAt = m.CreatedAt
+	return nil
+}
+
+func (r *PostgresScanReportRepository) ListReports(ctx context.Context, unresolved bool, limit int) ([]domain.ScanReport, error) {
+	q := r.db.WithContext(ctx)
+	if unresolved {
+		q = q.Where("resolved_at IS NULL")
+	}
+	var ms []ReportModel
+	if err := q.Order("created_at DESC").Limit(limit).Find(&ms).Error; err != nil {
+		return nil, err
+	}
+	return toReports(ms), nil
+}
+
+func (r *PostgresScanReportRepository) Resolve(ctx context.Context, mediaID, resolution string, at time.Time) ([]domain.ScanReport, error) {
+	var ms []ReportModel
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&ms).
+			Clauses(clause.Returning{}).
+			Where("media_id = ? AND resolved_at IS NULL", mediaID).
+			Updates(map[string]any{"resolved_at": at, "resolution": resolution}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&MediaModel{}).Where("id = ?", mediaID).Update("status", resolution).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toReports(ms), nil
+}
+
+func toReports(ms []ReportModel) []domain.ScanReport {
+	out := make([]domain.ScanReport, 0, len(ms))
+	for _, m := range ms {
+		rep := domain.ScanReport{
+			MediaID:    m.MediaID,
+			OwnerID:    m.OwnerID,
+			Verdict:    domain.Verdict(m.Verdict),
+			Reason:     m.Reason,
+			CreatedAt:  m.CreatedAt,
+			Resolution: m.Resolution,
+		}
+		if m.ResolvedAt != nil {
+			rep.ResolvedAt = *m.ResolvedAt
+		}
+		out = append(out, rep)
+	}
+	return out
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
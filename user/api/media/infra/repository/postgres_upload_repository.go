@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"wsim/user/api/media/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UploadModel 分片上传会话；UpdatedAt 索引用于清理长时间无进展的上传
+type UploadModel struct {
+	ID        string    `gorm:"type:varchar(32);primaryKey"`
+	OwnerID   uint      `gorm:"not null"`
+	Size      int64     `gorm:"not null"`
+	ChunkSize int64     `gorm:"not null"`
+	MIME      string    `gorm:"column:mime;type:varchar(128);not null;default:''"`
+	CreatedAt time.Time `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null;index"`
+}
+
+func (UploadModel) TableName() string { return "media_uploads" }
+
+type ChunkModel struct {
+	UploadID string `gorm:"type:varchar(32);primaryKey"`
+	Index    int    `gorm:"column:idx;primaryKey;autoIncrement:false"`
+	Size     int64  `gorm:"not null"`
+	Checksum string `gorm:"type:char(64);not null"`
+}
+
+func (ChunkModel) TableName() string { return "media_upload_chunks" }
+
+type PostgresUploadRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresUploadRepository(db *gorm.DB) (*PostgresUploadRepository, error) {
+	if err := db.AutoMigrate(&UploadModel{}, &ChunkModel{}); err != nil {
+		return nil, err
+	}
+	return &PostgresUploadRepository{db: db}, nil
+}
+
+func (r *PostgresUploadRepository) Create(ctx context.Context, u *domain.Upload) error {
+	now := time.Now()
+	m := &UploadModel{ID: u.ID, OwnerID: u.OwnerID, Size: u.Size, ChunkSize: u.ChunkSize, MIME: u.MIME, CreatedAt: now, UpdatedAt: now}
+	if err := r.db.WithContext(ctx).Create(m).Error; err != nil {
+		return err
+	}
+	u.CreatedAt, u.UpdatedAt = now, now
+	return nil
+}
+
+func (r *PostgresUploadRepository) Get(ctx context.Context, id string) (*domain.Upload, error) {
+	var m UploadModel
+	tx := r.db.WithContext(ctx).Where("id = ?", id).Limit(1).Find(&m)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return nil, domain.ErrUploadNotFound
+	}
+	return toUpload(&m), nil
+}
+
+func (r *PostgresUploadRepository) SaveChunk(ctx context.Context, uploadID string, c domain.Chunk) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&UploadModel{}).Where("id = ?", uploadID).Update("updated_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return domain.ErrUploadNotFound
+		}
+		m := &ChunkModel{UploadID: uploadID, Index: c.Index, Size: c.Size, Checksum: c.Checksum}
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(m).Error
+	})
+}
+
+func (r *PostgresUploadRepository) ListChunks(ctx context.Context, uploadID string) ([]domain.Chunk, error) {
+	var ms []ChunkModel
+	if err := r.db.WithContext(ctx).Where("upload_id = ?", uploadID).Order("idx").Find(&ms).Error; err != nil {
+		return nil, err
+	}
+	out := make([]domain.Chunk, 0, len(ms))
+	for _, m := range ms {
+		out = append(out, domain.Chunk{Index: m.Index, Size: m.Size, Checksum: m.Checksum})
+	}
+	return out, nil
+}
+
+func (r *PostgresUploadRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("upload_id = ?", id).Delete(&ChunkModel{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&UploadModel{}).Error
+	})
+}
+
+func (r *PostgresUploadRepository) ListStale(ctx context.Context, before time.Time, limit int) ([]domain.Upload, error) {
+	var ms []UploadModel
+	if err := r.db.WithContext(ctx).Where("updated_at < ?", before).Order("updated_at").Limit(limit).Find(&ms).Error; err != nil {
+		return nil, err
+	}
+	out := make([]domain.Upload, 0, len(ms))
+	for i := range ms {
+		out = append(out, *toUpload(&ms[i]))
+	}
+	return out, nil
+}
+
+func toUpload(m *UploadModel) *domain.Upload {
+	return &domain.Upload{
+		ID:        m.ID,
+		OwnerID:   m.OwnerID,
+		Size:      m.Size,
+		ChunkSize: m.ChunkSize,
+		MIME:      m.MIME,
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}
+}
@@ -0,0 +1,129 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"wsim/user/api/media/domain"
+)
+
+// ClamAV clamd 客户端，使用 INSTREAM 命令把内容分块发给守护进程扫描
+type ClamAV struct {
+	network string
+	addr    string
+	// Timeout 单次扫描（含连接）的超时
+	Timeout time.Duration
+	// ChunkSize 每块大小，不能超过 clamd 的 StreamMaxLength
+	ChunkSize int
+	// OnFound 命中特征时的结论，默认隔离待复核
+	OnFound domain.Verdict
+}
+
+// NewClamAV addr 形如 tcp://127.0.0.1:3310、unix:///var/run/clamav/clamd.ctl，或省略协议的 host:port
+func NewClamAV(addr string) (*ClamAV, error) {
+	network, address := "tcp", addr
+	if n, a, ok := strings.Cut(addr, "://"); ok {
+		network, address = n, a
+	}
+	if address == "" || (network != "tcp" && network != "unix") {
+		return nil, fmt.Errorf("invalid clamd address: %q", addr)
+	}
+	return &ClamAV{
+		network:   network,
+		addr:      address,
+		Timeout:   time.Minute,
+		ChunkSize: 64 << 10,
+		OnFound:   domain.VerdictQuarantine,
+	}, nil
+}
+
+// NewFromEnv CLAMAV_ADDR 未配置时返回 nil（不扫描）；CLAMAV_ON_FOUND=reject 时命中直接拒绝
+func NewFromEnv() (domain.ContentScanner, error) {
+	addr := os.Getenv("CLAMAV_ADDR")
+	if addr == "" {
+		return nil, nil
+	}
+	c, err := NewClamAV(addr)
+	if err != nil {
+		return nil, err
+	}
+	switch v := os.Getenv("CLAMAV_ON_FOUND"); v {
+	case "", string(domain.VerdictQuarantine):
+	case string(domain.VerdictReject):
+		c.OnFound = domain.VerdictReject
+	default:
+		return nil, fmt.Errorf("invalid CLAMAV_ON_FOUND: %q", v)
+	}
+	return c, nil
+}
+
+func (c *ClamAV) Scan(ctx context.Context, r io.Reader) (domain.ScanResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, c.network, c.addr)
+	if err != nil {
+		return domain.ScanResult{}, fmt.Errorf("%w: %v", domain.ErrScanUnavailable, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// 超过 StreamMaxLength 时 clamd 会提前回复并断开，写失败后仍尝试读取回复
+	werr := c.stream(conn, r)
+	reply, rerr := bufio.NewReader(io.LimitReader(conn, 4096)).ReadString(0)
+	if rerr != nil && reply == "" {
+		if werr != nil {
+			rerr = werr
+		}
+		return domain.ScanResult{}, fmt.Errorf("%w: %v", domain.ErrScanUnavailable, rerr)
+	}
+	return c.parse(strings.TrimRight(reply, "\x00\n"))
+}
+
+func (c *ClamAV) stream(w io.Writer, r io.Reader) error {
+	if _, err := io.WriteString(w, "zINSTREAM\x00"); err != nil {
+		return err
+	}
+	buf := make([]byte, 4+c.ChunkSize)
+	for {
+		n, err := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, werr := w.Write(buf[:4+n]); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := w.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+// parse 回复形如 "stream: OK"、"stream: Eicar-Test-Signature FOUND"、"INSTREAM size limit exceeded. ERROR"
+func (c *ClamAV) parse(reply string) (domain.ScanResult, error) {
+	msg := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case msg == "OK":
+		return domain.ScanResult{Verdict: domain.VerdictAllow}, nil
+	case strings.HasSuffix(msg, " FOUND"):
+		return domain.ScanResult{Verdict: c.OnFound, Reason: strings.TrimSuffix(msg, " FOUND")}, nil
+	case strings.Contains(msg, "size limit exceeded"):
+		// 太大扫不了：不放行也不直接拒绝，交给管理员
+		return domain.ScanResult{Verdict: domain.VerdictQuarantine, Reason: "scan size limit exceeded"}, nil
+	}
+	return domain.ScanResult{}, fmt.Errorf("%w: clamd: %s", domain.ErrScanUnavailable, reply)
+}
@@ -0,0 +1,98 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"wsim/user/api/media/domain"
+)
+
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// fakeClamd 本地假守护进程：实现 INSTREAM，内容含 EICAR 串时报毒
+func fakeClamd(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveClamd(conn)
+		}
+	}()
+	return "tcp://" + ln.Addr().String()
+}
+
+func serveClamd(conn net.Conn) {
+	defer conn.Close()
+	cmd := make([]byte, len("zINSTREAM\x00"))
+	if _, err := io.ReadFull(conn, cmd); err != nil || string(cmd) != "zINSTREAM\x00" {
+		io.WriteString(conn, "UNKNOWN COMMAND\x00")
+		return
+	}
+	var data bytes.Buffer
+	for {
+		var size uint32
+		if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+			return
+		}
+		if size == 0 {
+			break
+		}
+		if _, err := io.CopyN(&data, conn, int64(size)); err != nil {
+			return
+		}
+	}
+	if bytes.Contains(data.Bytes(), []byte(eicar)) {
+		io.WriteString(conn, "stream: Eicar-Test-Signature FOUND\x00")
+		return
+	}
+	io.WriteString(conn, "stream: OK\x00")
+}
+
+func TestClamAVScan(t *testing.T) {
+	c, err := NewClamAV(fakeClamd(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.ChunkSize = 16 // 让内容跨多个块
+
+	res, err := c.Scan(context.Background(), strings.NewReader("just a holiday photo, honestly"))
+	if err != nil || res.Verdict != domain.VerdictAllow {
+		t.Fatalf("clean scan = %+v, %v", res, err)
+	}
+	res, err = c.Scan(context.Background(), strings.NewReader("prefix "+eicar+" suffix"))
+	if err != nil || res.Verdict != domain.VerdictQuarantine || res.Reason != "Eicar-Test-Signature" {
+		t.Fatalf("eicar scan = %+v, %v", res, err)
+	}
+	c.OnFound = domain.VerdictReject
+	res, _ = c.Scan(context.Background(), strings.NewReader(eicar))
+	if res.Verdict != domain.VerdictReject {
+		t.Fatalf("eicar scan with reject policy = %+v", res)
+	}
+}
+
+func TestClamAVUnavailable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	c, _ := NewClamAV(addr)
+	if _, err := c.Scan(context.Background(), strings.NewReader("x")); !errors.Is(err, domain.ErrScanUnavailable) {
+		t.Fatalf("scan with daemon down err = %v", err)
+	}
+}
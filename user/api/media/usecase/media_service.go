@@ -0,0 +1,380 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"wsim/user/api/media/domain"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+var (
+	ErrBadRequest = errors.New("bad request")
+	// ErrForbidden 既不是上传者，也不是引用该媒体的会话的成员
+	ErrForbidden = errors.New("forbidden")
+	// ErrTooLarge 超过单次上传上限
+	ErrTooLarge = errors.New("media too large")
+)
+
+// 图片像素上限，防止解压炸弹
+const maxImagePixels = 50_000_000
+
+// ConversationMedia 查询媒体是否出现在用户参与的会话里（由消息存储实现）
+type ConversationMedia interface {
+	SharesMedia(ctx context.Context, userID uint, mediaID string) (bool, error)
+}
+
+// Access 媒体访问判定：上传者，或引用了该媒体的会话的成员；缩略图跟随原图。
+// 被隔离或拒绝的媒体任何人都不可访问
+type Access struct {
+	media domain.MediaRepository
+	convs ConversationMedia
+}
+
+func NewAccess(media domain.MediaRepository, convs ConversationMedia) *Access {
+	return &Access{media: media, convs: convs}
+}
+
+func (a *Access) CanAccess(ctx context.Context, userID uint, mediaID string) (bool, error) {
+	m, err := a.media.Get(ctx, mediaID)
+	if errors.Is(err, domain.ErrMediaNotFound) {
+		return false, nil
+	}
+	if err != nil || !m.Available() {
+		return false, err
+	}
+	ok, err := a.direct(ctx, userID, mediaID)
+	if err != nil || ok {
+		return ok, err
+	}
+	sources, err := a.media.ThumbnailSources(ctx, mediaID)
+	if err != nil {
+		return false, err
+	}
+	for _, src := range sources {
+		if ok, err := a.direct(ctx, userID, src); err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+func (a *Access) direct(ctx context.Context, userID uint, mediaID string) (bool, error) {
+	owner, err := a.media.IsOwner(ctx, mediaID, userID)
+	if err != nil || owner {
+		return owner, err
+	}
+	return a.convs.SharesMedia(ctx, userID, mediaID)
+}
+
+type MediaService struct {
+	media  domain.MediaRepository
+	blobs  domain.BlobStore
+	access *Access
+	images domain.ImageProcessor
+	// scanner 为 nil 时不扫描
+	scanner domain.ContentScanner
+	reports domain.ScanReportRepository
+	// thumbs 由 NewThumbnailPipeline 挂上；为 nil 时不生成缩略图
+	thumbs *ThumbnailPipeline
+	// MaxUploadSize 单次上传上限（字节），环境变量 MEDIA_MAX_UPLOAD_BYTES 覆盖（默认 32MB）
+	MaxUploadSize int64
+	// MaxImageSize 需要解码处理的图片上限（字节），分片上传的大图同样受限（默认 32MB）
+	MaxImageSize int64
+}
+
+func NewMediaService(media domain.MediaRepository, blobs domain.BlobStore, access *Access, images domain.ImageProcessor,
+	scanner domain.ContentScanner, reports domain.ScanReportRepository) *MediaService {
+	limit := int64(32 << 20)
+	if v, err := strconv.ParseInt(os.Getenv("MEDIA_MAX_UPLOAD_BYTES"), 10, 64); err == nil && v > 0 {
+		limit = v
+	}
+	return &MediaService{
+		media:         media,
+		blobs:         blobs,
+		access:        access,
+		images:        images,
+		scanner:       scanner,
+		reports:       reports,
+		MaxUploadSize: limit,
+		MaxImageSize:  32 << 20,
+	}
+}
+
+// Upload 保存上传内容，返回以内容哈希为 ID 的媒体；相同内容只存一份。
+// claimed 为客户端声明的类型，可为空；与实际内容不符时拒绝
+func (s *MediaService) Upload(ctx context.Context, ownerID uint, r io.Reader, claimed string) (*domain.Media, error) {
+	if ownerID == 0 {
+		return nil, ErrBadRequest
+	}
+	tmp, n, id, err := spool(r, s.MaxUploadSize)
+	if err != nil {
+		return nil, err
+	}
+	defer discard(tmp)
+	if n == 0 {
+		return nil, ErrBadRequest
+	}
+	return s.store(ctx, ownerID, tmp, n, id, claimed)
+}
+
+// spool 先把内容落到临时文件：哈希要读完才知道，且同一内容已存在时无需再写存储。
+// 超过 limit 返回 ErrTooLarge；调用方负责 discard
+func spool(r io.Reader, limit int64) (*os.File, int64, string, error) {
+	tmp, err := os.CreateTemp("", "media-upload-*")
+	if err != nil {
+		return nil, 0, "", err
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), io.LimitReader(r, limit+1))
+	if err == nil && n > limit {
+		err = ErrTooLarge
+	}
+	if err != nil {
+		discard(tmp)
+		return nil, 0, "", err
+	}
+	return tmp, n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func discard(f *os.File) {
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// store 校验类型后写入存储并登记。图片会先解析尺寸、去掉位置信息（内容变了则重新计算 ID）；
+// 新内容经扫描放行后才可用，图片再交给后台生成缩略图
+func (s *MediaService) store(ctx context.Context, ownerID uint, f *os.File, n int64, id, claimed string) (*domain.Media, error) {
+	head := make([]byte, 512)
+	hn, _ := f.ReadAt(head, 0)
+	sniffed := sniff(head[:hn])
+	if !matchesClaim(claimed, sniffed) {
+		return nil, domain.ErrTypeMismatch
+	}
+	m := &domain.Media{ID: id, Size: n, MIME: sniffed}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	var body io.ReadSeeker = f
+
+	image := isImage(sniffed)
+	if image {
+		if n > s.MaxImageSize {
+			return nil, ErrTooLarge
+		}
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return nil, err
+		}
+		info, err := s.images.Inspect(data)
+		if err != nil {
+			return nil, domain.ErrTypeMismatch
+		}
+		if info.Width*info.Height > maxImagePixels {
+			return nil, ErrTooLarge
+		}
+		if stripped, changed := s.images.StripLocation(info.Format, data); changed {
+			data = stripped
+			sum := sha256.Sum256(data)
+			m.ID, m.Size = hex.EncodeToString(sum[:]), int64(len(data))
+		}
+		m.Width, m.Height = info.Width, info.Height
+		body = bytes.NewReader(data)
+	}
+	if err := s.screen(ctx, ownerID, m, body); err != nil {
+		return nil, err
+	}
+	switch m.Status {
+	case domain.StatusRejected:
+		// 只登记哈希，不存内容，之后相同内容直接拒绝
+		if err := s.media.Save(ctx, m, ownerID); err != nil {
+			return nil, err
+		}
+		return nil, domain.ErrRejected
+	case domain.StatusQuarantined:
+		if err := s.put(ctx, ownerID, m, body); err != nil {
+			return nil, err
+		}
+		return nil, domain.ErrQuarantined
+	}
+	if err := s.put(ctx, ownerID, m, body); err != nil {
+		return nil, err
+	}
+	if image && s.thumbs != nil {
+		s.thumbs.Enqueue(m.ID, ownerID)
+	}
+	return m, nil
+}
+
+// screen 决定媒体状态：已有的内容沿用之前的结论，新内容交给扫描器；隔离和拒绝都登记报告
+func (s *MediaService) screen(ctx context.Context, ownerID uint, m *domain.Media, body io.ReadSeeker) error {
+	existing, err := s.media.Get(ctx, m.ID)
+	if err == nil {
+		switch existing.Status {
+		case domain.StatusRejected:
+			return domain.ErrRejected
+		case domain.StatusQuarantined:
+			return domain.ErrQuarantined
+		}
+		m.Status = domain.StatusActive
+		return nil
+	}
+	if !errors.Is(err, domain.ErrMediaNotFound) {
+		return err
+	}
+	m.Status = domain.StatusActive
+	if s.scanner == nil {
+		return nil
+	}
+	res, err := s.scanner.Scan(ctx, body)
+	if err != nil {
+		if !errors.Is(err, domain.ErrScanUnavailable) {
+			err = fmt.Errorf("%w: %v", domain.ErrScanUnavailable, err)
+		}
+		return err
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	switch res.Verdict {
+	case domain.VerdictAllow:
+		return nil
+	case domain.VerdictQuarantine:
+		m.Status = domain.StatusQuarantined
+	case domain.VerdictReject:
+		m.Status = domain.StatusRejected
+	default:
+		return fmt.Errorf("unknown scan verdict: %q", res.Verdict)
+	}
+	s.report(ctx, m, ownerID, res)
+	return nil
+}
+
+// report 登记隔离/拒绝，供管理员复核
+func (s *MediaService) report(ctx context.Context, m *domain.Media, ownerID uint, res domain.ScanResult) {
+	hlog.CtxWarnf(ctx, "media: %s by user %d %s: %s", m.ID, ownerID, res.Verdict, res.Reason)
+	r := &domain.ScanReport{MediaID: m.ID, OwnerID: ownerID, Verdict: res.Verdict, Reason: res.Reason}
+	if err := s.reports.Report(ctx, r); err != nil {
+		hlog.CtxErrorf(ctx, "media: save scan report for %s failed: %v", m.ID, err)
+	}
+}
+
+// put 存储中已有同一内容时跳过写入，只登记上传者
+func (s *MediaService) put(ctx context.Context, ownerID uint, m *domain.Media, body io.Reader) error {
+	exists, err := s.blobs.Exists(ctx, m.ID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if err := s.blobs.Put(ctx, m.ID, body, m.Size); err != nil {
+			return err
+		}
+	}
+	return s.media.Save(ctx, m, ownerID)
+}
+
+// Open 校验访问权限后打开媒体内容，调用方负责关闭
+func (s *MediaService) Open(ctx context.Context, userID uint, mediaID string) (*domain.Media, io.ReadCloser, error) {
+	m, err := s.authorized(ctx, userID, mediaID)
+	if err != nil {
+		return nil, nil, err
+	}
+	rc, _, err := s.blobs.Get(ctx, mediaID)
+	if errors.Is(err, domain.ErrBlobNotFound) {
+		return nil, nil, domain.ErrMediaNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, rc, nil
+}
+
+// Info 媒体元数据及已生成的缩略图
+func (s *MediaService) Info(ctx context.Context, userID uint, mediaID string) (*domain.Media, []domain.Thumbnail, error) {
+	m, err := s.authorized(ctx, userID, mediaID)
+	if err != nil {
+		return nil, nil, err
+	}
+	thumbs, err := s.media.ListThumbnails(ctx, mediaID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, thumbs, nil
+}
+
+func (s *MediaService) authorized(ctx context.Context, userID uint, mediaID string) (*domain.Media, error) {
+	if !domain.IsMediaID(mediaID) {
+		return nil, domain.ErrMediaNotFound
+	}
+	m, err := s.media.Get(ctx, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	switch m.Status {
+	case domain.StatusRejected:
+		return nil, domain.ErrMediaNotFound
+	case domain.StatusQuarantined:
+		return nil, domain.ErrQuarantined
+	}
+	ok, err := s.access.CanAccess(ctx, userID, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrForbidden
+	}
+	return m, nil
+}
+
+// sniff 按内容判断类型，去掉 charset 等参数
+func sniff(head []byte) string {
+	t, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	return t
+}
+
+func isImage(t string) bool {
+	return t == "image/jpeg" || t == "image/png" || t == "image/gif"
+}
+
+// sniffable 能按内容识别出来的图片类型
+var sniffable = map[string]bool{
+	"image/jpeg": true, "image/png": true, "image/gif": true, "image/webp": true, "image/bmp": true,
+}
+
+// matchesClaim 声明类型与内容是否相符：能识别的图片类型须完全一致，其他类型只比较大类；
+// 内容无法识别时，只要声明的不是能识别的图片类型就放行（如 HEIC）
+func matchesClaim(claimed, sniffed string) bool {
+	if claimed == "" {
+		return true
+	}
+	t, _, err := mime.ParseMediaType(claimed)
+	if err != nil {
+		return false
+	}
+	if t == "image/jpg" {
+		t = "image/jpeg"
+	}
+	if t == "application/octet-stream" {
+		return true
+	}
+	if sniffed == "application/octet-stream" {
+		return !sniffable[t]
+	}
+	if sniffable[t] || sniffable[sniffed] {
+		return t == sniffed
+	}
+	major, _, _ := strings.Cut(t, "/")
+	sniffedMajor, _, _ := strings.Cut(sniffed, "/")
+	return major == sniffedMajor
+}
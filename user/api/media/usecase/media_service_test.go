@@ -0,0 +1,135 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"wsim/user/api/media/domain"
+)
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 0, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUploadRejectsTypeMismatch(t *testing.T) {
+	s, _, _ := newTestUploads(t)
+	_, err := s.media.Upload(context.Background(), 1, bytes.NewReader([]byte("<html><script>alert(1)</script>")), "image/png")
+	if !errors.Is(err, domain.ErrTypeMismatch) {
+		t.Fatalf("html claimed as png err = %v", err)
+	}
+	// 头部像 PNG，但内容无法解析
+	_, err = s.media.Upload(context.Background(), 1, bytes.NewReader([]byte("\x89PNG\r\n\x1a\ngarbage")), "image/png")
+	if !errors.Is(err, domain.ErrTypeMismatch) {
+		t.Fatalf("corrupt png err = %v", err)
+	}
+}
+
+func TestImageUploadThumbnails(t *testing.T) {
+	ctx := context.Background()
+	s, _, _ := newTestUploads(t)
+	m, err := s.media.Upload(ctx, 1, bytes.NewReader(testPNG(t, 640, 320)), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Width != 640 || m.Height != 320 || m.MIME != "image/png" {
+		t.Fatalf("media = %+v", m)
+	}
+	p := NewThumbnailPipeline(s.media)
+	p.Sizes = []int{160, 480, 1024}
+	if err := p.Process(ctx, m.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+	_, thumbs, err := s.media.Info(ctx, 1, m.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1024 比原图大，不生成
+	if len(thumbs) != 2 || thumbs[0].Width != 160 || thumbs[0].Height != 80 || thumbs[1].Width != 480 {
+		t.Fatalf("thumbnails = %+v", thumbs)
+	}
+	// 缩略图随原图授权：没上传过原图的人拿不到
+	if _, _, err := s.media.Open(ctx, 2, thumbs[0].MediaID); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("thumbnail open by stranger err = %v", err)
+	}
+}
+
+// keywordScanner 内容含 EVIL 时隔离，含 WORM 时拒绝
+type keywordScanner struct{ calls int }
+
+func (k *keywordScanner) Scan(ctx context.Context, r io.Reader) (domain.ScanResult, error) {
+	k.calls++
+	b, _ := io.ReadAll(r)
+	switch {
+	case bytes.Contains(b, []byte("EVIL")):
+		return domain.ScanResult{Verdict: domain.VerdictQuarantine, Reason: "evil"}, nil
+	case bytes.Contains(b, []byte("WORM")):
+		return domain.ScanResult{Verdict: domain.VerdictReject, Reason: "worm"}, nil
+	}
+	return domain.ScanResult{Verdict: domain.VerdictAllow}, nil
+}
+
+func TestUploadScanning(t *testing.T) {
+	ctx := context.Background()
+	scanner := &keywordScanner{}
+	s, _, blobs := newTestUploadsWithScanner(t, scanner)
+	svc := s.media
+
+	clean, err := svc.Upload(ctx, 1, strings.NewReader("hello"), "text/plain")
+	if err != nil || clean.Status != domain.StatusActive {
+		t.Fatalf("clean upload = %+v, %v", clean, err)
+	}
+
+	evil := "some EVIL bytes"
+	if _, err := svc.Upload(ctx, 1, strings.NewReader(evil), ""); !errors.Is(err, domain.ErrQuarantined) {
+		t.Fatalf("quarantined upload err = %v", err)
+	}
+	id := checksum([]byte(evil))
+	if _, _, err := svc.Open(ctx, 1, id); !errors.Is(err, domain.ErrQuarantined) {
+		t.Fatalf("open quarantined err = %v", err)
+	}
+	calls := scanner.calls
+	if _, err := svc.Upload(ctx, 2, strings.NewReader(evil), ""); !errors.Is(err, domain.ErrQuarantined) || scanner.calls != calls {
+		t.Fatalf("re-upload of quarantined content err = %v, rescanned = %v", err, scanner.calls != calls)
+	}
+	reports, _ := svc.Reports(ctx, true, 0)
+	if len(reports) != 1 || reports[0].MediaID != id || reports[0].Reason != "evil" {
+		t.Fatalf("reports = %+v", reports)
+	}
+	if err := svc.Release(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, rc, err := svc.Open(ctx, 1, id); err != nil {
+		t.Fatalf("open after release err = %v", err)
+	} else {
+		rc.Close()
+	}
+
+	worm := "a WORM"
+	if _, err := svc.Upload(ctx, 1, strings.NewReader(worm), ""); !errors.Is(err, domain.ErrRejected) {
+		t.Fatalf("rejected upload err = %v", err)
+	}
+	if ok, _ := blobs.Exists(ctx, checksum([]byte(worm))); ok {
+		t.Fatal("rejected content was stored")
+	}
+	if _, err := svc.Upload(ctx, 2, strings.NewReader(worm), ""); !errors.Is(err, domain.ErrRejected) {
+		t.Fatalf("re-upload of rejected content err = %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"wsim/user/api/media/domain"
+)
+
+const maxReports = 200
+
+// Reports 管理员查看扫描报告
+func (s *MediaService) Reports(ctx context.Context, unresolved bool, limit int) ([]domain.ScanReport, error) {
+	if limit <= 0 || limit > maxReports {
+		limit = maxReports
+	}
+	return s.reports.ListReports(ctx, unresolved, limit)
+}
+
+// OpenForReview 管理员复核时下载，不受隔离限制；已拒绝的内容已删除
+func (s *MediaService) OpenForReview(ctx context.Context, mediaID string) (*domain.Media, io.ReadCloser, error) {
+	if !domain.IsMediaID(mediaID) {
+		return nil, nil, domain.ErrMediaNotFound
+	}
+	m, err := s.media.Get(ctx, mediaID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if m.Status == domain.StatusRejected {
+		return nil, nil, domain.ErrMediaNotFound
+	}
+	rc, _, err := s.blobs.Get(ctx, mediaID)
+	if errors.Is(err, domain.ErrBlobNotFound) {
+		return nil, nil, domain.ErrMediaNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, rc, nil
+}
+
+// Release 复核后放行隔离中的媒体；图片补生成缩略图
+func (s *MediaService) Release(ctx context.Context, mediaID string) error {
+	m, err := s.quarantined(ctx, mediaID)
+	if err != nil {
+		return err
+	}
+	resolved, err := s.reports.Resolve(ctx, mediaID, domain.StatusActive, time.Now())
+	if err != nil {
+		return err
+	}
+	if isImage(m.MIME) && s.thumbs != nil && len(resolved) > 0 {
+		s.thumbs.Enqueue(mediaID, resolved[0].OwnerID)
+	}
+	return nil
+}
+
+// Reject 复核后确认有害：删除内容，之后相同内容的上传直接拒绝
+func (s *MediaService) Reject(ctx context.Context, mediaID string) error {
+	if _, err := s.quarantined(ctx, mediaID); err != nil {
+		return err
+	}
+	if _, err := s.reports.Resolve(ctx, mediaID, domain.StatusRejected, time.Now()); err != nil {
+		return err
+	}
+	return s.blobs.Delete(ctx, mediaID)
+}
+
+func (s *MediaService) quarantined(ctx context.Context, mediaID string) (*domain.Media, error) {
+	if !domain.IsMediaID(mediaID) {
+		return nil, domain.ErrMediaNotFound
+	}
+	m, err := s.media.Get(ctx, mediaID)
+	if err != nil {
+		return nil, err
+	}
+	if m.Status != domain.StatusQuarantined {
+		return nil, ErrBadRequest
+	}
+	return m, nil
+}
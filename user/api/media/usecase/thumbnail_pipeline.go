@@ -0,0 +1,128 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"wsim/user/api/media/domain"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+type thumbJob struct {
+	mediaID string
+	ownerID uint
+}
+
+// ThumbnailPipeline 后台为新上传的图片生成各尺寸缩略图；队列满时丢弃，不阻塞上传
+type ThumbnailPipeline struct {
+	media *MediaService
+	// Sizes 缩略图最长边，环境变量 MEDIA_THUMBNAIL_SIZES 覆盖（逗号分隔，默认 160,480）
+	Sizes []int
+	jobs  chan thumbJob
+}
+
+// NewThumbnailPipeline 创建流水线并挂到 MediaService 上，Start 之后才开始处理
+func NewThumbnailPipeline(media *MediaService) *ThumbnailPipeline {
+	p := &ThumbnailPipeline{
+		media: media,
+		Sizes: thumbnailSizes(os.Getenv("MEDIA_THUMBNAIL_SIZES")),
+		jobs:  make(chan thumbJob, 256),
+	}
+	media.thumbs = p
+	return p
+}
+
+func thumbnailSizes(v string) []int {
+	var sizes []int
+	for _, f := range strings.Split(v, ",") {
+		if n, err := strconv.Atoi(strings.TrimSpace(f)); err == nil && n >= 16 && n <= 4096 {
+			sizes = append(sizes, n)
+		}
+	}
+	if len(sizes) == 0 {
+		return []int{160, 480}
+	}
+	sort.Ints(sizes)
+	return sizes
+}
+
+// Start 启动 workers 个处理协程，直到 ctx 结束
+func (p *ThumbnailPipeline) Start(ctx context.Context, workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-p.jobs:
+					if err := p.Process(ctx, job.mediaID, job.ownerID); err != nil {
+						hlog.CtxErrorf(ctx, "media: thumbnail %s failed: %v", job.mediaID, err)
+					}
+				}
+			}
+		}()
+	}
+}
+
+func (p *ThumbnailPipeline) Enqueue(mediaID string, ownerID uint) {
+	select {
+	case p.jobs <- thumbJob{mediaID: mediaID, ownerID: ownerID}:
+	default:
+		hlog.Warnf("media: thumbnail queue full, skip %s", mediaID)
+	}
+}
+
+// Process 生成缺少的缩略图；原图比某个尺寸还小时不生成该尺寸
+func (p *ThumbnailPipeline) Process(ctx context.Context, mediaID string, ownerID uint) error {
+	m, err := p.media.media.Get(ctx, mediaID)
+	if err != nil {
+		return err
+	}
+	existing, err := p.media.media.ListThumbnails(ctx, mediaID)
+	if err != nil {
+		return err
+	}
+	done := make(map[int]bool, len(existing))
+	for _, t := range existing {
+		done[t.Size] = true
+	}
+	var data []byte
+	for _, size := range p.Sizes {
+		if done[size] || (m.Width <= size && m.Height <= size) {
+			continue
+		}
+		if data == nil {
+			rc, _, err := p.media.blobs.Get(ctx, mediaID)
+			if err != nil {
+				return err
+			}
+			data, err = io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+		out, w, h, err := p.media.images.Thumbnail(data, size)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(out)
+		thumb := &domain.Media{ID: hex.EncodeToString(sum[:]), Size: int64(len(out)), MIME: "image/jpeg", Width: w, Height: h}
+		if err := p.media.put(ctx, ownerID, thumb, bytes.NewReader(out)); err != nil {
+			return err
+		}
+		err = p.media.media.SaveThumbnail(ctx, mediaID, domain.Thumbnail{Size: size, MediaID: thumb.ID, Width: w, Height: h})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
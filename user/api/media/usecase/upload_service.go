@@ -0,0 +1,238 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"wsim/user/api/media/domain"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+const (
+	defaultChunkSize = 4 << 20
+	minChunkSize     = 256 << 10
+	maxChunkSize     = 16 << 20
+	// 每轮清理的上传会话数
+	gcBatch = 100
+)
+
+// UploadService 可续传的分片上传：init 拿到上传 ID，按偏移逐片上传（带 SHA-256 校验），
+// 随时可查询已到达的分片，全部到齐后 complete 拼装成媒体
+type UploadService struct {
+	uploads domain.UploadRepository
+	blobs   domain.BlobStore
+	media   *MediaService
+	// MaxFileSize 分片上传的文件上限，环境变量 MEDIA_MAX_FILE_BYTES 覆盖（默认 2GB）
+	MaxFileSize int64
+	// TTL 上传会话多久没有新分片视为放弃，环境变量 MEDIA_UPLOAD_TTL 覆盖（默认 24h）
+	TTL time.Duration
+	now func() time.Time
+}
+
+func NewUploadService(uploads domain.UploadRepository, blobs domain.BlobStore, media *MediaService) *UploadService {
+	limit := int64(2 << 30)
+	if v, err := strconv.ParseInt(os.Getenv("MEDIA_MAX_FILE_BYTES"), 10, 64); err == nil && v > 0 {
+		limit = v
+	}
+	ttl := 24 * time.Hour
+	if v := os.Getenv("MEDIA_UPLOAD_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			ttl = d
+		}
+	}
+	return &UploadService{
+		uploads:     uploads,
+		blobs:       blobs,
+		media:       media,
+		MaxFileSize: limit,
+		TTL:         ttl,
+		now:         time.Now,
+	}
+}
+
+// Init 开始一次分片上传；chunkSize 为 0 时使用默认分片大小，claimed 为声明的文件类型，可为空
+func (s *UploadService) Init(ctx context.Context, ownerID uint, size, chunkSize int64, claimed string) (*domain.Upload, error) {
+	if ownerID == 0 || size <= 0 {
+		return nil, ErrBadRequest
+	}
+	if size > s.MaxFileSize {
+		return nil, ErrTooLarge
+	}
+	if chunkSize == 0 {
+		chunkSize = defaultChunkSize
+	}
+	if chunkSize < minChunkSize || chunkSize > maxChunkSize || len(claimed) > 128 {
+		return nil, ErrBadRequest
+	}
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+	u := &domain.Upload{ID: hex.EncodeToString(b[:]), OwnerID: ownerID, Size: size, ChunkSize: chunkSize, MIME: claimed}
+	if err := s.uploads.Create(ctx, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// PutChunk 写入从 offset 开始的一个分片；offset 须对齐分片边界，checksum 为分片的 SHA-256（十六进制）。
+// 同一分片可重复上传，以最后一次为准
+func (s *UploadService) PutChunk(ctx context.Context, ownerID uint, uploadID string, offset int64, data []byte, checksum string) error {
+	u, err := s.get(ctx, ownerID, uploadID)
+	if err != nil {
+		return err
+	}
+	if offset < 0 || offset >= u.Size || offset%u.ChunkSize != 0 {
+		return ErrBadRequest
+	}
+	index := int(offset / u.ChunkSize)
+	if int64(len(data)) != u.ChunkLen(index) {
+		return ErrBadRequest
+	}
+	sum := sha256.Sum256(data)
+	got := hex.EncodeToString(sum[:])
+	if got != strings.ToLower(checksum) {
+		return domain.ErrChecksumMismatch
+	}
+	if err := s.blobs.Put(ctx, u.ChunkKey(index), bytes.NewReader(data), int64(len(data))); err != nil {
+		return err
+	}
+	return s.uploads.SaveChunk(ctx, u.ID, domain.Chunk{Index: index, Size: int64(len(data)), Checksum: got})
+}
+
+// Status 上传会话及已到达的分片
+func (s *UploadService) Status(ctx context.Context, ownerID uint, uploadID string) (*domain.Upload, []domain.Chunk, error) {
+	u, err := s.get(ctx, ownerID, uploadID)
+	if err != nil {
+		return nil, nil, err
+	}
+	chunks, err := s.uploads.ListChunks(ctx, u.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return u, chunks, nil
+}
+
+// Complete 按序拼装全部分片（逐片复核校验和），以内容哈希存为媒体并清理分片；
+// 内容与已有媒体相同时直接复用
+func (s *UploadService) Complete(ctx context.Context, ownerID uint, uploadID string) (*domain.Media, error) {
+	u, chunks, err := s.Status(ctx, ownerID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) != u.ChunkCount() {
+		return nil, domain.ErrUploadIncomplete
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(s.assemble(ctx, u, chunks, pw))
+	}()
+	tmp, n, id, err := spool(pr, u.Size)
+	pr.Close()
+	if err != nil {
+		return nil, err
+	}
+	defer discard(tmp)
+	if n != u.Size {
+		return nil, domain.ErrUploadIncomplete
+	}
+	m, err := s.media.store(ctx, u.OwnerID, tmp, n, id, u.MIME)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.discardUpload(ctx, u); err != nil {
+		hlog.CtxWarnf(ctx, "media: clean up upload %s failed: %v", u.ID, err)
+	}
+	return m, nil
+}
+
+func (s *UploadService) assemble(ctx context.Context, u *domain.Upload, chunks []domain.Chunk, w io.Writer) error {
+	for i, c := range chunks {
+		if c.Index != i || c.Size != u.ChunkLen(i) {
+			return domain.ErrUploadIncomplete
+		}
+		rc, _, err := s.blobs.Get(ctx, u.ChunkKey(i))
+		if err != nil {
+			return err
+		}
+		h := sha256.New()
+		_, err = io.Copy(io.MultiWriter(w, h), rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		if hex.EncodeToString(h.Sum(nil)) != c.Checksum {
+			return domain.ErrChecksumMismatch
+		}
+	}
+	return nil
+}
+
+// get 取 ownerID 自己的、未过期的上传会话；他人的会话同样报不存在
+func (s *UploadService) get(ctx context.Context, ownerID uint, uploadID string) (*domain.Upload, error) {
+	u, err := s.uploads.Get(ctx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if u.OwnerID != ownerID || s.now().Sub(u.UpdatedAt) > s.TTL {
+		return nil, domain.ErrUploadNotFound
+	}
+	return u, nil
+}
+
+func (s *UploadService) discardUpload(ctx context.Context, u *domain.Upload) error {
+	for i := 0; i < u.ChunkCount(); i++ {
+		if err := s.blobs.Delete(ctx, u.ChunkKey(i)); err != nil {
+			return err
+		}
+	}
+	return s.uploads.Delete(ctx, u.ID)
+}
+
+// CollectGarbage 清理超过 TTL 没有进展的上传会话及其分片，返回清理数量
+func (s *UploadService) CollectGarbage(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		stale, err := s.uploads.ListStale(ctx, s.now().Add(-s.TTL), gcBatch)
+		if err != nil {
+			return total, err
+		}
+		for i := range stale {
+			if err := s.discardUpload(ctx, &stale[i]); err != nil {
+				return total, err
+			}
+			total++
+		}
+		if len(stale) < gcBatch {
+			return total, nil
+		}
+	}
+}
+
+// RunGC 周期性清理，直到 ctx 结束
+func (s *UploadService) RunGC(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if n, err := s.CollectGarbage(ctx); err != nil {
+			hlog.CtxErrorf(ctx, "media: upload gc failed: %v", err)
+		} else if n > 0 {
+			hlog.CtxInfof(ctx, "media: upload gc removed %d abandoned uploads", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"wsim/user/api/media/domain"
+	"wsim/user/api/media/infra/blob"
+	"wsim/user/api/media/infra/imaging"
+)
+
+type memMedia struct {
+	mu     sync.Mutex
+	media  map[string]domain.Media
+	owners map[string]map[uint]bool
+	thumbs map[string][]domain.Thumbnail
+}
+
+func newMemMedia() *memMedia {
+	return &memMedia{
+		media:  map[string]domain.Media{},
+		owners: map[string]map[uint]bool{},
+		thumbs: map[string][]domain.Thumbnail{},
+	}
+}
+
+func (r *memMedia) SaveThumbnail(ctx context.Context, sourceID string, t domain.Thumbnail) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.thumbs[sourceID] = append(r.thumbs[sourceID], t)
+	return nil
+}
+
+func (r *memMedia) ListThumbnails(ctx context.Context, sourceID string) ([]domain.Thumbnail, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]domain.Thumbnail(nil), r.thumbs[sourceID]...), nil
+}
+
+func (r *memMedia) ThumbnailSources(ctx context.Context, thumbID string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []string
+	for src, ts := range r.thumbs {
+		for _, t := range ts {
+			if t.MediaID == thumbID {
+				out = append(out, src)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (r *memMedia) Save(ctx context.Context, m *domain.Media, ownerID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if old, ok := r.media[m.ID]; ok {
+		m.CreatedAt, m.Status = old.CreatedAt, old.Status
+	} else {
+		m.CreatedAt = time.Now()
+		if m.Status == "" {
+			m.Status = domain.StatusActive
+		}
+		r.media[m.ID] = *m
+		r.owners[m.ID] = map[uint]bool{}
+	}
+	r.owners[m.ID][ownerID] = true
+	return nil
+}
+
+func (r *memMedia) Get(ctx context.Context, id string) (*domain.Media, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.media[id]
+	if !ok {
+		return nil, domain.ErrMediaNotFound
+	}
+	return &m, nil
+}
+
+func (r *memMedia) IsOwner(ctx context.Context, id string, userID uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.owners[id][userID], nil
+}
+
+// noShares 测试里没有会话，只有上传者能访问
+type noShares struct{}
+
+func (noShares) SharesMedia(ctx context.Context, userID uint, mediaID string) (bool, error) {
+	return false, nil
+}
+
+type memUploads struct {
+	mu      sync.Mutex
+	uploads map[string]domain.Upload
+	chunks  map[string]map[int]domain.Chunk
+}
+
+func (r *memUploads) Create(ctx context.Context, u *domain.Upload) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u.CreatedAt, u.UpdatedAt = time.Now(), time.Now()
+	r.uploads[u.ID] = *u
+	r.chunks[u.ID] = map[int]domain.Chunk{}
+	return nil
+}
+
+func (r *memUploads) Get(ctx context.Context, id string) (*domain.Upload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.uploads[id]
+	if !ok {
+		return nil, domain.ErrUploadNotFound
+	}
+	return &u, nil
+}
+
+func (r *memUploads) SaveChunk(ctx context.Context, uploadID string, c domain.Chunk) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.chunks[uploadID][c.Index] = c
+	return nil
+}
+
+func (r *memUploads) ListChunks(ctx context.Context, uploadID string) ([]domain.Chunk, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []domain.Chunk
+	for _, c := range r.chunks[uploadID] {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Index < out[j].Index })
+	return out, nil
+}
+
+func (r *memUploads) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.uploads, id)
+	delete(r.chunks, id)
+	return nil
+}
+
+func (r *memUploads) ListStale(ctx context.Context, before time.Time, limit int) ([]domain.Upload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []domain.Upload
+	for _, u := range r.uploads {
+		if u.UpdatedAt.Before(before) && len(out) < limit {
+			out = append(out, u)
+		}
+	}
+	return out, nil
+}
+
+type memReports struct {
+	media   *memMedia
+	reports []domain.ScanReport
+}
+
+func (r *memReports) Report(ctx context.Context, rep *domain.ScanReport) error {
+	rep.CreatedAt = time.Now()
+	r.reports = append(r.reports, *rep)
+	return nil
+}
+
+func (r *memReports) ListReports(ctx context.Context, unresolved bool, limit int) ([]domain.ScanReport, error) {
+	var out []domain.ScanReport
+	for _, rep := range r.reports {
+		if !unresolved || rep.Resolution == "" {
+			out = append(out, rep)
+		}
+	}
+	return out, nil
+}
+
+func (r *memReports) Resolve(ctx context.Context, mediaID, resolution string, at time.Time) ([]domain.ScanReport, error) {
+	var out []domain.ScanReport
+	for i := range r.reports {
+		if r.reports[i].MediaID == mediaID && r.reports[i].Resolution == "" {
+			r.reports[i].Resolution, r.reports[i].ResolvedAt = resolution, at
+			out = append(out, r.reports[i])
+		}
+	}
+	r.media.mu.Lock()
+	m := r.media.media[mediaID]
+	m.Status = resolution
+	r.media.media[mediaID] = m
+	r.media.mu.Unlock()
+	return out, nil
+}
+
+func newTestUploads(t *testing.T) (*UploadService, *memUploads, domain.BlobStore) {
+	t.Helper()
+	return newTestUploadsWithScanner(t, nil)
+}
+
+func newTestUploadsWithScanner(t *testing.T, scanner domain.ContentScanner) (*UploadService, *memUploads, domain.BlobStore) {
+	t.Helper()
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	media := newMemMedia()
+	uploads := &memUploads{uploads: map[string]domain.Upload{}, chunks: map[string]map[int]domain.Chunk{}}
+	svc := NewMediaService(media, blobs, NewAccess(media, noShares{}), imaging.NewProcessor(), scanner, &memReports{media: media})
+	return NewUploadService(uploads, blobs, svc), uploads, blobs
+}
+
+func checksum(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestChunkedUploadResumeAndDedupe(t *testing.T) {
+	ctx := context.Background()
+	s, _, blobs := newTestUploads(t)
+	content := bytes.Repeat([]byte("0123456789abcdef"), 40000) // 640000 字节，3 片
+	const chunk = minChunkSize
+
+	u, err := s.Init(ctx, 1, int64(len(content)), chunk, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.ChunkCount() != 3 {
+		t.Fatalf("chunk count = %d, want 3", u.ChunkCount())
+	}
+	put := func(i int) error {
+		part := content[i*chunk : min((i+1)*chunk, len(content))]
+		return s.PutChunk(ctx, 1, u.ID, int64(i*chunk), part, checksum(part))
+	}
+	// 乱序上传，中途"断线"
+	if err := put(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := put(0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PutChunk(ctx, 1, u.ID, chunk, content[chunk:2*chunk], checksum([]byte("bad"))); !errors.Is(err, domain.ErrChecksumMismatch) {
+		t.Fatalf("bad checksum err = %v", err)
+	}
+	if _, err := s.Complete(ctx, 1, u.ID); !errors.Is(err, domain.ErrUploadIncomplete) {
+		t.Fatalf("early complete err = %v", err)
+	}
+	if _, _, err := s.Status(ctx, 2, u.ID); !errors.Is(err, domain.ErrUploadNotFound) {
+		t.Fatalf("status by other user err = %v", err)
+	}
+	_, chunks, err := s.Status(ctx, 1, u.ID)
+	if err != nil || len(chunks) != 2 {
+		t.Fatalf("status = %v, %v", chunks, err)
+	}
+	if err := put(1); err != nil {
+		t.Fatal(err)
+	}
+	m, err := s.Complete(ctx, 1, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.ID != checksum(content) || m.Size != int64(len(content)) {
+		t.Fatalf("media = %+v", m)
+	}
+	rc, _, err := blobs.Get(ctx, m.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	if !bytes.Equal(got, content) {
+		t.Fatal("assembled content differs")
+	}
+	if ok, _ := blobs.Exists(ctx, u.ChunkKey(0)); ok {
+		t.Fatal("chunks not cleaned up after complete")
+	}
+
+	// 同一内容普通上传，得到同一个媒体
+	again, err := s.media.Upload(ctx, 2, bytes.NewReader(content), "")
+	if err != nil || again.ID != m.ID {
+		t.Fatalf("dedupe upload = %+v, %v", again, err)
+	}
+}
+
+func TestUploadGC(t *testing.T) {
+	ctx := context.Background()
+	s, uploads, blobs := newTestUploads(t)
+	u, err := s.Init(ctx, 1, 10, minChunkSize, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part := []byte("0123456789")
+	if err := s.PutChunk(ctx, 1, u.ID, 0, part, checksum(part)); err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return time.Now().Add(s.TTL + time.Minute) }
+	if _, _, err := s.Status(ctx, 1, u.ID); !errors.Is(err, domain.ErrUploadNotFound) {
+		t.Fatalf("expired status err = %v", err)
+	}
+	n, err := s.CollectGarbage(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("gc = %d, %v", n, err)
+	}
+	if _, ok := uploads.uploads[u.ID]; ok {
+		t.Fatal("upload record not removed")
+	}
+	if ok, _ := blobs.Exists(ctx, u.ChunkKey(0)); ok {
+		t.Fatal("chunk blob not removed")
+	}
+}
@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// ConversationType 会话类型
+type ConversationType string
+
+const (
+	ConversationDirect ConversationType = "direct"
+)
+
+// DirectConversationID 单聊会话 ID 由双方 ID 决定，小的在前
+func DirectConversationID(a, b uint) string {
+	if a > b {
+		a, b = b, a
+	}
+	return fmt.Sprintf("d_%d_%d", a, b)
+}
+
+type Conversation struct {
+	ID   string
+	Type ConversationType
+	// LastSeq 会话内最后一条消息的序号；序号从 1 开始、会话内单调递增
+	LastSeq   uint64
+	UpdatedAt time.Time
+}
+
+// Member 会话成员；ReadSeq 为已读游标：该序号及之前的消息均视为已读
+type Member struct {
+	ConversationID string
+	UserID         uint
+	ReadSeq        uint64
+	JoinedAt       time.Time
+}
+
+type Message struct {
+	ID             uint64
+	ConversationID string
+	Seq            uint64
+	SenderID       uint
+	// Type 与网关帧类型一致
+	Type      int
+	Content   string
+	CreatedAt time.Time
+	// EditedAt/RecalledAt 零值表示未编辑/未撤回；撤回后 Content 置空
+	EditedAt   time.Time
+	RecalledAt time.Time
+	// ReplyToID 引用/回复的消息，0 表示不是回复
+	ReplyToID uint64
+	// ThreadRootID 话题内回复所属的根消息，0 表示顶层消息（含普通引用回复）
+	ThreadRootID uint64
+	// 话题根消息上的汇总
+	ThreadReplyCount  int64
+	ThreadLastReplyAt time.Time
+	// Reactions 表情回应汇总，仅历史查询时填充
+	Reactions []Reaction
+	// Mentions 消息里的 @ 实体；MentionedIDs 为发送时展开得到的被提及成员（@online 展开），
+	// 只在发送时填充，用于统计未读 @ 数
+	Mentions     []Mention
+	MentionedIDs []uint
+}
+
+// Draft 待发送的消息
+type Draft struct {
+	Type    int
+	Content string
+	// ReplyToID 回复的消息；InThread 为 true 时作为话题回复，不出现在会话顶层
+	ReplyToID uint64
+	InThread  bool
+	Mentions  []Mention
+}
+
+// Recalled 是否已撤回
+func (m *Message) Recalled() bool {
+	return !m.RecalledAt.IsZero()
+}
+
+// MessageOp 对已存储消息的操作
+type MessageOp string
+
+const (
+	// OpRecall 撤回：发送方在时间窗口内撤回，对所有人生效
+	OpRecall MessageOp = "recall"
+	// OpEdit 编辑：发送方修改内容，对所有人生效
+	OpEdit MessageOp = "edit"
+	// OpDelete 仅对自己删除
+	OpDelete MessageOp = "delete"
+)
+
+// ConversationSummary 会话列表项
+type ConversationSummary struct {
+	Conversation
+	ReadSeq uint64
+	Unread  int64
+	// UnreadMentions 未读消息中 @ 了该用户的条数
+	UnreadMentions int64
+	LastMessage    *Message
+}
+
+// Reaction 某个表情在一条消息上的汇总
+type Reaction struct {
+	Emoji   string
+	Count   int
+	UserIDs []uint
+}
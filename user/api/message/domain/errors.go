@@ -0,0 +1,12 @@
+package domain
+
+import "errors"
+
+var (
+	// ErrConversationNotFound 会话不存在
+	ErrConversationNotFound = errors.New("conversation not found")
+	// ErrNotMember 不是会话成员
+	ErrNotMember = errors.New("not a conversation member")
+	// ErrMessageNotFound 消息不存在
+	ErrMessageNotFound = errors.New("message not found")
+)
@@ -0,0 +1,33 @@
+package domain
+
+// 提及类型。@all（仅管理员可用）依赖群聊与成员角色，目前只有单聊，暂不支持
+const (
+	MentionUser   = "user"
+	MentionOnline = "online"
+)
+
+// Mention 消息里的一个 @ 实体。Offset/Length 为其在文本内容中的位置（按字符计），
+// 仅文本消息有意义，便于客户端高亮；@online 不带 UserID
+type Mention struct {
+	Type   string `json:"type"`
+	UserID uint   `json:"user_id,omitempty"`
+	Offset int    `json:"offset,omitempty"`
+	Length int    `json:"length,omitempty"`
+}
+
+// MentionsUser 一组提及是否指向 userID；online 为该用户当前是否在线（用于 @online）
+func MentionsUser(ms []Mention, userID uint, online bool) bool {
+	for _, m := range ms {
+		switch m.Type {
+		case MentionUser:
+			if m.UserID == userID {
+				return true
+			}
+		case MentionOnline:
+			if online {
+				return true
+			}
+		}
+	}
+	return false
+}
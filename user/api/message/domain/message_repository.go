@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ConversationRepository 会话与成员（含已读游标）
+type ConversationRepository interface {
+	// EnsureDirect 单聊会话不存在时创建，并登记双方为成员
+	EnsureDirect(ctx context.Context, conversationID string, a, b uint) error
+	GetMember(ctx context.Context, conversationID string, userID uint) (*Member, error)
+	ListMembers(ctx context.Context, conversationID string) ([]Member, error)
+	// AdvanceReadSeq 只前进不后退，且不超过会话 LastSeq；返回推进后的游标与是否有变化
+	AdvanceReadSeq(ctx context.Context, conversationID string, userID uint, seq uint64) (uint64, bool, error)
+	ListConversations(ctx context.Context, userID uint, limit int) ([]ConversationSummary, error)
+}
+
+// MessageRepository 消息存储
+type MessageRepository interface {
+	// Append 在会话内分配序号并写入，回填 ID/Seq/CreatedAt；话题回复同时更新根消息的汇总；
+	// 富消息引用的媒体登记到会话下，供下载鉴权
+	Append(ctx context.Context, m *Message) error
+	// ListMessages 按序号倒序取 beforeSeq 之前的 limit 条（beforeSeq 为 0 表示从最新开始），结果按序号正序返回；
+	// 跳过 viewerID 自己删除的消息与话题内回复
+	ListMessages(ctx context.Context, conversationID string, viewerID uint, beforeSeq uint64, limit int) ([]Message, error)
+	// ListThread 按序号正序取话题 rootID 下 afterSeq 之后的 limit 条回复，跳过 viewerID 自己删除的
+	ListThread(ctx context.Context, rootID uint64, viewerID uint, afterSeq uint64, limit int) ([]Message, error)
+	GetMessage(ctx context.Context, messageID uint64) (*Message, error)
+	// Recall 标记撤回并清空内容；已撤回的返回 ErrMessageNotFound
+	Recall(ctx context.Context, messageID uint64, at time.Time) error
+	// Edit 修改内容；已撤回的返回 ErrMessageNotFound
+	Edit(ctx context.Context, messageID uint64, content string, at time.Time) error
+	// DeleteForUser 仅对 userID 隐藏该消息
+	DeleteForUser(ctx context.Context, userID uint, messageID uint64) error
+	// SharesMedia userID 所在的会话中是否有未撤回的消息引用了该媒体
+	SharesMedia(ctx context.Context, userID uint, mediaID string) (bool, error)
+}
+
+// ReactionRepository 表情回应
+type ReactionRepository interface {
+	// AddReaction 幂等，返回是否新增
+	AddReaction(ctx context.Context, messageID uint64, userID uint, emoji string) (bool, error)
+	// RemoveReaction 幂等，返回是否删除
+	RemoveReaction(ctx context.Context, messageID uint64, userID uint, emoji string) (bool, error)
+	CountReaction(ctx context.Context, messageID uint64, emoji string) (int, error)
+	// ListReactions 按消息聚合，表情按首次出现时间排序
+	ListReactions(ctx context.Context, messageIDs []uint64) (map[uint64][]Reaction, error)
+}
@@ -0,0 +1,236 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"strings"
+)
+
+// 消息内容类型，取值与网关帧类型一致
+const (
+	TypeText     = 1
+	TypeImage    = 2
+	TypeVoice    = 3
+	TypeVideo    = 4
+	TypeFile     = 5
+	TypeLocation = 18
+	TypeContact  = 19
+	TypeSticker  = 20
+	// TypeCall 通话记录，由服务端在通话结束时写入，客户端不能直接发送
+	TypeCall = 21
+)
+
+// PayloadVersion 当前富消息结构版本；结构有不兼容变化时递增，旧版本仍需能解析
+const PayloadVersion = 1
+
+// ErrInvalidPayload 富消息内容不合法
+var ErrInvalidPayload = errors.New("invalid payload")
+
+// 富消息字段上限
+const (
+	maxMediaRefLen  = 255
+	maxNameLen      = 255
+	maxDimension    = 20000
+	maxDurationMs   = 4 * 60 * 60 * 1000
+	maxFileSize     = 4 << 30
+	maxStickerIDLen = 64
+)
+
+// ImagePayload 图片：Media 为媒体 ID/引用，不内联二进制
+type ImagePayload struct {
+	V         int    `json:"v"`
+	Media     string `json:"media"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Thumbnail string `json:"thumbnail,omitempty"`
+}
+
+type VoicePayload struct {
+	V          int    `json:"v"`
+	Media      string `json:"media"`
+	DurationMs int    `json:"duration_ms"`
+}
+
+type VideoPayload struct {
+	V          int    `json:"v"`
+	Media      string `json:"media"`
+	DurationMs int    `json:"duration_ms"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	Thumbnail  string `json:"thumbnail,omitempty"`
+}
+
+type FilePayload struct {
+	V     int    `json:"v"`
+	Media string `json:"media"`
+	Name  string `json:"name"`
+	Size  int64  `json:"size"`
+	MIME  string `json:"mime"`
+}
+
+type LocationPayload struct {
+	V         int     `json:"v"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Name      string  `json:"name,omitempty"`
+	Address   string  `json:"address,omitempty"`
+}
+
+// ContactPayload 名片：分享一个用户
+type ContactPayload struct {
+	V           int    `json:"v"`
+	UserID      uint   `json:"user_id"`
+	DisplayName string `json:"display_name,omitempty"`
+}
+
+type StickerPayload struct {
+	V         int    `json:"v"`
+	PackID    string `json:"pack_id"`
+	StickerID string `json:"sticker_id"`
+}
+
+// CallPayload 通话记录：Result 为通话结束原因（completed/canceled/rejected/missed/busy）
+type CallPayload struct {
+	V          int    `json:"v"`
+	CallID     string `json:"call_id"`
+	Media      string `json:"media"`
+	CallerID   uint   `json:"caller_id"`
+	Result     string `json:"result"`
+	DurationMs int64  `json:"duration_ms"`
+	// Participants 接通过的参与者
+	Participants []uint `json:"participants,omitempty"`
+}
+
+// IsRichType 内容为结构化 JSON 的消息类型
+func IsRichType(typ int) bool {
+	switch typ {
+	case TypeImage, TypeVoice, TypeVideo, TypeFile, TypeLocation, TypeContact, TypeSticker:
+		return true
+	}
+	return false
+}
+
+// IsContentType 可存储的聊天内容类型
+func IsContentType(typ int) bool {
+	return typ == TypeText || IsRichType(typ)
+}
+
+// HasPayload 内容以 JSON 形式下发的消息类型：富消息及服务端生成的通话记录
+func HasPayload(typ int) bool {
+	return IsRichType(typ) || typ == TypeCall
+}
+
+// NormalizePayload 校验富消息内容并重新编码：丢弃未知字段，得到规范化的 JSON
+func NormalizePayload(typ int, content string) (string, error) {
+	var (
+		p     any
+		valid func() bool
+	)
+	switch typ {
+	case TypeImage:
+		v := &ImagePayload{}
+		p, valid = v, func() bool {
+			return validRef(v.Media) && validDims(v.Width, v.Height) && validOptionalRef(v.Thumbnail)
+		}
+	case TypeVoice:
+		v := &VoicePayload{}
+		p, valid = v, func() bool {
+			return validRef(v.Media) && validDuration(v.DurationMs)
+		}
+	case TypeVideo:
+		v := &VideoPayload{}
+		p, valid = v, func() bool {
+			return validRef(v.Media) && validDuration(v.DurationMs) &&
+				validDims(v.Width, v.Height) && validOptionalRef(v.Thumbnail)
+		}
+	case TypeFile:
+		v := &FilePayload{}
+		p, valid = v, func() bool {
+			if !validRef(v.Media) || v.Name == "" || len(v.Name) > maxNameLen || strings.ContainsAny(v.Name, "/\\\x00") {
+				return false
+			}
+			if v.Size <= 0 || v.Size > maxFileSize {
+				return false
+			}
+			mt, _, err := mime.ParseMediaType(v.MIME)
+			return err == nil && strings.Contains(mt, "/")
+		}
+	case TypeLocation:
+		v := &LocationPayload{}
+		p, valid = v, func() bool {
+			return v.Latitude >= -90 && v.Latitude <= 90 &&
+				v.Longitude >= -180 && v.Longitude <= 180 &&
+				len(v.Name) <= maxNameLen && len(v.Address) <= maxNameLen
+		}
+	case TypeContact:
+		v := &ContactPayload{}
+		p, valid = v, func() bool {
+			return v.UserID != 0 && len(v.DisplayName) <= maxNameLen
+		}
+	case TypeSticker:
+		v := &StickerPayload{}
+		p, valid = v, func() bool {
+			return v.PackID != "" && len(v.PackID) <= maxStickerIDLen &&
+				v.StickerID != "" && len(v.StickerID) <= maxStickerIDLen
+		}
+	default:
+		return "", ErrInvalidPayload
+	}
+
+	if err := json.Unmarshal([]byte(content), p); err != nil {
+		return "", ErrInvalidPayload
+	}
+	// 版本号单独读取：未知的新版本一律拒绝，由客户端降级发送
+	var ver struct {
+		V int `json:"v"`
+	}
+	_ = json.Unmarshal([]byte(content), &ver)
+	if ver.V < 1 || ver.V > PayloadVersion || !valid() {
+		return "", ErrInvalidPayload
+	}
+	out, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// MediaRefs 富消息引用的媒体（原文件及缩略图），内容须已通过 NormalizePayload
+func MediaRefs(typ int, content string) []string {
+	var refs struct {
+		Media     string `json:"media"`
+		Thumbnail string `json:"thumbnail"`
+	}
+	switch typ {
+	case TypeImage, TypeVoice, TypeVideo, TypeFile:
+	default:
+		return nil
+	}
+	if json.Unmarshal([]byte(content), &refs) != nil {
+		return nil
+	}
+	var out []string
+	for _, r := range []string{refs.Media, refs.Thumbnail} {
+		if r != "" {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func validRef(ref string) bool {
+	return ref != "" && len(ref) <= maxMediaRefLen
+}
+
+func validOptionalRef(ref string) bool {
+	return ref == "" || validRef(ref)
+}
+
+func validDims(w, h int) bool {
+	return w > 0 && h > 0 && w <= maxDimension && h <= maxDimension
+}
+
+func validDuration(ms int) bool {
+	return ms > 0 && ms <= maxDurationMs
+}
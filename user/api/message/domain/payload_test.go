@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizePayload(t *testing.T) {
+	long := strings.Repeat("x", 256)
+	cases := []struct {
+		name    string
+		typ     int
+		content string
+		ok      bool
+	}{
+		{"image", TypeImage, `{"v":1,"media":"m1","width":640,"height":480,"thumbnail":"t1"}`, true},
+		{"image without thumbnail", TypeImage, `{"v":1,"media":"m1","width":640,"height":480}`, true},
+		{"image missing media", TypeImage, `{"v":1,"width":640,"height":480}`, false},
+		{"image zero width", TypeImage, `{"v":1,"media":"m1","width":0,"height":480}`, false},
+		{"image too large", TypeImage, `{"v":1,"media":"m1","width":20001,"height":480}`, false},
+		{"image long ref", TypeImage, `{"v":1,"media":"` + long + `","width":1,"height":1}`, false},
+		{"image long thumbnail", TypeImage, `{"v":1,"media":"m1","width":1,"height":1,"thumbnail":"` + long + `"}`, false},
+
+		{"voice", TypeVoice, `{"v":1,"media":"m1","duration_ms":3000}`, true},
+		{"voice zero duration", TypeVoice, `{"v":1,"media":"m1","duration_ms":0}`, false},
+		{"voice too long", TypeVoice, `{"v":1,"media":"m1","duration_ms":14400001}`, false},
+
+		{"video", TypeVideo, `{"v":1,"media":"m1","duration_ms":3000,"width":1920,"height":1080}`, true},
+		{"video missing dims", TypeVideo, `{"v":1,"media":"m1","duration_ms":3000}`, false},
+
+		{"file", TypeFile, `{"v":1,"media":"m1","name":"a.pdf","size":1024,"mime":"application/pdf"}`, true},
+		{"file path in name", TypeFile, `{"v":1,"media":"m1","name":"../a.pdf","size":1024,"mime":"application/pdf"}`, false},
+		{"file empty", TypeFile, `{"v":1,"media":"m1","name":"a.pdf","size":0,"mime":"application/pdf"}`, false},
+		{"file bad mime", TypeFile, `{"v":1,"media":"m1","name":"a.pdf","size":1,"mime":"pdf"}`, false},
+
+		{"location", TypeLocation, `{"v":1,"latitude":31.2,"longitude":121.5,"name":"office"}`, true},
+		{"location out of range", TypeLocation, `{"v":1,"latitude":91,"longitude":0}`, false},
+		{"location long address", TypeLocation, `{"v":1,"latitude":0,"longitude":0,"address":"` + long + `"}`, false},
+
+		{"contact", TypeContact, `{"v":1,"user_id":7,"display_name":"bob"}`, true},
+		{"contact without user", TypeContact, `{"v":1,"display_name":"bob"}`, false},
+
+		{"sticker", TypeSticker, `{"v":1,"pack_id":"cats","sticker_id":"wave"}`, true},
+		{"sticker missing id", TypeSticker, `{"v":1,"pack_id":"cats"}`, false},
+
+		{"missing version", TypeSticker, `{"pack_id":"cats","sticker_id":"wave"}`, false},
+		{"future version", TypeSticker, `{"v":2,"pack_id":"cats","sticker_id":"wave"}`, false},
+		{"not json", TypeImage, `hello`, false},
+		{"wrong field type", TypeImage, `{"v":1,"media":1,"width":1,"height":1}`, false},
+		{"text is not rich", TypeText, `{"v":1}`, false},
+		{"call is server only", TypeCall, `{"v":1,"call_id":"c1"}`, false},
+	}
+	for _, c := range cases {
+		_, err := NormalizePayload(c.typ, c.content)
+		if (err == nil) != c.ok {
+			t.Errorf("%s: got %v", c.name, err)
+		}
+	}
+}
+
+func TestNormalizePayloadDropsUnknownFields(t *testing.T) {
+	out, err := NormalizePayload(TypeSticker, `{"v":1,"pack_id":"cats","sticker_id":"wave","script":"alert(1)"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal([]byte(out), &m); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["script"]; ok || m["pack_id"] != "cats" {
+		t.Fatalf("normalized: %s", out)
+	}
+}
+
+func TestMediaRefs(t *testing.T) {
+	cases := []struct {
+		typ     int
+		content string
+		want    []string
+	}{
+		{TypeImage, `{"v":1,"media":"m1","width":1,"height":1,"thumbnail":"t1"}`, []string{"m1", "t1"}},
+		{TypeVoice, `{"v":1,"media":"m1","duration_ms":1}`, []string{"m1"}},
+		{TypeFile, `{"v":1,"media":"m1","name":"a","size":1,"mime":"text/plain"}`, []string{"m1"}},
+		{TypeLocation, `{"v":1,"latitude":0,"longitude":0}`, nil},
+		{TypeSticker, `{"v":1,"pack_id":"cats","sticker_id":"wave"}`, nil},
+		{TypeImage, `broken`, nil},
+	}
+	for _, c := range cases {
+		if got := MediaRefs(c.typ, c.content); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("type %d %s: got %v, want %v", c.typ, c.content, got, c.want)
+		}
+	}
+}
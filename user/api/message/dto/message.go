@@ -0,0 +1,185 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	"wsim/user/api/message/domain"
+)
+
+// Message 历史接口与网关推送共用的消息结构
+type Message struct {
+	MessageID      uint64 `json:"message_id"`
+	ConversationID string `json:"conversation_id"`
+	Seq            uint64 `json:"seq"`
+	SenderID       uint   `json:"sender_id"`
+	Type           int    `json:"type"`
+	// Content 文本内容；富消息（图片、语音、文件、位置等）及通话记录的内容放在 Payload，Content 为空
+	Content   string          `json:"content"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	// EditedAt 未编辑为 null
+	EditedAt *time.Time `json:"edited_at"`
+	Recalled bool       `json:"recalled"`
+	// ReplyToID 引用/回复的消息；ThreadRootID 非 0 表示这是话题内回复
+	ReplyToID    uint64 `json:"reply_to_id,omitempty"`
+	ThreadRootID uint64 `json:"thread_root_id,omitempty"`
+	// 仅话题根消息有值
+	ThreadReplyCount  int64      `json:"thread_reply_count,omitempty"`
+	ThreadLastReplyAt *time.Time `json:"thread_last_reply_at,omitempty"`
+	Reactions         []Reaction `json:"reactions,omitempty"`
+	Mentions          []Mention  `json:"mentions,omitempty"`
+	// Silent 仅网关投递时有值：接收方的通知设置（静音、免打扰等）要求这条消息不响铃、不弹横幅
+	Silent bool `json:"silent,omitempty"`
+}
+
+// Reaction 表情回应汇总
+type Reaction struct {
+	Emoji   string `json:"emoji"`
+	Count   int    `json:"count"`
+	UserIDs []uint `json:"user_ids"`
+}
+
+// Mention @ 实体：type 为 user（带 user_id）或 online；offset/length 为在文本中的字符位置
+type Mention struct {
+	Type   string `json:"type"`
+	UserID uint   `json:"user_id,omitempty"`
+	Offset int    `json:"offset,omitempty"`
+	Length int    `json:"length,omitempty"`
+}
+
+// ReactionEvent 表情回应帧。客户端上报 message_id、emoji、action（add/remove）；
+// 服务端转发时补全会话、操作人与该表情的最新计数，不带完整的回应者列表
+type ReactionEvent struct {
+	MessageID      uint64 `json:"message_id"`
+	Emoji          string `json:"emoji"`
+	Action         string `json:"action"`
+	ConversationID string `json:"conversation_id,omitempty"`
+	UserID         uint   `json:"user_id,omitempty"`
+	Count          int    `json:"count"`
+}
+
+// Reply 回复帧：包裹一条任意类型的消息，附带被回复消息 ID 与 @ 实体。
+// 不回复任何消息、只带 @ 时 reply_to 为 0（见网关 MessageTypeCompose）
+type Reply struct {
+	ReplyTo  uint64 `json:"reply_to"`
+	InThread bool   `json:"in_thread"`
+	// Type 被包裹消息的帧类型，缺省为文本
+	Type    int    `json:"type"`
+	Content string `json:"content"`
+	// Payload 富消息内容，与对应帧的 Data 结构相同；有值时忽略 Content
+	Payload  json.RawMessage `json:"payload,omitempty"`
+	Mentions []Mention       `json:"mentions,omitempty"`
+}
+
+// ThreadUpdate 话题有新回复时推给会话成员的摘要，话题回复不再作为顶层消息投递
+type ThreadUpdate struct {
+	ConversationID string  `json:"conversation_id"`
+	RootID         uint64  `json:"root_id"`
+	ReplyCount     int64   `json:"reply_count"`
+	Reply          Message `json:"reply"`
+}
+
+type ThreadResponse struct {
+	Root    Message   `json:"root"`
+	Replies []Message `json:"replies"`
+}
+
+// MessageOp 撤回/编辑/删除控制帧。客户端上报 op、message_id（编辑时带 content），
+// 服务端转发时补全会话、序号、操作人与时间
+type MessageOp struct {
+	Op             string    `json:"op"`
+	MessageID      uint64    `json:"message_id"`
+	Content        string    `json:"content,omitempty"`
+	ConversationID string    `json:"conversation_id,omitempty"`
+	Seq            uint64    `json:"seq,omitempty"`
+	OperatorID     uint      `json:"operator_id,omitempty"`
+	At             time.Time `json:"at,omitzero"`
+}
+
+// ReadCursor 成员已读游标
+type ReadCursor struct {
+	UserID  uint   `json:"user_id"`
+	ReadSeq uint64 `json:"read_seq"`
+}
+
+// ReadReceipt 已读回执：客户端上报时只需 conversation_id 与 seq，服务端转发时补全 user_id
+type ReadReceipt struct {
+	ConversationID string `json:"conversation_id"`
+	UserID         uint   `json:"user_id,omitempty"`
+	Seq            uint64 `json:"seq"`
+}
+
+type HistoryResponse struct {
+	Messages    []Message    `json:"messages"`
+	ReadCursors []ReadCursor `json:"read_cursors"`
+}
+
+type Conversation struct {
+	ConversationID string    `json:"conversation_id"`
+	Type           string    `json:"type"`
+	LastSeq        uint64    `json:"last_seq"`
+	ReadSeq        uint64    `json:"read_seq"`
+	Unread         int64     `json:"unread"`
+	UnreadMentions int64     `json:"unread_mentions"`
+	LastMessage    *Message  `json:"last_message"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type ConversationListResponse struct {
+	Conversations []Conversation `json:"conversations"`
+}
+
+func FromMessage(m *domain.Message) Message {
+	out := Message{
+		MessageID:      m.ID,
+		ConversationID: m.ConversationID,
+		Seq:            m.Seq,
+		SenderID:       m.SenderID,
+		Type:           m.Type,
+		Content:        m.Content,
+		CreatedAt:      m.CreatedAt,
+		Recalled:       m.Recalled(),
+	}
+	if domain.HasPayload(m.Type) {
+		out.Content = ""
+		if m.Content != "" {
+			out.Payload = json.RawMessage(m.Content)
+		}
+	}
+	if !m.EditedAt.IsZero() {
+		t := m.EditedAt
+		out.EditedAt = &t
+	}
+	out.ReplyToID = m.ReplyToID
+	out.ThreadRootID = m.ThreadRootID
+	out.ThreadReplyCount = m.ThreadReplyCount
+	if !m.ThreadLastReplyAt.IsZero() {
+		t := m.ThreadLastReplyAt
+		out.ThreadLastReplyAt = &t
+	}
+	for _, r := range m.Reactions {
+		out.Reactions = append(out.Reactions, Reaction{Emoji: r.Emoji, Count: r.Count, UserIDs: r.UserIDs})
+	}
+	for _, mt := range m.Mentions {
+		out.Mentions = append(out.Mentions, Mention(mt))
+	}
+	return out
+}
+
+// ToMentions 转为领域实体
+func ToMentions(ms []Mention) []domain.Mention {
+	out := make([]domain.Mention, 0, len(ms))
+	for _, m := range ms {
+		out = append(out, domain.Mention(m))
+	}
+	return out
+}
+
+func FromMembers(ms []domain.Member) []ReadCursor {
+	out := make([]ReadCursor, 0, len(ms))
+	for _, m := range ms {
+		out = append(out, ReadCursor{UserID: m.UserID, ReadSeq: m.ReadSeq})
+	}
+	return out
+}
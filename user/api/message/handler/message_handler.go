@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"wsim/user/api/identity"
+	"wsim/user/api/message/domain"
+	"wsim/user/api/message/dto"
+	"wsim/user/api/message/usecase"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+)
+
+type MessageHandler struct {
+	messages *usecase.MessageService
+}
+
+func NewMessageHandler(messages *usecase.MessageService) *MessageHandler {
+	return &MessageHandler{messages: messages}
+}
+
+// Conversations 会话列表（含未读数）
+func (h *MessageHandler) Conversations(ctx context.Context, c *app.RequestContext) {
+	uid, err := identity.UserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	cs, err := h.messages.Conversations(ctx, uid)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	res := dto.ConversationListResponse{Conversations: make([]dto.Conversation, 0, len(cs))}
+	for _, cv := range cs {
+		item := dto.Conversation{
+			ConversationID: cv.ID,
+			Type:           string(cv.Type),
+			LastSeq:        cv.LastSeq,
+			ReadSeq:        cv.ReadSeq,
+			Unread:         cv.Unread,
+			UnreadMentions: cv.UnreadMentions,
+			UpdatedAt:      cv.UpdatedAt,
+		}
+		if cv.LastMessage != nil {
+			m := dto.FromMessage(cv.LastMessage)
+			item.LastMessage = &m
+		}
+		res.Conversations = append(res.Conversations, item)
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// History GET ?before_seq=&limit=
+func (h *MessageHandler) History(ctx context.Context, c *app.RequestContext) {
+	uid, err := identity.UserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	beforeSeq, _ := strconv.ParseUint(c.Query("before_seq"), 10, 64)
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	msgs, members, err := h.messages.History(ctx, uid, c.Param("conversation_id"), beforeSeq, limit)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	res := dto.HistoryResponse{
+		Messages:    make([]dto.Message, 0, len(msgs)),
+		ReadCursors: dto.FromMembers(members),
+	}
+	for i := range msgs {
+		res.Messages = append(res.Messages, dto.FromMessage(&msgs[i]))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// Thread GET ?after_seq=&limit=
+func (h *MessageHandler) Thread(ctx context.Context, c *app.RequestContext) {
+	uid, err := identity.UserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	rootID, err := strconv.ParseUint(c.Param("root_id"), 10, 64)
+	if err != nil {
+		writeErr(c, usecase.ErrBadRequest)
+		return
+	}
+	afterSeq, _ := strconv.ParseUint(c.Query("after_seq"), 10, 64)
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	root, replies, err := h.messages.Thread(ctx, uid, c.Param("conversation_id"), rootID, afterSeq, limit)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	res := dto.ThreadResponse{
+		Root:    dto.FromMessage(root),
+		Replies: make([]dto.Message, 0, len(replies)),
+	}
+	for i := range replies {
+		res.Replies = append(res.Replies, dto.FromMessage(&replies[i]))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func writeErr(c *app.RequestContext, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrBadRequest), errors.Is(err, usecase.ErrInvalidEmoji):
+		c.JSON(http.StatusBadRequest, utils.H{"error": "bad request"})
+	case errors.Is(err, identity.ErrUnauthorized):
+		c.JSON(http.StatusUnauthorized, utils.H{"error": "unauthorized"})
+	case errors.Is(err, domain.ErrNotMember), errors.Is(err, usecase.ErrForbidden):
+		c.JSON(http.StatusForbidden, utils.H{"error": "forbidden"})
+	case errors.Is(err, domain.ErrConversationNotFound):
+		c.JSON(http.StatusNotFound, utils.H{"error": "conversation not found"})
+	case errors.Is(err, domain.ErrMessageNotFound):
+		c.JSON(http.StatusNotFound, utils.H{"error": "message not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, utils.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,440 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"wsim/user/api/message/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ConversationModel struct {
+	ID        string    `gorm:"type:varchar(64);primaryKey"`
+	Type      string    `gorm:"type:varchar(16);not null"`
+	LastSeq   uint64    `gorm:"not null;default:0"`
+	CreatedAt time.Time `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null;index"`
+}
+
+func (ConversationModel) TableName() string { return "conversations" }
+
+// MemberModel 会话成员；user_id 索引用于查询"我的会话列表"
+type MemberModel struct {
+	ConversationID string    `gorm:"type:varchar(64);primaryKey"`
+	UserID         uint      `gorm:"primaryKey;autoIncrement:false;index"`
+	ReadSeq        uint64    `gorm:"not null;default:0"`
+	JoinedAt       time.Time `gorm:"not null"`
+}
+
+func (MemberModel) TableName() string { return "conversation_members" }
+
+type MessageModel struct {
+	ID             uint64    `gorm:"primaryKey"`
+	ConversationID string    `gorm:"type:varchar(64);not null;uniqueIndex:idx_messages_conversation_seq,priority:1"`
+	Seq            uint64    `gorm:"not null;uniqueIndex:idx_messages_conversation_seq,priority:2"`
+	SenderID       uint      `gorm:"not null"`
+	Type           int       `gorm:"type:smallint;not null"`
+	Content        string    `gorm:"type:text;not null"`
+	CreatedAt      time.Time `gorm:"not null"`
+	EditedAt       *time.Time
+	RecalledAt     *time.Time
+	ReplyToID      *uint64
+	ThreadRootID   *uint64 `gorm:"index"`
+	// 话题根消息上的汇总，避免每次 COUNT
+	ThreadReplyCount  int64 `gorm:"not null;default:0"`
+	ThreadLastReplyAt *time.Time
+	// Mentions @ 实体的 JSON，没有时为空串
+	Mentions string `gorm:"type:text;not null;default:''"`
+}
+
+func (MessageModel) TableName() string { return "messages" }
+
+// DeletionModel 仅对自己删除的消息
+type DeletionModel struct {
+	UserID    uint      `gorm:"primaryKey;autoIncrement:false"`
+	MessageID uint64    `gorm:"primaryKey;autoIncrement:false"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+func (DeletionModel) TableName() string { return "message_deletions" }
+
+// MediaRefModel 消息引用的媒体，用于判断谁可以下载
+type MediaRefModel struct {
+	MessageID      uint64 `gorm:"primaryKey;autoIncrement:false"`
+	MediaID        string `gorm:"type:varchar(255);primaryKey;index"`
+	ConversationID string `gorm:"type:varchar(64);not null"`
+}
+
+func (MediaRefModel) TableName() string { return "message_media" }
+
+// MentionModel 消息展开后的被提及成员，用于统计未读 @ 数
+type MentionModel struct {
+	MessageID      uint64 `gorm:"primaryKey;autoIncrement:false"`
+	UserID         uint   `gorm:"primaryKey;autoIncrement:false"`
+	ConversationID string `gorm:"type:varchar(64);not null;index:idx_message_mentions_user_conv_seq,priority:2"`
+	Seq            uint64 `gorm:"not null;index:idx_message_mentions_user_conv_seq,priority:3"`
+}
+
+func (MentionModel) TableName() string { return "message_mentions" }
+
+// notDeletedFor 过滤掉查看者自己删除的消息
+const notDeletedFor = "NOT EXISTS (SELECT 1 FROM message_deletions d WHERE d.message_id = messages.id AND d.user_id = ?)"
+
+type PostgresMessageRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresMessageRepository(db *gorm.DB) (*PostgresMessageRepository, error) {
+	if err := db.AutoMigrate(&ConversationModel{}, &MemberModel{}, &MessageModel{}, &DeletionModel{}, &MediaRefModel{}, &MentionModel{}); err != nil {
+		return nil, err
+	}
+	return &PostgresMessageRepository{db: db}, nil
+}
+
+func (r *PostgresMessageRepository) EnsureDirect(ctx context.Context, conversationID string, a, b uint) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		conv := &ConversationModel{
+			ID:        conversationID,
+			Type:      string(domain.ConversationDirect),
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(conv)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			// 已存在
+			return nil
+		}
+		members := []MemberModel{
+			{ConversationID: conversationID, UserID: a, JoinedAt: now},
+			{ConversationID: conversationID, UserID: b, JoinedAt: now},
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&members).Error
+	})
+}
+
+func (r *PostgresMessageRepository) GetMember(ctx context.Context, conversationID string, userID uint) (*domain.Member, error) {
+	var m MemberModel
+	tx := r.db.WithContext(ctx).
+		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		Limit(1).
+		Find(&m)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return nil, domain.ErrNotMember
+	}
+	return toMember(&m), nil
+}
+
+func (r *PostgresMessageRepository) ListMembers(ctx context.Context, conversationID string) ([]domain.Member, error) {
+	var ms []MemberModel
+	if err := r.db.WithContext(ctx).Where("conversation_id = ?", conversationID).Find(&ms).Error; err != nil {
+		return nil, err
+	}
+	out := make([]domain.Member, 0, len(ms))
+	for i := range ms {
+		out = append(out, *toMember(&ms[i]))
+	}
+	return out, nil
+}
+
+func (r *PostgresMessageRepository) AdvanceReadSeq(ctx context.Context, conversationID string, userID uint, seq uint64) (uint64, bool, error) {
+	var readSeq uint64
+	res := r.db.WithContext(ctx).Raw(`
+UPDATE conversation_members m
+SET read_seq = LEAST(?, c.last_seq)
+FROM conversations c
+WHERE c.id = m.conversation_id
+  AND m.conversation_id = ? AND m.user_id = ?
+  AND m.read_seq < LEAST(?, c.last_seq)
+RETURNING m.read_seq`, seq, conversationID, userID, seq).Scan(&readSeq)
+	if res.Error != nil {
+		return 0, false, res.Error
+	}
+	if res.RowsAffected > 0 {
+		return readSeq, true, nil
+	}
+	// 没有推进：游标已不小于 seq，或不是成员
+	m, err := r.GetMember(ctx, conversationID, userID)
+	if err != nil {
+		return 0, false, err
+	}
+	return m.ReadSeq, false, nil
+}
+
+func (r *PostgresMessageRepository) ListConversations(ctx context.Context, userID uint, limit int) ([]domain.ConversationSummary, error) {
+	type row struct {
+		ID             string
+		Type           string
+		LastSeq        uint64
+		UpdatedAt      time.Time
+		ReadSeq        uint64
+		Unread         int64
+		UnreadMentions int64
+	}
+	var rows []row
+	err := r.db.WithContext(ctx).Raw(`
+SELECT c.id, c.type, c.last_seq, c.updated_at, m.read_seq,
+       (SELECT COUNT(*) FROM messages msg
+         WHERE msg.conversation_id = c.id AND msg.seq > m.read_seq AND msg.sender_id <> m.user_id
+           AND msg.recalled_at IS NULL AND msg.thread_root_id IS NULL) AS unread,
+       (SELECT COUNT(*) FROM message_mentions mm
+         JOIN messages msg ON msg.id = mm.message_id AND msg.recalled_at IS NULL
+         WHERE mm.user_id = m.user_id AND mm.conversation_id = c.id AND mm.seq > m.read_seq) AS unread_mentions
+FROM conversation_members m
+JOIN conversations c ON c.id = m.conversation_id
+WHERE m.user_id = ?
+ORDER BY c.updated_at DESC
+LIMIT ?`, userID, limit).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.ConversationSummary, 0, len(rows))
+	keys := make([][]any, 0, len(rows))
+	for _, rw := range rows {
+		out = append(out, domain.ConversationSummary{
+			Conversation: domain.Conversation{
+				ID:        rw.ID,
+				Type:      domain.ConversationType(rw.Type),
+				LastSeq:   rw.LastSeq,
+				UpdatedAt: rw.UpdatedAt,
+			},
+			ReadSeq:        rw.ReadSeq,
+			Unread:         rw.Unread,
+			UnreadMentions: rw.UnreadMentions,
+		})
+		if rw.LastSeq > 0 {
+			keys = append(keys, []any{rw.ID, rw.LastSeq})
+		}
+	}
+	if len(keys) == 0 {
+		return out, nil
+	}
+
+	var last []MessageModel
+	if err := r.db.WithContext(ctx).Where("(conversation_id, seq) IN ?", keys).Find(&last).Error; err != nil {
+		return nil, err
+	}
+	byConv := make(map[string]*domain.Message, len(last))
+	for i := range last {
+		byConv[last[i].ConversationID] = toMessage(&last[i])
+	}
+	for i := range out {
+		out[i].LastMessage = byConv[out[i].ID]
+	}
+	return out, nil
+}
+
+func (r *PostgresMessageRepository) Append(ctx context.Context, m *domain.Message) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var seq uint64
+		res := tx.Raw(`UPDATE conversations SET last_seq = last_seq + 1, updated_at = ? WHERE id = ? RETURNING last_seq`,
+			now, m.ConversationID).Scan(&seq)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return domain.ErrConversationNotFound
+		}
+		mm := &MessageModel{
+			ConversationID: m.ConversationID,
+			Seq:            seq,
+			SenderID:       m.SenderID,
+			Type:           m.Type,
+			Content:        m.Content,
+			CreatedAt:      now,
+			ReplyToID:      nullableID(m.ReplyToID),
+			ThreadRootID:   nullableID(m.ThreadRootID),
+		}
+		if len(m.Mentions) > 0 {
+			data, err := json.Marshal(m.Mentions)
+			if err != nil {
+				return err
+			}
+			mm.Mentions = string(data)
+		}
+		if err := tx.Create(mm).Error; err != nil {
+			return err
+		}
+		if len(m.MentionedIDs) > 0 {
+			rows := make([]MentionModel, 0, len(m.MentionedIDs))
+			for _, uid := range m.MentionedIDs {
+				rows = append(rows, MentionModel{MessageID: mm.ID, UserID: uid, ConversationID: m.ConversationID, Seq: seq})
+			}
+			if err := tx.Create(&rows).Error; err != nil {
+				return err
+			}
+		}
+		if m.ThreadRootID != 0 {
+			err := tx.Model(&MessageModel{}).
+				Where("id = ?", m.ThreadRootID).
+				Updates(map[string]any{
+					"thread_reply_count":   gorm.Expr("thread_reply_count + 1"),
+					"thread_last_reply_at": now,
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		if refs := domain.MediaRefs(m.Type, m.Content); len(refs) > 0 {
+			rows := make([]MediaRefModel, 0, len(refs))
+			for _, ref := range refs {
+				rows = append(rows, MediaRefModel{MessageID: mm.ID, MediaID: ref, ConversationID: m.ConversationID})
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+				return err
+			}
+		}
+		m.ID = mm.ID
+		m.Seq = mm.Seq
+		m.CreatedAt = mm.CreatedAt
+		return nil
+	})
+}
+
+func (r *PostgresMessageRepository) SharesMedia(ctx context.Context, userID uint, mediaID string) (bool, error) {
+	var ok bool
+	err := r.db.WithContext(ctx).Raw(`SELECT EXISTS (
+		SELECT 1 FROM message_media mm
+		JOIN conversation_members cm ON cm.conversation_id = mm.conversation_id AND cm.user_id = ?
+		JOIN messages m ON m.id = mm.message_id AND m.recalled_at IS NULL
+		WHERE mm.media_id = ?)`, userID, mediaID).Scan(&ok).Error
+	return ok, err
+}
+
+func (r *PostgresMessageRepository) ListMessages(ctx context.Context, conversationID string, viewerID uint, beforeSeq uint64, limit int) ([]domain.Message, error) {
+	q := r.db.WithContext(ctx).
+		Where("conversation_id = ?", conversationID).
+		Where("thread_root_id IS NULL").
+		Where(notDeletedFor, viewerID)
+	if beforeSeq > 0 {
+		q = q.Where("seq < ?", beforeSeq)
+	}
+	var ms []MessageModel
+	if err := q.Order("seq DESC").Limit(limit).Find(&ms).Error; err != nil {
+		return nil, err
+	}
+	out := make([]domain.Message, 0, len(ms))
+	for i := len(ms) - 1; i >= 0; i-- {
+		out = append(out, *toMessage(&ms[i]))
+	}
+	return out, nil
+}
+
+func (r *PostgresMessageRepository) ListThread(ctx context.Context, rootID uint64, viewerID uint, afterSeq uint64, limit int) ([]domain.Message, error) {
+	var ms []MessageModel
+	err := r.db.WithContext(ctx).
+		Where("thread_root_id = ? AND seq > ?", rootID, afterSeq).
+		Where(notDeletedFor, viewerID).
+		Order("seq ASC").
+		Limit(limit).
+		Find(&ms).Error
+	if err != nil {
+		return nil, err
+	}
+	out := make([]domain.Message, 0, len(ms))
+	for i := range ms {
+		out = append(out, *toMessage(&ms[i]))
+	}
+	return out, nil
+}
+
+func (r *PostgresMessageRepository) GetMessage(ctx context.Context, messageID uint64) (*domain.Message, error) {
+	var m MessageModel
+	tx := r.db.WithContext(ctx).Where("id = ?", messageID).Limit(1).Find(&m)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return nil, domain.ErrMessageNotFound
+	}
+	return toMessage(&m), nil
+}
+
+func (r *PostgresMessageRepository) Recall(ctx context.Context, messageID uint64, at time.Time) error {
+	return r.updateLive(ctx, messageID, map[string]any{"recalled_at": at, "content": ""})
+}
+
+func (r *PostgresMessageRepository) Edit(ctx context.Context, messageID uint64, content string, at time.Time) error {
+	return r.updateLive(ctx, messageID, map[string]any{"edited_at": at, "content": content})
+}
+
+// updateLive 只更新未撤回的消息
+func (r *PostgresMessageRepository) updateLive(ctx context.Context, messageID uint64, updates map[string]any) error {
+	tx := r.db.WithContext(ctx).
+		Model(&MessageModel{}).
+		Where("id = ? AND recalled_at IS NULL", messageID).
+		Updates(updates)
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return domain.ErrMessageNotFound
+	}
+	return nil
+}
+
+func (r *PostgresMessageRepository) DeleteForUser(ctx context.Context, userID uint, messageID uint64) error {
+	m := &DeletionModel{UserID: userID, MessageID: messageID, CreatedAt: time.Now()}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(m).Error
+}
+
+func toMember(m *MemberModel) *domain.Member {
+	return &domain.Member{
+		ConversationID: m.ConversationID,
+		UserID:         m.UserID,
+		ReadSeq:        m.ReadSeq,
+		JoinedAt:       m.JoinedAt,
+	}
+}
+
+func toMessage(m *MessageModel) *domain.Message {
+	out := &domain.Message{
+		ID:             m.ID,
+		ConversationID: m.ConversationID,
+		Seq:            m.Seq,
+		SenderID:       m.SenderID,
+		Type:           m.Type,
+		Content:        m.Content,
+		CreatedAt:      m.CreatedAt,
+	}
+	if m.EditedAt != nil {
+		out.EditedAt = *m.EditedAt
+	}
+	if m.RecalledAt != nil {
+		out.RecalledAt = *m.RecalledAt
+	}
+	if m.ReplyToID != nil {
+		out.ReplyToID = *m.ReplyToID
+	}
+	if m.ThreadRootID != nil {
+		out.ThreadRootID = *m.ThreadRootID
+	}
+	out.ThreadReplyCount = m.ThreadReplyCount
+	if m.ThreadLastReplyAt != nil {
+		out.ThreadLastReplyAt = *m.ThreadLastReplyAt
+	}
+	if m.Mentions != "" {
+		_ = json.Unmarshal([]byte(m.Mentions), &out.Mentions)
+	}
+	return out
+}
+
+func nullableID(id uint64) *uint64 {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
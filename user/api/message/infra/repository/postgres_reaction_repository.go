@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"wsim/user/api/message/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReactionModel 一个用户对一条消息的一个表情
+type ReactionModel struct {
+	MessageID uint64    `gorm:"primaryKey;autoIncrement:false"`
+	Emoji     string    `gorm:"type:varchar(32);primaryKey"`
+	UserID    uint      `gorm:"primaryKey;autoIncrement:false"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+func (ReactionModel) TableName() string { return "message_reactions" }
+
+type PostgresReactionRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresReactionRepository(db *gorm.DB) (*PostgresReactionRepository, error) {
+	if err := db.AutoMigrate(&ReactionModel{}); err != nil {
+		return nil, err
+	}
+	return &PostgresReactionRepository{db: db}, nil
+}
+
+func (r *PostgresReactionRepository) AddReaction(ctx context.Context, messageID uint64, userID uint, emoji string) (bool, error) {
+	m := &ReactionModel{MessageID: messageID, Emoji: emoji, UserID: userID, CreatedAt: time.Now()}
+	tx := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(m)
+	return tx.RowsAffected > 0, tx.Error
+}
+
+func (r *PostgresReactionRepository) RemoveReaction(ctx context.Context, messageID uint64, userID uint, emoji string) (bool, error) {
+	tx := r.db.WithContext(ctx).
+		Where("message_id = ? AND user_id = ? AND emoji = ?", messageID, userID, emoji).
+		Delete(&ReactionModel{})
+	return tx.RowsAffected > 0, tx.Error
+}
+
+func (r *PostgresReactionRepository) CountReaction(ctx context.Context, messageID uint64, emoji string) (int, error) {
+	var n int64
+	err := r.db.WithContext(ctx).
+		Model(&ReactionModel{}).
+		Where("message_id = ? AND emoji = ?", messageID, emoji).
+		Count(&n).Error
+	return int(n), err
+}
+
+func (r *PostgresReactionRepository) ListReactions(ctx context.Context, messageIDs []uint64) (map[uint64][]domain.Reaction, error) {
+	out := make(map[uint64][]domain.Reaction, len(messageIDs))
+	if len(messageIDs) == 0 {
+		return out, nil
+	}
+	var ms []ReactionModel
+	err := r.db.WithContext(ctx).
+		Where("message_id IN ?", messageIDs).
+		Order("created_at ASC").
+		Find(&ms).Error
+	if err != nil {
+		return nil, err
+	}
+	// 按消息、表情聚合；表情顺序取第一次被使用的时间
+	index := make(map[uint64]map[string]int)
+	for _, m := range ms {
+		if index[m.MessageID] == nil {
+			index[m.MessageID] = make(map[string]int)
+		}
+		i, ok := index[m.MessageID][m.Emoji]
+		if !ok {
+			i = len(out[m.MessageID])
+			index[m.MessageID][m.Emoji] = i
+			out[m.MessageID] = append(out[m.MessageID], domain.Reaction{Emoji: m.Emoji})
+		}
+		rc := &out[m.MessageID][i]
+		rc.Count++
+		rc.UserIDs = append(rc.UserIDs, m.UserID)
+	}
+	return out, nil
+}
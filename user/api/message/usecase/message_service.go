@@ -0,0 +1,493 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"wsim/user/api/message/domain"
+)
+
+var (
+	ErrBadRequest = errors.New("bad request")
+	// ErrInvalidEmoji 表情不合法
+	ErrInvalidEmoji = errors.New("invalid emoji")
+	// ErrForbidden 无权操作该消息
+	ErrForbidden = errors.New("forbidden")
+	// ErrRecallExpired 超过撤回时间窗口
+	ErrRecallExpired = errors.New("recall window expired")
+)
+
+const (
+	// 单条消息内容上限（字节）；超过 NOTIFY 上限的网关间广播由 fanout 暂存表承载
+	maxContentLen = 16 * 1024
+	// 历史分页默认/最大条数
+	defaultPageSize = 50
+	maxPageSize     = 200
+	// 会话列表上限
+	maxConversations = 200
+	// 单条消息的 @ 实体上限
+	maxMentions = 50
+)
+
+// MediaAccess 判断用户能否引用某个媒体（上传者，或已在其参与的会话中出现过，可转发）
+type MediaAccess interface {
+	CanAccess(ctx context.Context, userID uint, mediaID string) (bool, error)
+}
+
+// Presence 批量查询哪些用户在线，用于展开 @online
+type Presence interface {
+	Online(ctx context.Context, userIDs []uint) (map[uint]bool, error)
+}
+
+type MessageService struct {
+	convs     domain.ConversationRepository
+	messages  domain.MessageRepository
+	reactions domain.ReactionRepository
+	media     MediaAccess
+	presence  Presence
+	// RecallWindow 发送后多久内允许撤回，环境变量 MESSAGE_RECALL_WINDOW 覆盖（默认 2m）
+	RecallWindow time.Duration
+	now          func() time.Time
+}
+
+func NewMessageService(convs domain.ConversationRepository, messages domain.MessageRepository, reactions domain.ReactionRepository, media MediaAccess, presence Presence) *MessageService {
+	window := 2 * time.Minute
+	if v := os.Getenv("MESSAGE_RECALL_WINDOW"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			window = d
+		}
+	}
+	return &MessageService{
+		convs:        convs,
+		messages:     messages,
+		reactions:    reactions,
+		media:        media,
+		presence:     presence,
+		RecallWindow: window,
+		now:          time.Now,
+	}
+}
+
+// SendDirect 存储一条单聊消息，会话不存在时自动创建
+func (s *MessageService) SendDirect(ctx context.Context, senderID, receiverID uint, d domain.Draft) (*domain.Message, error) {
+	if senderID == 0 || receiverID == 0 || senderID == receiverID {
+		return nil, ErrBadRequest
+	}
+	if len(d.Content) == 0 || len(d.Content) > maxContentLen || !utf8.ValidString(d.Content) {
+		return nil, ErrBadRequest
+	}
+	if !domain.IsContentType(d.Type) {
+		return nil, ErrBadRequest
+	}
+	if domain.IsRichType(d.Type) {
+		content, err := domain.NormalizePayload(d.Type, d.Content)
+		if err != nil {
+			return nil, ErrBadRequest
+		}
+		d.Content = content
+		for _, ref := range domain.MediaRefs(d.Type, d.Content) {
+			ok, err := s.media.CanAccess(ctx, senderID, ref)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, ErrForbidden
+			}
+		}
+	}
+	convID := domain.DirectConversationID(senderID, receiverID)
+	if err := s.convs.EnsureDirect(ctx, convID, senderID, receiverID); err != nil {
+		return nil, err
+	}
+	m := &domain.Message{
+		ConversationID: convID,
+		SenderID:       senderID,
+		Type:           d.Type,
+		Content:        d.Content,
+	}
+	if err := s.resolveReply(ctx, m, d); err != nil {
+		return nil, err
+	}
+	if err := s.resolveMentions(ctx, m, d.Mentions); err != nil {
+		return nil, err
+	}
+	if err := s.messages.Append(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// RecordCall 把通话记录写入会话历史，发送方记为主叫；conversationID 为空时写入主叫与 peerID 的单聊
+func (s *MessageService) RecordCall(ctx context.Context, conversationID string, callerID, peerID uint, content string) (*domain.Message, error) {
+	if conversationID == "" {
+		if callerID == 0 || peerID == 0 || callerID == peerID {
+			return nil, ErrBadRequest
+		}
+		conversationID = domain.DirectConversationID(callerID, peerID)
+		if err := s.convs.EnsureDirect(ctx, conversationID, callerID, peerID); err != nil {
+			return nil, err
+		}
+	}
+	m := &domain.Message{
+		ConversationID: conversationID,
+		SenderID:       callerID,
+		Type:           domain.TypeCall,
+		Content:        content,
+	}
+	if err := s.messages.Append(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// resolveReply 校验被回复的消息并确定话题根：回复话题内的消息时归入同一个话题
+func (s *MessageService) resolveReply(ctx context.Context, m *domain.Message, d domain.Draft) error {
+	if d.ReplyToID == 0 {
+		if d.InThread {
+			return ErrBadRequest
+		}
+		return nil
+	}
+	parent, err := s.messages.GetMessage(ctx, d.ReplyToID)
+	if err != nil {
+		return err
+	}
+	if parent.ConversationID != m.ConversationID || parent.Recalled() {
+		return ErrBadRequest
+	}
+	m.ReplyToID = parent.ID
+	if d.InThread {
+		m.ThreadRootID = parent.ID
+		if parent.ThreadRootID != 0 {
+			m.ThreadRootID = parent.ThreadRootID
+		}
+	}
+	return nil
+}
+
+// resolveMentions 校验 @ 实体并展开被提及成员：被 @ 的用户须是会话成员，
+// @online 展开为发送时在线的成员
+func (s *MessageService) resolveMentions(ctx context.Context, m *domain.Message, mentions []domain.Mention) error {
+	if len(mentions) == 0 {
+		return nil
+	}
+	if len(mentions) > maxMentions {
+		return ErrBadRequest
+	}
+	runes := 0
+	if m.Type == domain.TypeText {
+		runes = utf8.RuneCountInString(m.Content)
+	}
+	members, err := s.convs.ListMembers(ctx, m.ConversationID)
+	if err != nil {
+		return err
+	}
+	isMember := make(map[uint]bool, len(members))
+	var others []uint
+	for _, mb := range members {
+		isMember[mb.UserID] = true
+		if mb.UserID != m.SenderID {
+			others = append(others, mb.UserID)
+		}
+	}
+	if !isMember[m.SenderID] {
+		return domain.ErrNotMember
+	}
+
+	mentioned := make(map[uint]bool)
+	for _, mt := range mentions {
+		if mt.Offset < 0 || mt.Length < 0 || mt.Offset+mt.Length > runes {
+			return ErrBadRequest
+		}
+		switch mt.Type {
+		case domain.MentionUser:
+			if !isMember[mt.UserID] || mt.UserID == m.SenderID {
+				return ErrBadRequest
+			}
+			mentioned[mt.UserID] = true
+		case domain.MentionOnline:
+			if mt.UserID != 0 {
+				return ErrBadRequest
+			}
+			if s.presence == nil {
+				continue
+			}
+			online, err := s.presence.Online(ctx, others)
+			if err != nil {
+				return err
+			}
+			for id := range online {
+				mentioned[id] = true
+			}
+		default:
+			return ErrBadRequest
+		}
+	}
+	m.Mentions = mentions
+	for _, id := range others {
+		if mentioned[id] {
+			m.MentionedIDs = append(m.MentionedIDs, id)
+		}
+	}
+	return nil
+}
+
+// ThreadRoot 取话题根消息（带回复数汇总）
+func (s *MessageService) ThreadRoot(ctx context.Context, rootID uint64) (*domain.Message, error) {
+	return s.messages.GetMessage(ctx, rootID)
+}
+
+// Thread 拉取话题：根消息及其后按序号排列的回复
+func (s *MessageService) Thread(ctx context.Context, userID uint, conversationID string, rootID, afterSeq uint64, limit int) (*domain.Message, []domain.Message, error) {
+	if _, err := s.convs.GetMember(ctx, conversationID, userID); err != nil {
+		return nil, nil, err
+	}
+	root, err := s.messages.GetMessage(ctx, rootID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if root.ConversationID != conversationID || root.ThreadRootID != 0 {
+		return nil, nil, domain.ErrMessageNotFound
+	}
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	replies, err := s.messages.ListThread(ctx, rootID, userID, afterSeq, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	all := append([]domain.Message{*root}, replies...)
+	if err := s.fillReactions(ctx, all); err != nil {
+		return nil, nil, err
+	}
+	return &all[0], all[1:], nil
+}
+
+// Members 会话成员
+func (s *MessageService) Members(ctx context.Context, conversationID string) ([]domain.Member, error) {
+	return s.convs.ListMembers(ctx, conversationID)
+}
+
+// MarkRead 推进 userID 在会话中的已读游标。
+// 返回推进后的游标、是否有变化，以及会话全部成员（用于转发已读回执）。
+func (s *MessageService) MarkRead(ctx context.Context, userID uint, conversationID string, seq uint64) (uint64, bool, []domain.Member, error) {
+	if conversationID == "" || seq == 0 {
+		return 0, false, nil, ErrBadRequest
+	}
+	readSeq, advanced, err := s.convs.AdvanceReadSeq(ctx, conversationID, userID, seq)
+	if err != nil {
+		return 0, false, nil, err
+	}
+	if !advanced {
+		return readSeq, false, nil, nil
+	}
+	members, err := s.convs.ListMembers(ctx, conversationID)
+	if err != nil {
+		return 0, false, nil, err
+	}
+	return readSeq, true, members, nil
+}
+
+// History 分页拉取会话消息，同时返回各成员的已读游标
+func (s *MessageService) History(ctx context.Context, userID uint, conversationID string, beforeSeq uint64, limit int) ([]domain.Message, []domain.Member, error) {
+	if _, err := s.convs.GetMember(ctx, conversationID, userID); err != nil {
+		return nil, nil, err
+	}
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	msgs, err := s.messages.ListMessages(ctx, conversationID, userID, beforeSeq, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.fillReactions(ctx, msgs); err != nil {
+		return nil, nil, err
+	}
+	members, err := s.convs.ListMembers(ctx, conversationID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return msgs, members, nil
+}
+
+// Conversations 会话列表，按最近活跃倒序，附带未读数
+func (s *MessageService) Conversations(ctx context.Context, userID uint) ([]domain.ConversationSummary, error) {
+	return s.convs.ListConversations(ctx, userID, maxConversations)
+}
+
+// IsMember userID 是否在会话中
+func (s *MessageService) IsMember(ctx context.Context, conversationID string, userID uint) (bool, error) {
+	_, err := s.convs.GetMember(ctx, conversationID, userID)
+	if errors.Is(err, domain.ErrNotMember) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// UnreadTotal 全部会话的未读总数，用作推送角标
+func (s *MessageService) UnreadTotal(ctx context.Context, userID uint) (int64, error) {
+	cs, err := s.convs.ListConversations(ctx, userID, maxConversations)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, c := range cs {
+		total += c.Unread
+	}
+	return total, nil
+}
+
+// Recall 撤回自己发送的消息；返回撤回后的消息与会话成员（用于转发）
+func (s *MessageService) Recall(ctx context.Context, userID uint, messageID uint64) (*domain.Message, []domain.Member, error) {
+	m, err := s.ownMessage(ctx, userID, messageID)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := s.now()
+	if now.Sub(m.CreatedAt) > s.RecallWindow {
+		return nil, nil, ErrRecallExpired
+	}
+	if err := s.messages.Recall(ctx, messageID, now); err != nil {
+		return nil, nil, err
+	}
+	m.Content = ""
+	m.RecalledAt = now
+	members, err := s.convs.ListMembers(ctx, m.ConversationID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, members, nil
+}
+
+// Edit 修改自己发送的消息；返回修改后的消息与会话成员（用于转发）
+func (s *MessageService) Edit(ctx context.Context, userID uint, messageID uint64, content string) (*domain.Message, []domain.Member, error) {
+	if len(content) == 0 || len(content) > maxContentLen || !utf8.ValidString(content) {
+		return nil, nil, ErrBadRequest
+	}
+	m, err := s.ownMessage(ctx, userID, messageID)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 只有文本可以编辑，富消息需撤回后重发
+	if m.Type != domain.TypeText {
+		return nil, nil, ErrBadRequest
+	}
+	now := s.now()
+	if err := s.messages.Edit(ctx, messageID, content, now); err != nil {
+		return nil, nil, err
+	}
+	m.Content = content
+	m.EditedAt = now
+	members, err := s.convs.ListMembers(ctx, m.ConversationID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, members, nil
+}
+
+// DeleteForMe 仅对自己隐藏一条消息，任何成员都可以操作
+func (s *MessageService) DeleteForMe(ctx context.Context, userID uint, messageID uint64) (*domain.Message, error) {
+	m, err := s.messages.GetMessage(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.convs.GetMember(ctx, m.ConversationID, userID); err != nil {
+		return nil, err
+	}
+	if err := s.messages.DeleteForUser(ctx, userID, messageID); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ownMessage 取 userID 自己发送且未撤回的消息
+func (s *MessageService) ownMessage(ctx context.Context, userID uint, messageID uint64) (*domain.Message, error) {
+	m, err := s.messages.GetMessage(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if m.SenderID != userID {
+		return nil, ErrForbidden
+	}
+	if m.Recalled() {
+		return nil, domain.ErrMessageNotFound
+	}
+	return m, nil
+}
+
+// 表情最大长度（字节），组合表情（肤色、ZWJ 序列）也在范围内
+const maxEmojiLen = 32
+
+// ReactionChange 一次表情回应的变化，用于推送
+type ReactionChange struct {
+	Message *domain.Message
+	Members []domain.Member
+	// Changed 为 false 表示重复添加/删除，无需推送
+	Changed bool
+	Count   int
+}
+
+// React 添加或取消表情回应；任何会话成员都可以对未撤回的消息回应
+func (s *MessageService) React(ctx context.Context, userID uint, messageID uint64, emoji string, add bool) (*ReactionChange, error) {
+	if emoji == "" || len(emoji) > maxEmojiLen || !utf8.ValidString(emoji) || strings.ContainsAny(emoji, " \t\r\n") {
+		return nil, ErrInvalidEmoji
+	}
+	m, err := s.messages.GetMessage(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if m.Recalled() {
+		return nil, domain.ErrMessageNotFound
+	}
+	if _, err := s.convs.GetMember(ctx, m.ConversationID, userID); err != nil {
+		return nil, err
+	}
+	var changed bool
+	if add {
+		changed, err = s.reactions.AddReaction(ctx, messageID, userID, emoji)
+	} else {
+		changed, err = s.reactions.RemoveReaction(ctx, messageID, userID, emoji)
+	}
+	if err != nil {
+		return nil, err
+	}
+	res := &ReactionChange{Message: m, Changed: changed}
+	if !changed {
+		return res, nil
+	}
+	if res.Count, err = s.reactions.CountReaction(ctx, messageID, emoji); err != nil {
+		return nil, err
+	}
+	if res.Members, err = s.convs.ListMembers(ctx, m.ConversationID); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *MessageService) fillReactions(ctx context.Context, msgs []domain.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	ids := make([]uint64, 0, len(msgs))
+	for _, m := range msgs {
+		ids = append(ids, m.ID)
+	}
+	byMsg, err := s.reactions.ListReactions(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range msgs {
+		msgs[i].Reactions = byMsg[msgs[i].ID]
+	}
+	return nil
+}
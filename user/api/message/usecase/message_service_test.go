@@ -0,0 +1,695 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"wsim/user/api/message/domain"
+)
+
+// memConvs 只实现测试用到的方法，其余调用会 panic
+type memConvs struct {
+	domain.ConversationRepository
+	members map[string][]domain.Member
+}
+
+func (m *memConvs) GetMember(_ context.Context, convID string, userID uint) (*domain.Member, error) {
+	for _, mb := range m.members[convID] {
+		if mb.UserID == userID {
+			return &mb, nil
+		}
+	}
+	return nil, domain.ErrNotMember
+}
+
+func (m *memConvs) ListMembers(_ context.Context, convID string) ([]domain.Member, error) {
+	return m.members[convID], nil
+}
+
+type memMessages struct {
+	domain.MessageRepository
+	msgs    map[uint64]*domain.Message
+	deleted map[uint64][]uint
+}
+
+func (m *memMessages) GetMessage(_ context.Context, id uint64) (*domain.Message, error) {
+	msg, ok := m.msgs[id]
+	if !ok {
+		return nil, domain.ErrMessageNotFound
+	}
+	cp := *msg
+	return &cp, nil
+}
+
+func (m *memMessages) Recall(_ context.Context, id uint64, at time.Time) error {
+	msg := m.msgs[id]
+	if msg.Recalled() {
+		return domain.ErrMessageNotFound
+	}
+	msg.Content, msg.RecalledAt = "", at
+	return nil
+}
+
+func (m *memMessages) Edit(_ context.Context, id uint64, content string, at time.Time) error {
+	msg := m.msgs[id]
+	if msg.Recalled() {
+		return domain.ErrMessageNotFound
+	}
+	msg.Content, msg.EditedAt = content, at
+	return nil
+}
+
+func (m *memMessages) DeleteForUser(_ context.Context, userID uint, id uint64) error {
+	m.deleted[id] = append(m.deleted[id], userID)
+	return nil
+}
+
+func newOpTestService(clock *time.Time) (*MessageService, *memMessages) {
+	conv := domain.DirectConversationID(1, 2)
+	convs := &memConvs{members: map[string][]domain.Member{
+		conv: {{ConversationID: conv, UserID: 1}, {ConversationID: conv, UserID: 2}},
+	}}
+	msgs := &memMessages{deleted: make(map[uint64][]uint), msgs: map[uint64]*domain.Message{
+		1: {ID: 1, ConversationID: conv, SenderID: 1, Type: domain.TypeText, Content: "hello", CreatedAt: *clock},
+		2: {ID: 2, ConversationID: conv, SenderID: 1, Type: domain.TypeImage, Content: "{}", CreatedAt: *clock},
+	}}
+	s := &MessageService{convs: convs, messages: msgs, RecallWindow: 2 * time.Minute, now: func() time.Time { return *clock }}
+	return s, msgs
+}
+
+func TestRecall(t *testing.T) {
+	ctx := context.Background()
+	clock := time.Now()
+	s, msgs := newOpTestService(&clock)
+
+	if _, _, err := s.Recall(ctx, 2, 1); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("other user: got %v", err)
+	}
+	clock = clock.Add(s.RecallWindow + time.Second)
+	if _, _, err := s.Recall(ctx, 1, 1); !errors.Is(err, ErrRecallExpired) {
+		t.Fatalf("expired: got %v", err)
+	}
+	clock = clock.Add(-2 * time.Second)
+	m, members, err := s.Recall(ctx, 1, 1)
+	if err != nil || m.Content != "" || !m.Recalled() || len(members) != 2 {
+		t.Fatalf("recall: %+v %v", m, err)
+	}
+	if msgs.msgs[1].Content != "" {
+		t.Fatal("recall not persisted")
+	}
+	// 撤回后不能再撤回或编辑
+	if _, _, err := s.Recall(ctx, 1, 1); !errors.Is(err, domain.ErrMessageNotFound) {
+		t.Fatalf("recall twice: got %v", err)
+	}
+	if _, _, err := s.Edit(ctx, 1, 1, "again"); !errors.Is(err, domain.ErrMessageNotFound) {
+		t.Fatalf("edit recalled: got %v", err)
+	}
+}
+
+func TestEdit(t *testing.T) {
+	ctx := context.Background()
+	clock := time.Now()
+	s, msgs := newOpTestService(&clock)
+
+	if _, _, err := s.Edit(ctx, 2, 1, "hijack"); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("other user: got %v", err)
+	}
+	if _, _, err := s.Edit(ctx, 1, 2, "caption"); !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("rich message: got %v", err)
+	}
+	for _, bad := range []string{"", strings.Repeat("a", maxContentLen+1), "\xff"} {
+		if _, _, err := s.Edit(ctx, 1, 1, bad); !errors.Is(err, ErrBadRequest) {
+			t.Fatalf("content len %d: got %v", len(bad), err)
+		}
+	}
+	// 编辑不受撤回窗口限制，上限与发送一致
+	clock = clock.Add(time.Hour)
+	long := strings.Repeat("a", maxContentLen)
+	m, _, err := s.Edit(ctx, 1, 1, long)
+	if err != nil || m.EditedAt != clock || msgs.msgs[1].Content != long {
+		t.Fatalf("edit: %v", err)
+	}
+}
+
+func TestDeleteForMe(t *testing.T) {
+	ctx := context.Background()
+	clock := time.Now()
+	s, msgs := newOpTestService(&clock)
+
+	if _, err := s.DeleteForMe(ctx, 3, 1); !errors.Is(err, domain.ErrNotMember) {
+		t.Fatalf("non-member: got %v", err)
+	}
+	// 接收方也可以对自己隐藏
+	if _, err := s.DeleteForMe(ctx, 2, 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs.deleted[1]) != 1 || msgs.deleted[1][0] != 2 || msgs.msgs[1].Content != "hello" {
+		t.Fatalf("delete for me: %v", msgs.deleted)
+	}
+}
+
+// memStore 按 Postgres 仓储的语义实现会话与消息存储，用于走完发送、已读与统计的流程
+type memStore struct {
+	domain.MessageRepository
+	convs     map[string]*domain.Conversation
+	members   map[string][]*domain.Member
+	msgs      []*domain.Message
+	reactions []reactionKey
+}
+
+func newMemStore() *memStore {
+	return &memStore{convs: make(map[string]*domain.Conversation), members: make(map[string][]*domain.Member)}
+}
+
+func (m *memStore) EnsureDirect(_ context.Context, convID string, a, b uint) error {
+	if _, ok := m.convs[convID]; ok {
+		return nil
+	}
+	m.convs[convID] = &domain.Conversation{ID: convID, Type: domain.ConversationDirect}
+	m.members[convID] = []*domain.Member{{ConversationID: convID, UserID: a}, {ConversationID: convID, UserID: b}}
+	return nil
+}
+
+func (m *memStore) GetMember(_ context.Context, convID string, userID uint) (*domain.Member, error) {
+	for _, mb := range m.members[convID] {
+		if mb.UserID == userID {
+			cp := *mb
+			return &cp, nil
+		}
+	}
+	return nil, domain.ErrNotMember
+}
+
+func (m *memStore) ListMembers(_ context.Context, convID string) ([]domain.Member, error) {
+	var out []domain.Member
+	for _, mb := range m.members[convID] {
+		out = append(out, *mb)
+	}
+	return out, nil
+}
+
+func (m *memStore) AdvanceReadSeq(_ context.Context, convID string, userID uint, seq uint64) (uint64, bool, error) {
+	for _, mb := range m.members[convID] {
+		if mb.UserID != userID {
+			continue
+		}
+		if last := m.convs[convID].LastSeq; seq > last {
+			seq = last
+		}
+		if seq <= mb.ReadSeq {
+			return mb.ReadSeq, false, nil
+		}
+		mb.ReadSeq = seq
+		return seq, true, nil
+	}
+	return 0, false, domain.ErrNotMember
+}
+
+func (m *memStore) ListConversations(_ context.Context, userID uint, limit int) ([]domain.ConversationSummary, error) {
+	var out []domain.ConversationSummary
+	for id, c := range m.convs {
+		mb, err := m.GetMember(context.Background(), id, userID)
+		if err != nil {
+			continue
+		}
+		s := domain.ConversationSummary{Conversation: *c, ReadSeq: mb.ReadSeq}
+		for _, msg := range m.msgs {
+			if msg.ConversationID != id || msg.Seq <= mb.ReadSeq || msg.Recalled() {
+				continue
+			}
+			if msg.SenderID != userID && msg.ThreadRootID == 0 {
+				s.Unread++
+			}
+			for _, uid := range msg.MentionedIDs {
+				if uid == userID {
+					s.UnreadMentions++
+				}
+			}
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UpdatedAt.After(out[j].UpdatedAt) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (m *memStore) Append(_ context.Context, msg *domain.Message) error {
+	c, ok := m.convs[msg.ConversationID]
+	if !ok {
+		return domain.ErrConversationNotFound
+	}
+	c.LastSeq++
+	c.UpdatedAt = time.Now()
+	msg.ID, msg.Seq, msg.CreatedAt = uint64(len(m.msgs)+1), c.LastSeq, c.UpdatedAt
+	if msg.ThreadRootID != 0 {
+		root := m.msgs[msg.ThreadRootID-1]
+		root.ThreadReplyCount++
+		root.ThreadLastReplyAt = msg.CreatedAt
+	}
+	cp := *msg
+	m.msgs = append(m.msgs, &cp)
+	return nil
+}
+
+func (m *memStore) GetMessage(_ context.Context, id uint64) (*domain.Message, error) {
+	if id == 0 || id > uint64(len(m.msgs)) {
+		return nil, domain.ErrMessageNotFound
+	}
+	cp := *m.msgs[id-1]
+	return &cp, nil
+}
+
+func (m *memStore) ListMessages(_ context.Context, convID string, _ uint, beforeSeq uint64, limit int) ([]domain.Message, error) {
+	var out []domain.Message
+	for i := len(m.msgs) - 1; i >= 0 && len(out) < limit; i-- {
+		msg := m.msgs[i]
+		if msg.ConversationID == convID && msg.ThreadRootID == 0 && (beforeSeq == 0 || msg.Seq < beforeSeq) {
+			out = append([]domain.Message{*msg}, out...)
+		}
+	}
+	return out, nil
+}
+
+func (m *memStore) ListThread(_ context.Context, rootID uint64, _ uint, afterSeq uint64, limit int) ([]domain.Message, error) {
+	var out []domain.Message
+	for _, msg := range m.msgs {
+		if msg.ThreadRootID == rootID && msg.Seq > afterSeq && len(out) < limit {
+			out = append(out, *msg)
+		}
+	}
+	return out, nil
+}
+
+func (m *memStore) Recall(_ context.Context, id uint64, at time.Time) error {
+	msg := m.msgs[id-1]
+	if msg.Recalled() {
+		return domain.ErrMessageNotFound
+	}
+	msg.Content, msg.RecalledAt = "", at
+	return nil
+}
+
+// reactionKey 一个用户在一条消息上的一个表情
+type reactionKey struct {
+	messageID uint64
+	userID    uint
+	emoji     string
+}
+
+func (m *memStore) AddReaction(_ context.Context, messageID uint64, userID uint, emoji string) (bool, error) {
+	k := reactionKey{messageID, userID, emoji}
+	for _, r := range m.reactions {
+		if r == k {
+			return false, nil
+		}
+	}
+	m.reactions = append(m.reactions, k)
+	return true, nil
+}
+
+func (m *memStore) RemoveReaction(_ context.Context, messageID uint64, userID uint, emoji string) (bool, error) {
+	k := reactionKey{messageID, userID, emoji}
+	for i, r := range m.reactions {
+		if r == k {
+			m.reactions = append(m.reactions[:i], m.reactions[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memStore) CountReaction(_ context.Context, messageID uint64, emoji string) (int, error) {
+	n := 0
+	for _, r := range m.reactions {
+		if r.messageID == messageID && r.emoji == emoji {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *memStore) ListReactions(_ context.Context, ids []uint64) (map[uint64][]domain.Reaction, error) {
+	out := make(map[uint64][]domain.Reaction)
+	for _, id := range ids {
+		for _, r := range m.reactions {
+			if r.messageID != id {
+				continue
+			}
+			rs := out[id]
+			i := 0
+			for i < len(rs) && rs[i].Emoji != r.emoji {
+				i++
+			}
+			if i == len(rs) {
+				rs = append(rs, domain.Reaction{Emoji: r.emoji})
+			}
+			rs[i].Count++
+			rs[i].UserIDs = append(rs[i].UserIDs, r.userID)
+			out[id] = rs
+		}
+	}
+	return out, nil
+}
+
+// onlineSet 在线用户集合
+type onlineSet map[uint]bool
+
+func (o onlineSet) Online(_ context.Context, ids []uint) (map[uint]bool, error) {
+	out := make(map[uint]bool)
+	for _, id := range ids {
+		if o[id] {
+			out[id] = true
+		}
+	}
+	return out, nil
+}
+
+func TestMentionValidation(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	s := NewMessageService(store, store, nil, nil, onlineSet{})
+	text := func(ms ...domain.Mention) domain.Draft {
+		return domain.Draft{Type: domain.TypeText, Content: "@bob hi", Mentions: ms}
+	}
+
+	bad := map[string]domain.Draft{
+		"non-member":      text(domain.Mention{Type: domain.MentionUser, UserID: 3}),
+		"self":            text(domain.Mention{Type: domain.MentionUser, UserID: 1}),
+		"past content":    text(domain.Mention{Type: domain.MentionUser, UserID: 2, Offset: 5, Length: 4}),
+		"negative offset": text(domain.Mention{Type: domain.MentionUser, UserID: 2, Offset: -1, Length: 1}),
+		"online with id":  text(domain.Mention{Type: domain.MentionOnline, UserID: 2}),
+		// @all 需要群聊与管理员角色，尚未支持
+		"all":            text(domain.Mention{Type: "all"}),
+		"too many":       text(make([]domain.Mention, maxMentions+1)...),
+		"offset on rich": {Type: domain.TypeLocation, Content: `{"v":1,"latitude":0,"longitude":0}`, Mentions: []domain.Mention{{Type: domain.MentionUser, UserID: 2, Length: 1}}},
+	}
+	for name, d := range bad {
+		if _, err := s.SendDirect(ctx, 1, 2, d); !errors.Is(err, ErrBadRequest) {
+			t.Errorf("%s: got %v", name, err)
+		}
+	}
+
+	m, err := s.SendDirect(ctx, 1, 2, text(domain.Mention{Type: domain.MentionUser, UserID: 2, Offset: 0, Length: 4}))
+	if err != nil || len(m.Mentions) != 1 || len(m.MentionedIDs) != 1 || m.MentionedIDs[0] != 2 {
+		t.Fatalf("mention: %+v %v", m, err)
+	}
+	// 富消息没有文本位置，只能整体提及
+	loc := domain.Draft{Type: domain.TypeLocation, Content: `{"v":1,"latitude":0,"longitude":0}`, Mentions: []domain.Mention{{Type: domain.MentionUser, UserID: 2}}}
+	if m, err := s.SendDirect(ctx, 1, 2, loc); err != nil || len(m.MentionedIDs) != 1 {
+		t.Fatalf("rich mention: %+v %v", m, err)
+	}
+}
+
+func TestMentionOnlineExpansion(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	online := onlineSet{1: true}
+	s := NewMessageService(store, store, nil, nil, online)
+	d := domain.Draft{Type: domain.TypeText, Content: "@online", Mentions: []domain.Mention{{Type: domain.MentionOnline, Length: 7}}}
+
+	// 只展开发送时在线的其他成员，不含发送方自己
+	m, err := s.SendDirect(ctx, 1, 2, d)
+	if err != nil || len(m.MentionedIDs) != 0 {
+		t.Fatalf("peer offline: %+v %v", m.MentionedIDs, err)
+	}
+	online[2] = true
+	m, err = s.SendDirect(ctx, 1, 2, d)
+	if err != nil || len(m.MentionedIDs) != 1 || m.MentionedIDs[0] != 2 {
+		t.Fatalf("peer online: %+v %v", m.MentionedIDs, err)
+	}
+}
+
+func TestUnreadMentions(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	s := NewMessageService(store, store, nil, nil, onlineSet{})
+	conv := domain.DirectConversationID(1, 2)
+	at := domain.Draft{Type: domain.TypeText, Content: "@bob", Mentions: []domain.Mention{{Type: domain.MentionUser, UserID: 2, Length: 4}}}
+	plain := domain.Draft{Type: domain.TypeText, Content: "hi"}
+
+	for _, d := range []domain.Draft{at, plain, at} {
+		if _, err := s.SendDirect(ctx, 1, 2, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	unread := func(userID uint) (int64, int64) {
+		cs, err := s.Conversations(ctx, userID)
+		if err != nil || len(cs) != 1 {
+			t.Fatalf("conversations: %+v %v", cs, err)
+		}
+		return cs[0].Unread, cs[0].UnreadMentions
+	}
+	if n, mentions := unread(2); n != 3 || mentions != 2 {
+		t.Fatalf("receiver: unread %d, mentions %d", n, mentions)
+	}
+	if n, mentions := unread(1); n != 0 || mentions != 0 {
+		t.Fatalf("sender: unread %d, mentions %d", n, mentions)
+	}
+	// 读到第 2 条后只剩最后一条 @
+	if _, _, _, err := s.MarkRead(ctx, 2, conv, 2); err != nil {
+		t.Fatal(err)
+	}
+	if n, mentions := unread(2); n != 1 || mentions != 1 {
+		t.Fatalf("after read: unread %d, mentions %d", n, mentions)
+	}
+}
+
+func TestReadCursor(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	s := NewMessageService(store, store, store, nil, nil)
+	conv := domain.DirectConversationID(1, 2)
+	for i := 0; i < 3; i++ {
+		if _, err := s.SendDirect(ctx, 1, 2, domain.Draft{Type: domain.TypeText, Content: "hi"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, _, _, err := s.MarkRead(ctx, 2, conv, 0); !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("zero seq: got %v", err)
+	}
+	if _, _, _, err := s.MarkRead(ctx, 3, conv, 1); !errors.Is(err, domain.ErrNotMember) {
+		t.Fatalf("non-member: got %v", err)
+	}
+	// 推进时返回全部成员用于转发回执
+	seq, advanced, members, err := s.MarkRead(ctx, 2, conv, 2)
+	if err != nil || seq != 2 || !advanced || len(members) != 2 {
+		t.Fatalf("advance: %d %v %+v %v", seq, advanced, members, err)
+	}
+	// 只进不退，没有变化时不转发
+	seq, advanced, members, err = s.MarkRead(ctx, 2, conv, 1)
+	if err != nil || seq != 2 || advanced || members != nil {
+		t.Fatalf("backwards: %d %v %+v %v", seq, advanced, members, err)
+	}
+	// 不超过会话最新序号
+	if seq, _, _, err = s.MarkRead(ctx, 2, conv, 99); err != nil || seq != 3 {
+		t.Fatalf("past last: %d %v", seq, err)
+	}
+
+	// 历史附带各成员的已读游标
+	msgs, members, err := s.History(ctx, 1, conv, 0, 0)
+	if err != nil || len(msgs) != 3 {
+		t.Fatalf("history: %d %v", len(msgs), err)
+	}
+	read := make(map[uint]uint64)
+	for _, mb := range members {
+		read[mb.UserID] = mb.ReadSeq
+	}
+	if read[1] != 0 || read[2] != 3 {
+		t.Fatalf("cursors: %v", read)
+	}
+	if _, _, err := s.History(ctx, 3, conv, 0, 0); !errors.Is(err, domain.ErrNotMember) {
+		t.Fatalf("non-member history: got %v", err)
+	}
+}
+
+func TestUnreadCounts(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	s := NewMessageService(store, store, store, nil, nil)
+	send := func(from, to uint) *domain.Message {
+		t.Helper()
+		m, err := s.SendDirect(ctx, from, to, domain.Draft{Type: domain.TypeText, Content: "hi"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	send(1, 2)
+	send(2, 1)
+	send(1, 2)
+	send(3, 2)
+	unread := func(userID uint) map[string]int64 {
+		t.Helper()
+		cs, err := s.Conversations(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := make(map[string]int64)
+		for _, c := range cs {
+			out[c.ID] = c.Unread
+		}
+		return out
+	}
+
+	// 自己发的不计未读
+	c12, c23 := domain.DirectConversationID(1, 2), domain.DirectConversationID(2, 3)
+	if got := unread(2); got[c12] != 2 || got[c23] != 1 {
+		t.Fatalf("user 2: %v", got)
+	}
+	if got := unread(1); got[c12] != 1 {
+		t.Fatalf("user 1: %v", got)
+	}
+	if total, err := s.UnreadTotal(ctx, 2); err != nil || total != 3 {
+		t.Fatalf("total: %d %v", total, err)
+	}
+	if _, _, _, err := s.MarkRead(ctx, 2, c12, 3); err != nil {
+		t.Fatal(err)
+	}
+	if got := unread(2); got[c12] != 0 || got[c23] != 1 {
+		t.Fatalf("after read: %v", got)
+	}
+	// 读完后的新消息重新计入
+	send(1, 2)
+	if total, err := s.UnreadTotal(ctx, 2); err != nil || total != 2 {
+		t.Fatalf("total after new message: %d %v", total, err)
+	}
+}
+
+func TestThreadHistory(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	s := NewMessageService(store, store, store, nil, nil)
+	conv := domain.DirectConversationID(1, 2)
+	send := func(from, to uint, d domain.Draft) *domain.Message {
+		t.Helper()
+		d.Type, d.Content = domain.TypeText, "hi"
+		m, err := s.SendDirect(ctx, from, to, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	root := send(1, 2, domain.Draft{})
+	r1 := send(2, 1, domain.Draft{ReplyToID: root.ID, InThread: true})
+	// 回复话题内的消息仍归入同一个话题
+	r2 := send(1, 2, domain.Draft{ReplyToID: r1.ID, InThread: true})
+	quote := send(2, 1, domain.Draft{ReplyToID: root.ID})
+	if r2.ThreadRootID != root.ID || r2.ReplyToID != r1.ID || quote.ThreadRootID != 0 {
+		t.Fatalf("thread roots: %+v %+v", r2, quote)
+	}
+	if _, err := s.SendDirect(ctx, 1, 2, domain.Draft{Type: domain.TypeText, Content: "hi", InThread: true}); !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("thread without parent: got %v", err)
+	}
+	if _, err := s.SendDirect(ctx, 1, 3, domain.Draft{Type: domain.TypeText, Content: "hi", ReplyToID: root.ID}); !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("reply across conversations: got %v", err)
+	}
+
+	// 话题回复不出现在会话顶层，根消息带汇总
+	msgs, _, err := s.History(ctx, 2, conv, 0, 0)
+	if err != nil || len(msgs) != 2 || msgs[0].ID != root.ID || msgs[1].ID != quote.ID {
+		t.Fatalf("history: %+v %v", msgs, err)
+	}
+	if msgs[0].ThreadReplyCount != 2 || !msgs[0].ThreadLastReplyAt.Equal(r2.CreatedAt) {
+		t.Fatalf("root summary: %+v", msgs[0])
+	}
+
+	got, replies, err := s.Thread(ctx, 2, conv, root.ID, 0, 0)
+	if err != nil || got.ID != root.ID || len(replies) != 2 || replies[0].ID != r1.ID || replies[1].ID != r2.ID {
+		t.Fatalf("thread: %+v %+v %v", got, replies, err)
+	}
+	// 按序号向后翻页
+	if _, replies, err = s.Thread(ctx, 2, conv, root.ID, r1.Seq, 1); err != nil || len(replies) != 1 || replies[0].ID != r2.ID {
+		t.Fatalf("thread page: %+v %v", replies, err)
+	}
+
+	if _, _, err := s.Thread(ctx, 3, conv, root.ID, 0, 0); !errors.Is(err, domain.ErrNotMember) {
+		t.Fatalf("non-member: got %v", err)
+	}
+	// 话题内回复不能作为根，也不能跨会话查询
+	other := send(1, 3, domain.Draft{})
+	if _, _, err := s.Thread(ctx, 2, conv, r1.ID, 0, 0); !errors.Is(err, domain.ErrMessageNotFound) {
+		t.Fatalf("reply as root: got %v", err)
+	}
+	if _, _, err := s.Thread(ctx, 1, conv, other.ID, 0, 0); !errors.Is(err, domain.ErrMessageNotFound) {
+		t.Fatalf("root in other conversation: got %v", err)
+	}
+}
+
+func TestReactions(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	s := NewMessageService(store, store, store, nil, nil)
+	conv := domain.DirectConversationID(1, 2)
+	var ids []uint64
+	for i := 0; i < 2; i++ {
+		m, err := s.SendDirect(ctx, 1, 2, domain.Draft{Type: domain.TypeText, Content: "hi"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, m.ID)
+	}
+	react := func(userID uint, id uint64, emoji string, add bool) *ReactionChange {
+		t.Helper()
+		res, err := s.React(ctx, userID, id, emoji, add)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	for _, bad := range []string{"", "a b", strings.Repeat("x", maxEmojiLen+1), "\xff"} {
+		if _, err := s.React(ctx, 1, ids[0], bad, true); !errors.Is(err, ErrInvalidEmoji) {
+			t.Fatalf("emoji %q: got %v", bad, err)
+		}
+	}
+	if _, err := s.React(ctx, 3, ids[0], "👍", true); !errors.Is(err, domain.ErrNotMember) {
+		t.Fatalf("non-member: got %v", err)
+	}
+
+	if res := react(2, ids[0], "👍", true); !res.Changed || res.Count != 1 || len(res.Members) != 2 {
+		t.Fatalf("add: %+v", res)
+	}
+	// 重复添加不推送
+	if res := react(2, ids[0], "👍", true); res.Changed {
+		t.Fatalf("duplicate add: %+v", res)
+	}
+	react(1, ids[0], "🎉", true)
+	if res := react(1, ids[0], "👍", true); res.Count != 2 {
+		t.Fatalf("second user: %+v", res)
+	}
+	react(1, ids[1], "👍", true)
+	if res := react(1, ids[1], "👍", false); !res.Changed || res.Count != 0 {
+		t.Fatalf("remove: %+v", res)
+	}
+	if res := react(1, ids[1], "👍", false); res.Changed {
+		t.Fatalf("duplicate remove: %+v", res)
+	}
+
+	// 历史按消息聚合，表情按首次出现排序
+	msgs, _, err := s.History(ctx, 2, conv, 0, 0)
+	if err != nil || len(msgs) != 2 {
+		t.Fatalf("history: %+v %v", msgs, err)
+	}
+	rs := msgs[0].Reactions
+	if len(rs) != 2 || rs[0].Emoji != "👍" || rs[0].Count != 2 || len(rs[0].UserIDs) != 2 || rs[1].Emoji != "🎉" || rs[1].Count != 1 {
+		t.Fatalf("aggregated: %+v", rs)
+	}
+	if len(msgs[1].Reactions) != 0 {
+		t.Fatalf("removed reaction still listed: %+v", msgs[1].Reactions)
+	}
+
+	// 撤回的消息不能再回应
+	if _, _, err := s.Recall(ctx, 1, ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.React(ctx, 2, ids[1], "👍", true); !errors.Is(err, domain.ErrMessageNotFound) {
+		t.Fatalf("recalled: got %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// State 在线状态
+type State string
+
+const (
+	StateOnline  State = "online"
+	StateAway    State = "away"
+	StateOffline State = "offline"
+)
+
+// Valid 客户端只能主动上报 online/away，offline 由断线决定
+func (s State) Valid() bool {
+	return s == StateOnline || s == StateAway
+}
+
+// Session 用户的一条在线连接（一个设备），按网关维度上报
+type Session struct {
+	ID        string
+	UserID    uint
+	GatewayID string
+	State     State
+	UpdatedAt time.Time
+}
+
+// Presence 聚合后的用户状态：任一设备 online 即 online；全部 away 则 away；无连接 offline
+type Presence struct {
+	UserID   uint
+	State    State
+	LastSeen time.Time
+}
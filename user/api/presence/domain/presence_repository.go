@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// PresenceRepository 在线会话与最后在线时间的存储，多个网关共享
+type PresenceRepository interface {
+	UpsertSession(ctx context.Context, s Session) error
+	DeleteSession(ctx context.Context, sessionID string) error
+	// TouchGateway 网关心跳：刷新该网关全部会话的 UpdatedAt
+	TouchGateway(ctx context.Context, gatewayID string, at time.Time) error
+	// DeleteGatewaySessions 清理某网关的全部会话（网关重启时），返回被删除的会话
+	DeleteGatewaySessions(ctx context.Context, gatewayID string) ([]Session, error)
+	// DeleteStaleSessions 清理 UpdatedAt 早于 before 的会话（网关宕机未清理），返回被删除的会话
+	DeleteStaleSessions(ctx context.Context, before time.Time) ([]Session, error)
+	ListSessions(ctx context.Context, userIDs []uint) ([]Session, error)
+	SetLastSeen(ctx context.Context, userID uint, at time.Time) error
+	GetLastSeen(ctx context.Context, userIDs []uint) (map[uint]time.Time, error)
+}
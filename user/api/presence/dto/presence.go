@@ -0,0 +1,27 @@
+package dto
+
+import (
+	"time"
+
+	"wsim/user/api/presence/domain"
+)
+
+type Presence struct {
+	UserID uint   `json:"user_id"`
+	State  string `json:"state"`
+	// LastSeen 从未上线过的用户为 null
+	LastSeen *time.Time `json:"last_seen"`
+}
+
+type PresenceListResponse struct {
+	Presences []Presence `json:"presences"`
+}
+
+func FromDomain(p domain.Presence) Presence {
+	out := Presence{UserID: p.UserID, State: string(p.State)}
+	if !p.LastSeen.IsZero() {
+		t := p.LastSeen
+		out.LastSeen = &t
+	}
+	return out
+}
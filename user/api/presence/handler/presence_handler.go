@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"wsim/user/api/identity"
+	"wsim/user/api/presence/dto"
+	"wsim/user/api/presence/usecase"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+)
+
+type PresenceHandler struct {
+	presence *usecase.PresenceService
+}
+
+func NewPresenceHandler(presence *usecase.PresenceService) *PresenceHandler {
+	return &PresenceHandler{presence: presence}
+}
+
+// Query GET ?user_ids=1,2,3
+func (h *PresenceHandler) Query(ctx context.Context, c *app.RequestContext) {
+	viewer, err := identity.UserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, utils.H{"error": "unauthorized"})
+		return
+	}
+	var ids []uint
+	for _, part := range strings.Split(c.Query("user_ids"), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, utils.H{"error": "bad request"})
+			return
+		}
+		ids = append(ids, uint(id))
+	}
+	ps, err := h.presence.Query(ctx, viewer, ids)
+	if err != nil {
+		if errors.Is(err, usecase.ErrBadRequest) {
+			c.JSON(http.StatusBadRequest, utils.H{"error": "bad request"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, utils.H{"error": err.Error()})
+		return
+	}
+	res := dto.PresenceListResponse{Presences: make([]dto.Presence, 0, len(ps))}
+	for _, p := range ps {
+		res.Presences = append(res.Presences, dto.FromDomain(p))
+	}
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,29 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+
+	"wsim/pkg/pubsub"
+	"wsim/user/api/presence/domain"
+	"wsim/user/api/presence/dto"
+
+	"gorm.io/gorm"
+)
+
+// PgNotifier 通过 PostgreSQL NOTIFY 把状态变化广播给所有网关
+type PgNotifier struct {
+	db *gorm.DB
+}
+
+func NewPgNotifier(db *gorm.DB) *PgNotifier {
+	return &PgNotifier{db: db}
+}
+
+func (n *PgNotifier) PresenceChanged(ctx context.Context, p domain.Presence) error {
+	data, err := json.Marshal(dto.FromDomain(p))
+	if err != nil {
+		return err
+	}
+	return pubsub.Publish(ctx, n.db, pubsub.ChannelPresence, string(data))
+}
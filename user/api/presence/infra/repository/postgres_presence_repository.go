@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"wsim/user/api/presence/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SessionModel 在线会话表；会话随连接建立/断开增删，不做软删除
+type SessionModel struct {
+	ID        string    `gorm:"type:varchar(128);primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	GatewayID string    `gorm:"type:varchar(64);not null;index"`
+	State     string    `gorm:"type:varchar(16);not null"`
+	UpdatedAt time.Time `gorm:"not null;index"`
+}
+
+func (SessionModel) TableName() string { return "presence_sessions" }
+
+// LastSeenModel 用户最后在线时间
+type LastSeenModel struct {
+	UserID     uint      `gorm:"primaryKey;autoIncrement:false"`
+	LastSeenAt time.Time `gorm:"not null"`
+}
+
+func (LastSeenModel) TableName() string { return "presence_last_seen" }
+
+type PostgresPresenceRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresPresenceRepository(db *gorm.DB) (*PostgresPresenceRepository, error) {
+	if err := db.AutoMigrate(&SessionModel{}, &LastSeenModel{}); err != nil {
+		return nil, err
+	}
+	return &PostgresPresenceRepository{db: db}, nil
+}
+
+func (r *PostgresPresenceRepository) UpsertSession(ctx context.Context, s domain.Session) error {
+	m := &SessionModel{
+		ID:        s.ID,
+		UserID:    s.UserID,
+		GatewayID: s.GatewayID,
+		State:     string(s.State),
+		UpdatedAt: s.UpdatedAt,
+	}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(m).Error
+}
+
+func (r *PostgresPresenceRepository) DeleteSession(ctx context.Context, sessionID string) error {
+	return r.db.WithContext(ctx).Where("id = ?", sessionID).Delete(&SessionModel{}).Error
+}
+
+func (r *PostgresPresenceRepository) TouchGateway(ctx context.Context, gatewayID string, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&SessionModel{}).
+		Where("gateway_id = ?", gatewayID).
+		Update("updated_at", at).Error
+}
+
+func (r *PostgresPresenceRepository) DeleteGatewaySessions(ctx context.Context, gatewayID string) ([]domain.Session, error) {
+	return r.deleteReturning(ctx, "gateway_id = ?", gatewayID)
+}
+
+func (r *PostgresPresenceRepository) DeleteStaleSessions(ctx context.Context, before time.Time) ([]domain.Session, error) {
+	return r.deleteReturning(ctx, "updated_at < ?", before)
+}
+
+func (r *PostgresPresenceRepository) deleteReturning(ctx context.Context, query string, args ...any) ([]domain.Session, error) {
+	var ms []SessionModel
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Returning{}).
+		Where(query, args...).
+		Delete(&ms).Error
+	if err != nil {
+		return nil, err
+	}
+	return toSessions(ms), nil
+}
+
+func (r *PostgresPresenceRepository) ListSessions(ctx context.Context, userIDs []uint) ([]domain.Session, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	var ms []SessionModel
+	if err := r.db.WithContext(ctx).Where("user_id IN ?", userIDs).Find(&ms).Error; err != nil {
+		return nil, err
+	}
+	return toSessions(ms), nil
+}
+
+func toSessions(ms []SessionModel) []domain.Session {
+	out := make([]domain.Session, 0, len(ms))
+	for _, m := range ms {
+		out = append(out, domain.Session{
+			ID:        m.ID,
+			UserID:    m.UserID,
+			GatewayID: m.GatewayID,
+			State:     domain.State(m.State),
+			UpdatedAt: m.UpdatedAt,
+		})
+	}
+	return out
+}
+
+func (r *PostgresPresenceRepository) SetLastSeen(ctx context.Context, userID uint, at time.Time) error {
+	m := &LastSeenModel{UserID: userID, LastSeenAt: at}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}},
+			// 只前进不后退：宕机清理时写入的心跳时间可能早于已有记录
+			DoUpdates: clause.Set{{
+				Column: clause.Column{Name: "last_seen_at"},
+				Value:  gorm.Expr("GREATEST(presence_last_seen.last_seen_at, EXCLUDED.last_seen_at)"),
+			}},
+		}).
+		Create(m).Error
+}
+
+func (r *PostgresPresenceRepository) GetLastSeen(ctx context.Context, userIDs []uint) (map[uint]time.Time, error) {
+	out := make(map[uint]time.Time, len(userIDs))
+	if len(userIDs) == 0 {
+		return out, nil
+	}
+	var ms []LastSeenModel
+	if err := r.db.WithContext(ctx).Where("user_id IN ?", userIDs).Find(&ms).Error; err != nil {
+		return nil, err
+	}
+	for _, m := range ms {
+		out[m.UserID] = m.LastSeenAt
+	}
+	return out, nil
+}
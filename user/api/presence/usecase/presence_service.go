@@ -0,0 +1,227 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"wsim/user/api/presence/domain"
+)
+
+var ErrBadRequest = errors.New("bad request")
+
+// 单次查询的用户数上限
+const maxQueryUsers = 500
+
+// SessionTTL 会话超过该时长未被网关心跳刷新即视为失效（网关宕机兜底）
+const SessionTTL = 90 * time.Second
+
+// PresenceNotifier 聚合状态变化后广播给所有网关
+type PresenceNotifier interface {
+	PresenceChanged(ctx context.Context, p domain.Presence) error
+}
+
+// BlockList 拉黑关系：查看者被对方拉黑时只能看到对方离线
+type BlockList interface {
+	// BlockersOf 返回 blockerIDs 中拉黑了 blockedID 的用户
+	BlockersOf(ctx context.Context, blockedID uint, blockerIDs []uint) ([]uint, error)
+}
+
+type PresenceService struct {
+	repo     domain.PresenceRepository
+	notifier PresenceNotifier
+	blocks   BlockList
+	now      func() time.Time
+}
+
+func NewPresenceService(repo domain.PresenceRepository, notifier PresenceNotifier, blocks BlockList) *PresenceService {
+	return &PresenceService{repo: repo, notifier: notifier, blocks: blocks, now: time.Now}
+}
+
+// Connect 网关上新建一条已认证连接
+func (s *PresenceService) Connect(ctx context.Context, sessionID string, userID uint, gatewayID string) error {
+	return s.change(ctx, userID, func() error {
+		return s.repo.UpsertSession(ctx, domain.Session{
+			ID:        sessionID,
+			UserID:    userID,
+			GatewayID: gatewayID,
+			State:     domain.StateOnline,
+			UpdatedAt: s.now(),
+		})
+	})
+}
+
+// SetState 客户端上报某个设备的状态（online/away）
+func (s *PresenceService) SetState(ctx context.Context, sessionID string, userID uint, gatewayID string, state domain.State) error {
+	if !state.Valid() {
+		return ErrBadRequest
+	}
+	return s.change(ctx, userID, func() error {
+		return s.repo.UpsertSession(ctx, domain.Session{
+			ID:        sessionID,
+			UserID:    userID,
+			GatewayID: gatewayID,
+			State:     state,
+			UpdatedAt: s.now(),
+		})
+	})
+}
+
+// Disconnect 连接断开
+func (s *PresenceService) Disconnect(ctx context.Context, sessionID string, userID uint) error {
+	return s.change(ctx, userID, func() error {
+		if err := s.repo.DeleteSession(ctx, sessionID); err != nil {
+			return err
+		}
+		return s.repo.SetLastSeen(ctx, userID, s.now())
+	})
+}
+
+// Heartbeat 网关定期调用：刷新本网关会话，并清理其他已宕机网关遗留的会话
+func (s *PresenceService) Heartbeat(ctx context.Context, gatewayID string) error {
+	now := s.now()
+	if err := s.repo.TouchGateway(ctx, gatewayID, now); err != nil {
+		return err
+	}
+	stale, err := s.repo.DeleteStaleSessions(ctx, now.Add(-SessionTTL))
+	if err != nil {
+		return err
+	}
+	return s.settle(ctx, stale)
+}
+
+// ResetGateway 网关启动时清理自己上次运行遗留的会话
+func (s *PresenceService) ResetGateway(ctx context.Context, gatewayID string) error {
+	sessions, err := s.repo.DeleteGatewaySessions(ctx, gatewayID)
+	if err != nil {
+		return err
+	}
+	return s.settle(ctx, sessions)
+}
+
+// Query 批量查询聚合状态。viewerID 为查看者：拉黑了查看者的用户一律显示为离线、不带最后在线时间，
+// 与网关订阅推送的处理一致，不暴露拉黑；viewerID 为 0 表示服务内部查询，不做屏蔽
+func (s *PresenceService) Query(ctx context.Context, viewerID uint, userIDs []uint) ([]domain.Presence, error) {
+	if len(userIDs) > maxQueryUsers {
+		return nil, ErrBadRequest
+	}
+	m, err := s.aggregate(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	hidden := make(map[uint]bool)
+	if viewerID != 0 && s.blocks != nil {
+		blockers, err := s.blocks.BlockersOf(ctx, viewerID, userIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range blockers {
+			hidden[id] = true
+		}
+	}
+	out := make([]domain.Presence, 0, len(userIDs))
+	for _, id := range userIDs {
+		if hidden[id] {
+			out = append(out, domain.Presence{UserID: id, State: domain.StateOffline})
+			continue
+		}
+		out = append(out, m[id])
+	}
+	return out, nil
+}
+
+// Online userIDs 中当前在线（online，不含 away）的用户，用于展开 @online
+func (s *PresenceService) Online(ctx context.Context, userIDs []uint) (map[uint]bool, error) {
+	m, err := s.aggregate(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[uint]bool)
+	for id, p := range m {
+		if p.State == domain.StateOnline {
+			out[id] = true
+		}
+	}
+	return out, nil
+}
+
+// change 执行 fn 前后各聚合一次，状态变化时广播
+func (s *PresenceService) change(ctx context.Context, userID uint, fn func() error) error {
+	before, err := s.aggregate(ctx, []uint{userID})
+	if err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	after, err := s.aggregate(ctx, []uint{userID})
+	if err != nil {
+		return err
+	}
+	if before[userID].State == after[userID].State {
+		return nil
+	}
+	return s.notifier.PresenceChanged(ctx, after[userID])
+}
+
+// settle 会话被批量清理后，记录最后在线时间并广播变为离线的用户
+func (s *PresenceService) settle(ctx context.Context, sessions []domain.Session) error {
+	if len(sessions) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(sessions))
+	seen := make(map[uint]struct{}, len(sessions))
+	for _, ss := range sessions {
+		if err := s.repo.SetLastSeen(ctx, ss.UserID, ss.UpdatedAt); err != nil {
+			return err
+		}
+		if _, ok := seen[ss.UserID]; !ok {
+			seen[ss.UserID] = struct{}{}
+			ids = append(ids, ss.UserID)
+		}
+	}
+	m, err := s.aggregate(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if p := m[id]; p.State == domain.StateOffline {
+			if err := s.notifier.PresenceChanged(ctx, p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *PresenceService) aggregate(ctx context.Context, userIDs []uint) (map[uint]domain.Presence, error) {
+	sessions, err := s.repo.ListSessions(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	lastSeen, err := s.repo.GetLastSeen(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[uint]domain.Presence, len(userIDs))
+	for _, id := range userIDs {
+		out[id] = domain.Presence{UserID: id, State: domain.StateOffline, LastSeen: lastSeen[id]}
+	}
+	staleBefore := s.now().Add(-SessionTTL)
+	for _, ss := range sessions {
+		if ss.UpdatedAt.Before(staleBefore) {
+			continue
+		}
+		p := out[ss.UserID]
+		switch {
+		case ss.State == domain.StateOnline:
+			p.State = domain.StateOnline
+		case p.State == domain.StateOffline:
+			p.State = domain.StateAway
+		}
+		// 在线用户的最后在线时间即当前
+		p.LastSeen = s.now()
+		out[ss.UserID] = p
+	}
+	return out, nil
+}
@@ -0,0 +1,106 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"wsim/user/api/presence/domain"
+)
+
+type memPresence struct {
+	sessions map[string]domain.Session
+	lastSeen map[uint]time.Time
+}
+
+func newMemPresence() *memPresence {
+	return &memPresence{sessions: make(map[string]domain.Session), lastSeen: make(map[uint]time.Time)}
+}
+
+func (m *memPresence) UpsertSession(_ context.Context, s domain.Session) error {
+	m.sessions[s.ID] = s
+	return nil
+}
+
+func (m *memPresence) DeleteSession(_ context.Context, id string) error {
+	delete(m.sessions, id)
+	return nil
+}
+
+func (m *memPresence) TouchGateway(context.Context, string, time.Time) error { return nil }
+
+func (m *memPresence) DeleteGatewaySessions(context.Context, string) ([]domain.Session, error) {
+	return nil, nil
+}
+
+func (m *memPresence) DeleteStaleSessions(context.Context, time.Time) ([]domain.Session, error) {
+	return nil, nil
+}
+
+func (m *memPresence) ListSessions(_ context.Context, userIDs []uint) ([]domain.Session, error) {
+	var out []domain.Session
+	for _, s := range m.sessions {
+		for _, id := range userIDs {
+			if s.UserID == id {
+				out = append(out, s)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (m *memPresence) SetLastSeen(_ context.Context, userID uint, at time.Time) error {
+	m.lastSeen[userID] = at
+	return nil
+}
+
+func (m *memPresence) GetLastSeen(_ context.Context, userIDs []uint) (map[uint]time.Time, error) {
+	return m.lastSeen, nil
+}
+
+type nopPresenceNotifier struct{}
+
+func (nopPresenceNotifier) PresenceChanged(context.Context, domain.Presence) error { return nil }
+
+// memBlocks blocker -> blocked
+type memBlocks map[uint]uint
+
+func (m memBlocks) BlockersOf(_ context.Context, blockedID uint, blockerIDs []uint) ([]uint, error) {
+	var out []uint
+	for _, id := range blockerIDs {
+		if b, ok := m[id]; ok && b == blockedID {
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
+
+func TestQueryHidesFromBlockedViewer(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemPresence()
+	s := NewPresenceService(repo, nopPresenceNotifier{}, memBlocks{1: 3})
+	for _, id := range []uint{1, 2} {
+		if err := s.Connect(ctx, fmt.Sprintf("conn-%d", id), id, "gw"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 用户 1 拉黑了 3：3 看到 1 离线，2 不受影响
+	ps, err := s.Query(ctx, 3, []uint{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps[0].State != domain.StateOffline || !ps[0].LastSeen.IsZero() || ps[1].State != domain.StateOnline {
+		t.Fatalf("viewer 3: %+v", ps)
+	}
+	ps, _ = s.Query(ctx, 2, []uint{1})
+	if ps[0].State != domain.StateOnline {
+		t.Fatalf("viewer 2: %+v", ps)
+	}
+	// 内部查询不屏蔽
+	ps, _ = s.Query(ctx, 0, []uint{1})
+	if ps[0].State != domain.StateOnline {
+		t.Fatalf("internal: %+v", ps)
+	}
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// 推送平台
+const (
+	PlatformAPNs = "apns"
+	PlatformFCM  = "fcm"
+)
+
+// Device 用户设备的推送令牌；同一令牌只属于一个用户，换账号登录时转移
+type Device struct {
+	UserID    uint
+	Platform  string
+	Token     string
+	UpdatedAt time.Time
+}
+
+type DeviceRepository interface {
+	// Register 登记令牌，令牌已存在时改绑到 UserID 并刷新时间
+	Register(ctx context.Context, d *Device) error
+	Unregister(ctx context.Context, userID uint, platform, token string) error
+	ListByUser(ctx context.Context, userID uint) ([]Device, error)
+	// DeleteToken 推送服务报告令牌失效时删除
+	DeleteToken(ctx context.Context, platform, token string) error
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrInvalidTimeZone DND 时区不是合法的 IANA 时区名
+var ErrInvalidTimeZone = errors.New("invalid time zone")
+
+// Preference 通知设置。ConversationID 为空是用户级默认设置，否则只作用于该会话；
+// 免打扰时段只在用户级设置上生效
+type Preference struct {
+	UserID         uint
+	ConversationID string
+	// MutedUntil 在此之前静音；零值不静音，很远的将来表示永久静音
+	MutedUntil time.Time
+	// MentionsOnly 只在被 @ 时提醒
+	MentionsOnly bool
+	DND          DNDSchedule
+	UpdatedAt    time.Time
+}
+
+// DNDSchedule 每天的免打扰时段，Start/End 为当地时间自零点起的分钟数；Start > End 表示跨零点
+type DNDSchedule struct {
+	Enabled  bool
+	Start    int
+	End      int
+	TimeZone string
+}
+
+// Active now 是否落在免打扰时段内；时区无法解析时按 UTC 计算
+func (d DNDSchedule) Active(now time.Time) bool {
+	if !d.Enabled || d.Start == d.End {
+		return false
+	}
+	loc, err := time.LoadLocation(d.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	t := now.In(loc)
+	m := t.Hour()*60 + t.Minute()
+	if d.Start < d.End {
+		return m >= d.Start && m < d.End
+	}
+	return m >= d.Start || m < d.End
+}
+
+// Muted now 是否处于静音期
+func (p *Preference) Muted(now time.Time) bool {
+	return p != nil && now.Before(p.MutedUntil)
+}
+
+// ShouldNotify 综合用户级与会话级设置判断一条消息是否提醒（推送、响铃、横幅）。
+// 免打扰时段内一律不提醒；静音或"仅 @ 提醒"时只有被 @ 才提醒。
+// 不提醒的消息照常投递，只是静默
+func ShouldNotify(now time.Time, global, conv *Preference, mentioned bool) bool {
+	if global != nil && global.DND.Active(now) {
+		return false
+	}
+	quiet := global.Muted(now) || conv.Muted(now) ||
+		(global != nil && global.MentionsOnly) || (conv != nil && conv.MentionsOnly)
+	return !quiet || mentioned
+}
+
+type PreferenceRepository interface {
+	// Get 取用户级与会话级设置，未设置过的返回 nil
+	Get(ctx context.Context, userID uint, conversationID string) (global, conv *Preference, err error)
+	// List 用户的全部设置（用户级在前）
+	List(ctx context.Context, userID uint) ([]Preference, error)
+	Save(ctx context.Context, p *Preference) error
+}
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDNDActive(t *testing.T) {
+	// 22:00–07:00 上海时间，跨零点
+	d := DNDSchedule{Enabled: true, Start: 22 * 60, End: 7 * 60, TimeZone: "Asia/Shanghai"}
+	cases := []struct {
+		utc  string
+		want bool
+	}{
+		{"2026-01-01T14:30:00Z", true},  // 22:30
+		{"2026-01-01T22:59:00Z", true},  // 06:59
+		{"2026-01-01T23:00:00Z", false}, // 07:00
+		{"2026-01-01T04:00:00Z", false}, // 12:00
+	}
+	for _, c := range cases {
+		now, _ := time.Parse(time.RFC3339, c.utc)
+		if got := d.Active(now); got != c.want {
+			t.Errorf("%s: got %v", c.utc, got)
+		}
+	}
+	d.Enabled = false
+	if d.Active(time.Date(2026, 1, 1, 15, 0, 0, 0, time.UTC)) {
+		t.Error("disabled schedule is active")
+	}
+}
+
+func TestShouldNotify(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	muted := &Preference{ConversationID: "c", MutedUntil: now.Add(time.Hour)}
+	expired := &Preference{ConversationID: "c", MutedUntil: now.Add(-time.Minute)}
+	mentionsOnly := &Preference{MentionsOnly: true}
+	dnd := &Preference{DND: DNDSchedule{Enabled: true, Start: 11 * 60, End: 13 * 60}}
+
+	cases := []struct {
+		name         string
+		global, conv *Preference
+		mentioned    bool
+		want         bool
+	}{
+		{"no settings", nil, nil, false, true},
+		{"muted", nil, muted, false, false},
+		{"muted but mentioned", nil, muted, true, true},
+		{"mute expired", nil, expired, false, true},
+		{"mentions only", mentionsOnly, nil, false, false},
+		{"mentions only, mentioned", mentionsOnly, nil, true, true},
+		{"dnd beats mention", dnd, nil, true, false},
+	}
+	for _, c := range cases {
+		if got := ShouldNotify(now, c.global, c.conv, c.mentioned); got != c.want {
+			t.Errorf("%s: got %v", c.name, got)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTokenInvalid 令牌已失效（应用卸载、令牌过期等），应删除，不再重试
+var ErrTokenInvalid = errors.New("device token invalid")
+
+// Notification 发给单个设备的一条推送
+type Notification struct {
+	Token string
+	Title string
+	Body  string
+	// Badge 应用图标角标，即全部会话未读总数
+	Badge int
+	// CollapseKey 相同 key 的推送在设备上只保留最新一条
+	CollapseKey string
+	Data        map[string]string
+}
+
+// PushProvider 推送服务（APNs、FCM）
+type PushProvider interface {
+	Platform() string
+	Send(ctx context.Context, n Notification) error
+}
+
+// ProviderError 推送服务返回的错误；Retryable 为 true 时可按 RetryAfter 退避重试
+type ProviderError struct {
+	Status     int
+	Reason     string
+	Retryable  bool
+	RetryAfter time.Duration
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("push provider: status %d: %s", e.Status, e.Reason)
+}
@@ -0,0 +1,27 @@
+package dto
+
+import (
+	"time"
+
+	"wsim/user/api/push/domain"
+)
+
+// DeviceRequest 注册/注销推送令牌，platform 为 apns 或 fcm
+type DeviceRequest struct {
+	Platform string `json:"platform"`
+	Token    string `json:"token"`
+}
+
+type Device struct {
+	Platform  string    `json:"platform"`
+	Token     string    `json:"token"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type DeviceListResponse struct {
+	Devices []Device `json:"devices"`
+}
+
+func FromDevice(d *domain.Device) Device {
+	return Device{Platform: d.Platform, Token: d.Token, UpdatedAt: d.UpdatedAt}
+}
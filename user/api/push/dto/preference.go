@@ -0,0 +1,81 @@
+package dto
+
+import (
+	"time"
+
+	"wsim/user/api/push/domain"
+)
+
+// Preference 通知设置；conversation_id 为空的是用户级设置。同一结构也作为设置同步帧推给用户的在线设备
+type Preference struct {
+	UserID         uint       `json:"user_id"`
+	ConversationID string     `json:"conversation_id,omitempty"`
+	MutedUntil     *time.Time `json:"muted_until"`
+	MentionsOnly   bool       `json:"mentions_only"`
+	DND            *DND       `json:"dnd,omitempty"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// DND 免打扰时段，start/end 为 "HH:MM"（当地时间），time_zone 为 IANA 时区名，缺省 UTC
+type DND struct {
+	Enabled  bool   `json:"enabled"`
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	TimeZone string `json:"time_zone"`
+}
+
+// UpdatePreferenceRequest 局部更新，缺省字段不修改；muted_until 传过去的时间即取消静音，
+// 永久静音传一个很远的时间；dnd 只能在用户级设置
+type UpdatePreferenceRequest struct {
+	MutedUntil   *time.Time `json:"muted_until"`
+	MentionsOnly *bool      `json:"mentions_only"`
+	DND          *DND       `json:"dnd"`
+}
+
+type PreferenceListResponse struct {
+	Preferences []Preference `json:"preferences"`
+}
+
+func FromPreference(p *domain.Preference) Preference {
+	out := Preference{
+		UserID:         p.UserID,
+		ConversationID: p.ConversationID,
+		MentionsOnly:   p.MentionsOnly,
+		UpdatedAt:      p.UpdatedAt,
+	}
+	if !p.MutedUntil.IsZero() {
+		t := p.MutedUntil
+		out.MutedUntil = &t
+	}
+	if p.ConversationID == "" {
+		out.DND = &DND{
+			Enabled:  p.DND.Enabled,
+			Start:    formatClock(p.DND.Start),
+			End:      formatClock(p.DND.End),
+			TimeZone: p.DND.TimeZone,
+		}
+	}
+	return out
+}
+
+// ToSchedule 解析 "HH:MM"；格式不对返回 false
+func (d *DND) ToSchedule() (domain.DNDSchedule, bool) {
+	start, ok1 := parseClock(d.Start)
+	end, ok2 := parseClock(d.End)
+	if !ok1 || !ok2 {
+		return domain.DNDSchedule{}, false
+	}
+	return domain.DNDSchedule{Enabled: d.Enabled, Start: start, End: end, TimeZone: d.TimeZone}, true
+}
+
+func parseClock(s string) (int, bool) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+func formatClock(m int) string {
+	return time.Date(0, 1, 1, m/60, m%60, 0, 0, time.UTC).Format("15:04")
+}
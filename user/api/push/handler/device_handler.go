@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"wsim/user/api/identity"
+	"wsim/user/api/push/dto"
+	"wsim/user/api/push/usecase"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+)
+
+type DeviceHandler struct {
+	devices *usecase.DeviceService
+}
+
+func NewDeviceHandler(devices *usecase.DeviceService) *DeviceHandler {
+	return &DeviceHandler{devices: devices}
+}
+
+// Register POST {platform, token}
+func (h *DeviceHandler) Register(ctx context.Context, c *app.RequestContext) {
+	uid, err := identity.UserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	var req dto.DeviceRequest
+	if err := c.BindJSON(&req); err != nil {
+		writeErr(c, usecase.ErrBadRequest)
+		return
+	}
+	d, err := h.devices.Register(ctx, uid, req.Platform, req.Token)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.FromDevice(d))
+}
+
+// Unregister DELETE {platform, token}
+func (h *DeviceHandler) Unregister(ctx context.Context, c *app.RequestContext) {
+	uid, err := identity.UserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	var req dto.DeviceRequest
+	if err := c.BindJSON(&req); err != nil {
+		writeErr(c, usecase.ErrBadRequest)
+		return
+	}
+	if err := h.devices.Unregister(ctx, uid, req.Platform, req.Token); err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// List 当前用户已登记的设备
+func (h *DeviceHandler) List(ctx context.Context, c *app.RequestContext) {
+	uid, err := identity.UserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	ds, err := h.devices.List(ctx, uid)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	res := dto.DeviceListResponse{Devices: make([]dto.Device, 0, len(ds))}
+	for i := range ds {
+		res.Devices = append(res.Devices, dto.FromDevice(&ds[i]))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func writeErr(c *app.RequestContext, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrBadRequest):
+		c.JSON(http.StatusBadRequest, utils.H{"error": "bad request"})
+	case errors.Is(err, identity.ErrUnauthorized):
+		c.JSON(http.StatusUnauthorized, utils.H{"error": "unauthorized"})
+	case errors.Is(err, usecase.ErrForbidden):
+		c.JSON(http.StatusForbidden, utils.H{"error": "forbidden"})
+	default:
+		c.JSON(http.StatusInternalServerError, utils.H{"error": err.Error()})
+	}
+}
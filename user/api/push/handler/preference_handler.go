@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"wsim/user/api/identity"
+	"wsim/user/api/push/dto"
+	"wsim/user/api/push/usecase"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+type PreferenceHandler struct {
+	prefs *usecase.PreferenceService
+}
+
+func NewPreferenceHandler(prefs *usecase.PreferenceService) *PreferenceHandler {
+	return &PreferenceHandler{prefs: prefs}
+}
+
+// List 用户级与各会话的通知设置
+func (h *PreferenceHandler) List(ctx context.Context, c *app.RequestContext) {
+	uid, err := identity.UserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	ps, err := h.prefs.List(ctx, uid)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	res := dto.PreferenceListResponse{Preferences: make([]dto.Preference, 0, len(ps))}
+	for i := range ps {
+		res.Preferences = append(res.Preferences, dto.FromPreference(&ps[i]))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// UpdateGlobal PATCH {muted_until, mentions_only, dnd}
+func (h *PreferenceHandler) UpdateGlobal(ctx context.Context, c *app.RequestContext) {
+	h.update(ctx, c, "")
+}
+
+// UpdateConversation PATCH {muted_until, mentions_only}
+func (h *PreferenceHandler) UpdateConversation(ctx context.Context, c *app.RequestContext) {
+	h.update(ctx, c, c.Param("conversation_id"))
+}
+
+func (h *PreferenceHandler) update(ctx context.Context, c *app.RequestContext, conversationID string) {
+	uid, err := identity.UserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	var req dto.UpdatePreferenceRequest
+	if err := c.BindJSON(&req); err != nil {
+		writeErr(c, usecase.ErrBadRequest)
+		return
+	}
+	patch := usecase.PreferencePatch{MutedUntil: req.MutedUntil, MentionsOnly: req.MentionsOnly}
+	if req.DND != nil {
+		d, ok := req.DND.ToSchedule()
+		if !ok {
+			writeErr(c, usecase.ErrBadRequest)
+			return
+		}
+		patch.DND = &d
+	}
+	p, err := h.prefs.Update(ctx, uid, conversationID, patch)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.FromPreference(p))
+}
@@ -0,0 +1,29 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+
+	"wsim/pkg/pubsub"
+	"wsim/user/api/push/domain"
+	"wsim/user/api/push/dto"
+
+	"gorm.io/gorm"
+)
+
+// PgNotifier 通过 PostgreSQL NOTIFY 把通知设置变更广播给网关，由网关推给用户的在线设备
+type PgNotifier struct {
+	db *gorm.DB
+}
+
+func NewPgNotifier(db *gorm.DB) *PgNotifier {
+	return &PgNotifier{db: db}
+}
+
+func (n *PgNotifier) PreferenceChanged(ctx context.Context, p *domain.Preference) error {
+	data, err := json.Marshal(dto.FromPreference(p))
+	if err != nil {
+		return err
+	}
+	return pubsub.Publish(ctx, n.db, pubsub.ChannelNotifyPrefs, string(data))
+}
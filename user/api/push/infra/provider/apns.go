@@ -0,0 +1,149 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"wsim/user/api/push/domain"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// APNs 提供者令牌有效期 1 小时，提前刷新
+const apnsTokenTTL = 50 * time.Minute
+
+// APNsConfig Endpoint 如 https://api.push.apple.com（沙盒为 api.sandbox.push.apple.com）；
+// Key 为开发者后台下载的 .p8 签名密钥
+type APNsConfig struct {
+	Endpoint string
+	KeyID    string
+	TeamID   string
+	// Topic 应用的 bundle ID
+	Topic string
+	Key   *ecdsa.PrivateKey
+}
+
+// APNs 基于 HTTP/2 的 APNs 推送，使用 ES256 提供者令牌鉴权
+type APNs struct {
+	cfg    APNsConfig
+	client *http.Client
+	now    func() time.Time
+
+	mu      sync.Mutex
+	token   string
+	tokenAt time.Time
+}
+
+func NewAPNs(cfg APNsConfig, client *http.Client) *APNs {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	return &APNs{cfg: cfg, client: client, now: time.Now}
+}
+
+func (a *APNs) Platform() string { return domain.PlatformAPNs }
+
+func (a *APNs) Send(ctx context.Context, n domain.Notification) error {
+	aps := map[string]any{
+		"alert": map[string]string{"title": n.Title, "body": n.Body},
+		"badge": n.Badge,
+		"sound": "default",
+	}
+	if n.CollapseKey != "" {
+		aps["thread-id"] = n.CollapseKey
+	}
+	payload := map[string]any{"aps": aps}
+	for k, v := range n.Data {
+		if k != "aps" {
+			payload[k] = v
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	token, err := a.providerToken()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.cfg.Endpoint+"/3/device/"+n.Token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("apns-topic", a.cfg.Topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+	req.Header.Set("apns-expiration", strconv.FormatInt(a.now().Add(24*time.Hour).Unix(), 10))
+	if n.CollapseKey != "" {
+		req.Header.Set("apns-collapse-id", n.CollapseKey)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return &domain.ProviderError{Reason: err.Error(), Retryable: true}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	var res struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&res)
+	switch {
+	case resp.StatusCode == http.StatusGone,
+		res.Reason == "BadDeviceToken", res.Reason == "Unregistered", res.Reason == "DeviceTokenNotForTopic":
+		return fmt.Errorf("%w: %s", domain.ErrTokenInvalid, res.Reason)
+	case res.Reason == "ExpiredProviderToken":
+		a.mu.Lock()
+		a.token = ""
+		a.mu.Unlock()
+		return &domain.ProviderError{Status: resp.StatusCode, Reason: res.Reason, Retryable: true}
+	}
+	return &domain.ProviderError{
+		Status:     resp.StatusCode,
+		Reason:     res.Reason,
+		Retryable:  resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500,
+		RetryAfter: retryAfter(resp.Header),
+	}
+}
+
+// providerToken 缓存的 ES256 提供者令牌；APNs 不允许过于频繁地更换令牌
+func (a *APNs) providerToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.now()
+	if a.token != "" && now.Sub(a.tokenAt) < apnsTokenTTL {
+		return a.token, nil
+	}
+	t := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": a.cfg.TeamID,
+		"iat": now.Unix(),
+	})
+	t.Header["kid"] = a.cfg.KeyID
+	signed, err := t.SignedString(a.cfg.Key)
+	if err != nil {
+		return "", err
+	}
+	a.token, a.tokenAt = signed, now
+	return signed, nil
+}
+
+// retryAfter 解析 Retry-After（秒数）
+func retryAfter(h http.Header) time.Duration {
+	if s, err := strconv.Atoi(h.Get("Retry-After")); err == nil && s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	return 0
+}
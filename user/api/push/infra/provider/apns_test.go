@@ -0,0 +1,87 @@
+package provider
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"wsim/user/api/push/domain"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// mockAPNs 校验提供者令牌与请求头，按设备令牌返回不同结果
+func mockAPNs(key *ecdsa.PrivateKey, got *map[string]any, retries *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := strings.TrimPrefix(r.Header.Get("Authorization"), "bearer ")
+		tok, err := jwt.Parse(auth, func(tk *jwt.Token) (any, error) {
+			if tk.Header["kid"] != "KEY123" {
+				return nil, errors.New("bad kid")
+			}
+			return &key.PublicKey, nil
+		}, jwt.WithValidMethods([]string{"ES256"}))
+		if err != nil || tok.Claims.(jwt.MapClaims)["iss"] != "TEAM42" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"reason":"InvalidProviderToken"}`))
+			return
+		}
+		if r.Header.Get("apns-topic") != "com.example.im" || r.Header.Get("apns-push-type") != "alert" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"reason":"BadTopic"}`))
+			return
+		}
+		switch strings.TrimPrefix(r.URL.Path, "/3/device/") {
+		case "gone":
+			w.WriteHeader(http.StatusGone)
+			w.Write([]byte(`{"reason":"Unregistered"}`))
+			return
+		case "busy":
+			*retries++
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"reason":"ServiceUnavailable"}`))
+			return
+		}
+		if r.Header.Get("apns-collapse-id") != "d_1_2" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(got)
+	}))
+}
+
+func TestAPNsSend(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	var got map[string]any
+	var retries int
+	srv := mockAPNs(key, &got, &retries)
+	defer srv.Close()
+	a := NewAPNs(APNsConfig{Endpoint: srv.URL, KeyID: "KEY123", TeamID: "TEAM42", Topic: "com.example.im", Key: key}, srv.Client())
+	n := domain.Notification{Token: "abc", Title: "Alice", Body: "hi", Badge: 3, CollapseKey: "d_1_2",
+		Data: map[string]string{"conversation_id": "d_1_2"}}
+
+	if err := a.Send(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	aps := got["aps"].(map[string]any)
+	if aps["badge"].(float64) != 3 || aps["alert"].(map[string]any)["body"] != "hi" || got["conversation_id"] != "d_1_2" {
+		t.Fatalf("unexpected payload: %v", got)
+	}
+
+	n.Token = "gone"
+	if err := a.Send(context.Background(), n); !errors.Is(err, domain.ErrTokenInvalid) {
+		t.Fatalf("want ErrTokenInvalid, got %v", err)
+	}
+	n.Token = "busy"
+	var pe *domain.ProviderError
+	if err := a.Send(context.Background(), n); !errors.As(err, &pe) || !pe.Retryable || pe.RetryAfter.Seconds() != 3 {
+		t.Fatalf("want retryable error, got %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package provider
+
+import (
+	"errors"
+	"os"
+
+	"wsim/user/api/push/domain"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// NewFromEnv 按环境变量启用推送服务，未配置的平台不推送：
+// APNs 需要 APNS_KEY_FILE（.p8）、APNS_KEY_ID、APNS_TEAM_ID、APNS_TOPIC，APNS_ENDPOINT 可覆盖地址；
+// FCM 需要 FCM_CREDENTIALS_FILE（服务账号 JSON），FCM_ENDPOINT 可覆盖地址
+func NewFromEnv() ([]domain.PushProvider, error) {
+	var out []domain.PushProvider
+	if path := os.Getenv("APNS_KEY_FILE"); path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := jwt.ParseECPrivateKeyFromPEM(raw)
+		if err != nil {
+			return nil, err
+		}
+		cfg := APNsConfig{
+			Endpoint: envOr("APNS_ENDPOINT", "https://api.push.apple.com"),
+			KeyID:    os.Getenv("APNS_KEY_ID"),
+			TeamID:   os.Getenv("APNS_TEAM_ID"),
+			Topic:    os.Getenv("APNS_TOPIC"),
+			Key:      key,
+		}
+		if cfg.KeyID == "" || cfg.TeamID == "" || cfg.Topic == "" {
+			return nil, errors.New("apns: APNS_KEY_ID, APNS_TEAM_ID and APNS_TOPIC are required")
+		}
+		out = append(out, NewAPNs(cfg, nil))
+	}
+	if path := os.Getenv("FCM_CREDENTIALS_FILE"); path != "" {
+		cfg, err := LoadFCMCredentials(path)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Endpoint = envOr("FCM_ENDPOINT", "https://fcm.googleapis.com")
+		out = append(out, NewFCM(cfg, nil))
+	}
+	return out, nil
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,203 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"wsim/user/api/push/domain"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// FCMConfig Endpoint 如 https://fcm.googleapis.com；其余字段来自服务账号凭据
+type FCMConfig struct {
+	Endpoint    string
+	ProjectID   string
+	ClientEmail string
+	TokenURI    string
+	Key         *rsa.PrivateKey
+}
+
+// LoadFCMCredentials 读取服务账号 JSON 凭据
+func LoadFCMCredentials(path string) (FCMConfig, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return FCMConfig{}, err
+	}
+	var sa struct {
+		ProjectID   string `json:"project_id"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(raw, &sa); err != nil {
+		return FCMConfig{}, err
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(sa.PrivateKey))
+	if err != nil {
+		return FCMConfig{}, err
+	}
+	if sa.ProjectID == "" || sa.ClientEmail == "" || sa.TokenURI == "" {
+		return FCMConfig{}, errors.New("fcm: incomplete service account credentials")
+	}
+	return FCMConfig{ProjectID: sa.ProjectID, ClientEmail: sa.ClientEmail, TokenURI: sa.TokenURI, Key: key}, nil
+}
+
+// FCM 基于 HTTP v1 接口的 FCM 推送；用服务账号签发的断言换取访问令牌
+type FCM struct {
+	cfg    FCMConfig
+	client *http.Client
+	now    func() time.Time
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func NewFCM(cfg FCMConfig, client *http.Client) *FCM {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	return &FCM{cfg: cfg, client: client, now: time.Now}
+}
+
+func (f *FCM) Platform() string { return domain.PlatformFCM }
+
+func (f *FCM) Send(ctx context.Context, n domain.Notification) error {
+	android := map[string]any{
+		"priority": "high",
+		"notification": map[string]any{
+			"notification_count": n.Badge,
+		},
+	}
+	if n.CollapseKey != "" {
+		android["collapse_key"] = n.CollapseKey
+		android["notification"].(map[string]any)["tag"] = n.CollapseKey
+	}
+	body, err := json.Marshal(map[string]any{
+		"message": map[string]any{
+			"token":        n.Token,
+			"notification": map[string]string{"title": n.Title, "body": n.Body},
+			"data":         n.Data,
+			"android":      android,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	token, err := f.accessToken(ctx)
+	if err != nil {
+		return &domain.ProviderError{Reason: err.Error(), Retryable: true}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		f.cfg.Endpoint+"/v1/projects/"+url.PathEscape(f.cfg.ProjectID)+"/messages:send", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return &domain.ProviderError{Reason: err.Error(), Retryable: true}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	var res struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 16<<10)).Decode(&res)
+	reason := res.Error.Status
+	for _, d := range res.Error.Details {
+		if d.ErrorCode != "" {
+			reason = d.ErrorCode
+		}
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound, reason == "UNREGISTERED":
+		return fmt.Errorf("%w: %s", domain.ErrTokenInvalid, reason)
+	case resp.StatusCode == http.StatusUnauthorized:
+		// 访问令牌被提前吊销：丢弃缓存，下次重新换取
+		f.mu.Lock()
+		f.token = ""
+		f.mu.Unlock()
+		return &domain.ProviderError{Status: resp.StatusCode, Reason: reason, Retryable: true}
+	}
+	return &domain.ProviderError{
+		Status:     resp.StatusCode,
+		Reason:     reason,
+		Retryable:  resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500,
+		RetryAfter: retryAfter(resp.Header),
+	}
+}
+
+// accessToken 缓存的 OAuth2 访问令牌，过期前一分钟刷新
+func (f *FCM) accessToken(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	if f.token != "" && now.Before(f.expiresAt.Add(-time.Minute)) {
+		return f.token, nil
+	}
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   f.cfg.ClientEmail,
+		"scope": fcmScope,
+		"aud":   f.cfg.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(f.cfg.Key)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.cfg.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New("fcm: token exchange failed: " + strconv.Itoa(resp.StatusCode))
+	}
+	var res struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 16<<10)).Decode(&res); err != nil {
+		return "", err
+	}
+	if res.AccessToken == "" {
+		return "", errors.New("fcm: empty access token")
+	}
+	f.token, f.expiresAt = res.AccessToken, now.Add(time.Duration(res.ExpiresIn)*time.Second)
+	return f.token, nil
+}
@@ -0,0 +1,100 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wsim/user/api/push/domain"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestFCMSend(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	var exchanges int
+	var got struct {
+		Message struct {
+			Token        string            `json:"token"`
+			Notification map[string]string `json:"notification"`
+			Data         map[string]string `json:"data"`
+			Android      struct {
+				CollapseKey  string `json:"collapse_key"`
+				Notification struct {
+					Count int `json:"notification_count"`
+				} `json:"notification"`
+			} `json:"android"`
+		} `json:"message"`
+	}
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		_, err := jwt.Parse(r.PostForm.Get("assertion"), func(*jwt.Token) (any, error) { return &key.PublicKey, nil },
+			jwt.WithValidMethods([]string{"RS256"}))
+		if err != nil || r.PostForm.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		exchanges++
+		w.Write([]byte(`{"access_token":"at-1","expires_in":3600}`))
+	})
+	mux.HandleFunc("/v1/projects/demo/messages:send", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer at-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			Message struct {
+				Token string `json:"token"`
+			} `json:"message"`
+		}
+		raw, _ := io.ReadAll(r.Body)
+		json.Unmarshal(raw, &body)
+		switch body.Message.Token {
+		case "stale":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"status":"NOT_FOUND","details":[{"errorCode":"UNREGISTERED"}]}}`))
+			return
+		case "quota":
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"status":"RESOURCE_EXHAUSTED"}}`))
+			return
+		}
+		json.Unmarshal(raw, &got)
+		w.Write([]byte(`{"name":"projects/demo/messages/1"}`))
+	})
+
+	f := NewFCM(FCMConfig{Endpoint: srv.URL, ProjectID: "demo", ClientEmail: "push@demo", TokenURI: srv.URL + "/token", Key: key}, srv.Client())
+	n := domain.Notification{Token: "tok", Title: "Alice", Body: "hi", Badge: 5, CollapseKey: "d_1_2",
+		Data: map[string]string{"message_id": "7"}}
+	for i := 0; i < 2; i++ {
+		if err := f.Send(context.Background(), n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if exchanges != 1 {
+		t.Fatalf("access token should be cached, exchanged %d times", exchanges)
+	}
+	if got.Message.Token != "tok" || got.Message.Notification["title"] != "Alice" || got.Message.Data["message_id"] != "7" ||
+		got.Message.Android.CollapseKey != "d_1_2" || got.Message.Android.Notification.Count != 5 {
+		t.Fatalf("unexpected message: %+v", got.Message)
+	}
+
+	n.Token = "stale"
+	if err := f.Send(context.Background(), n); !errors.Is(err, domain.ErrTokenInvalid) {
+		t.Fatalf("want ErrTokenInvalid, got %v", err)
+	}
+	n.Token = "quota"
+	var pe *domain.ProviderError
+	if err := f.Send(context.Background(), n); !errors.As(err, &pe) || !pe.Retryable {
+		t.Fatalf("want retryable error, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"wsim/user/api/push/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DeviceModel struct {
+	Platform  string    `gorm:"type:varchar(8);primaryKey"`
+	Token     string    `gorm:"type:varchar(512);primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
+
+func (DeviceModel) TableName() string { return "push_devices" }
+
+type PostgresDeviceRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresDeviceRepository(db *gorm.DB) (*PostgresDeviceRepository, error) {
+	if err := db.AutoMigrate(&DeviceModel{}); err != nil {
+		return nil, err
+	}
+	return &PostgresDeviceRepository{db: db}, nil
+}
+
+func (r *PostgresDeviceRepository) Register(ctx context.Context, d *domain.Device) error {
+	d.UpdatedAt = time.Now()
+	m := &DeviceModel{Platform: d.Platform, Token: d.Token, UserID: d.UserID, UpdatedAt: d.UpdatedAt}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "platform"}, {Name: "token"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "updated_at"}),
+	}).Create(m).Error
+}
+
+func (r *PostgresDeviceRepository) Unregister(ctx context.Context, userID uint, platform, token string) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND platform = ? AND token = ?", userID, platform, token).
+		Delete(&DeviceModel{}).Error
+}
+
+func (r *PostgresDeviceRepository) ListByUser(ctx context.Context, userID uint) ([]domain.Device, error) {
+	var ms []DeviceModel
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("updated_at DESC").Find(&ms).Error; err != nil {
+		return nil, err
+	}
+	out := make([]domain.Device, 0, len(ms))
+	for _, m := range ms {
+		out = append(out, domain.Device{UserID: m.UserID, Platform: m.Platform, Token: m.Token, UpdatedAt: m.UpdatedAt})
+	}
+	return out, nil
+}
+
+func (r *PostgresDeviceRepository) DeleteToken(ctx context.Context, platform, token string) error {
+	return r.db.WithContext(ctx).Where("platform = ? AND token = ?", platform, token).Delete(&DeviceModel{}).Error
+}
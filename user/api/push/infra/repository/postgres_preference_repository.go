@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"wsim/user/api/push/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PreferenceModel 通知设置；conversation_id 为空串的一行是用户级设置
+type PreferenceModel struct {
+	UserID         uint   `gorm:"primaryKey;autoIncrement:false"`
+	ConversationID string `gorm:"type:varchar(64);primaryKey"`
+	MutedUntil     *time.Time
+	MentionsOnly   bool      `gorm:"not null;default:false"`
+	DNDEnabled     bool      `gorm:"column:dnd_enabled;not null;default:false"`
+	DNDStart       int       `gorm:"column:dnd_start;not null;default:0"`
+	DNDEnd         int       `gorm:"column:dnd_end;not null;default:0"`
+	DNDTimeZone    string    `gorm:"column:dnd_time_zone;type:varchar(64);not null;default:''"`
+	UpdatedAt      time.Time `gorm:"not null"`
+}
+
+func (PreferenceModel) TableName() string { return "notification_preferences" }
+
+type PostgresPreferenceRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresPreferenceRepository(db *gorm.DB) (*PostgresPreferenceRepository, error) {
+	if err := db.AutoMigrate(&PreferenceModel{}); err != nil {
+		return nil, err
+	}
+	return &PostgresPreferenceRepository{db: db}, nil
+}
+
+func (r *PostgresPreferenceRepository) Get(ctx context.Context, userID uint, conversationID string) (*domain.Preference, *domain.Preference, error) {
+	var ms []PreferenceModel
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND conversation_id IN ?", userID, []string{"", conversationID}).
+		Find(&ms).Error; err != nil {
+		return nil, nil, err
+	}
+	var global, conv *domain.Preference
+	for i := range ms {
+		if ms[i].ConversationID == "" {
+			global = toPreference(&ms[i])
+		} else {
+			conv = toPreference(&ms[i])
+		}
+	}
+	return global, conv, nil
+}
+
+func (r *PostgresPreferenceRepository) List(ctx context.Context, userID uint) ([]domain.Preference, error) {
+	var ms []PreferenceModel
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("conversation_id").Find(&ms).Error; err != nil {
+		return nil, err
+	}
+	out := make([]domain.Preference, 0, len(ms))
+	for i := range ms {
+		out = append(out, *toPreference(&ms[i]))
+	}
+	return out, nil
+}
+
+func (r *PostgresPreferenceRepository) Save(ctx context.Context, p *domain.Preference) error {
+	p.UpdatedAt = time.Now()
+	m := &PreferenceModel{
+		UserID:         p.UserID,
+		ConversationID: p.ConversationID,
+		MentionsOnly:   p.MentionsOnly,
+		DNDEnabled:     p.DND.Enabled,
+		DNDStart:       p.DND.Start,
+		DNDEnd:         p.DND.End,
+		DNDTimeZone:    p.DND.TimeZone,
+		UpdatedAt:      p.UpdatedAt,
+	}
+	if !p.MutedUntil.IsZero() {
+		t := p.MutedUntil
+		m.MutedUntil = &t
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "conversation_id"}},
+		UpdateAll: true,
+	}).Create(m).Error
+}
+
+func toPreference(m *PreferenceModel) *domain.Preference {
+	p := &domain.Preference{
+		UserID:         m.UserID,
+		ConversationID: m.ConversationID,
+		MentionsOnly:   m.MentionsOnly,
+		DND: domain.DNDSchedule{
+			Enabled:  m.DNDEnabled,
+			Start:    m.DNDStart,
+			End:      m.DNDEnd,
+			TimeZone: m.DNDTimeZone,
+		},
+		UpdatedAt: m.UpdatedAt,
+	}
+	if m.MutedUntil != nil {
+		p.MutedUntil = *m.MutedUntil
+	}
+	return p
+}
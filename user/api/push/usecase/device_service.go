@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"wsim/user/api/push/domain"
+)
+
+var ErrBadRequest = errors.New("bad request")
+
+const maxTokenLen = 512
+
+type DeviceService struct {
+	devices domain.DeviceRepository
+}
+
+func NewDeviceService(devices domain.DeviceRepository) *DeviceService {
+	return &DeviceService{devices: devices}
+}
+
+// Register 登记当前设备的推送令牌；客户端每次启动拿到令牌后都应调用，令牌轮换后旧令牌由推送失败时清理
+func (s *DeviceService) Register(ctx context.Context, userID uint, platform, token string) (*domain.Device, error) {
+	if userID == 0 || !validPlatform(platform) || !validToken(token) {
+		return nil, ErrBadRequest
+	}
+	d := &domain.Device{UserID: userID, Platform: platform, Token: token}
+	if err := s.devices.Register(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Unregister 登出或关闭推送时注销；只能注销自己名下的令牌
+func (s *DeviceService) Unregister(ctx context.Context, userID uint, platform, token string) error {
+	if userID == 0 || !validPlatform(platform) || !validToken(token) {
+		return ErrBadRequest
+	}
+	return s.devices.Unregister(ctx, userID, platform, token)
+}
+
+func (s *DeviceService) List(ctx context.Context, userID uint) ([]domain.Device, error) {
+	return s.devices.ListByUser(ctx, userID)
+}
+
+func validPlatform(p string) bool {
+	return p == domain.PlatformAPNs || p == domain.PlatformFCM
+}
+
+func validToken(t string) bool {
+	return t != "" && len(t) <= maxTokenLen && !strings.ContainsAny(t, " \t\r\n/?#")
+}
@@ -0,0 +1,204 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"wsim/user/api/push/domain"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+const (
+	// 待发推送队列长度，满了直接丢弃（推送本身是尽力而为）
+	queueSize = 4096
+	// 默认最多尝试次数与首次重试间隔（之后翻倍）
+	defaultAttempts = 3
+	defaultBackoff  = time.Second
+	maxBackoff      = 30 * time.Second
+)
+
+// Badges 用户的未读总数，用作角标
+type Badges interface {
+	UnreadTotal(ctx context.Context, userID uint) (int64, error)
+}
+
+// Event 一条需要推送给离线用户的消息
+type Event struct {
+	UserID         uint
+	ConversationID string
+	MessageID      uint64
+	SenderID       uint
+	Title          string
+	Body           string
+}
+
+func (e Event) key() string {
+	return strconv.FormatUint(uint64(e.UserID), 10) + "/" + e.ConversationID
+}
+
+// Dispatcher 离线推送：同一用户同一会话排队期间的多条消息合并为最新一条，
+// 发送时带上会话作为 collapse key（设备上也只保留最新一条）与未读总数角标，
+// 可重试的失败按指数退避重试，令牌失效时删除
+type Dispatcher struct {
+	devices   domain.DeviceRepository
+	providers map[string]domain.PushProvider
+	badges    Badges
+	// MaxAttempts 单个设备最多尝试次数；Backoff 首次重试间隔，之后翻倍
+	MaxAttempts int
+	Backoff     time.Duration
+
+	mu      sync.Mutex
+	pending map[string]Event
+	queue   chan string
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+func NewDispatcher(devices domain.DeviceRepository, providers []domain.PushProvider, badges Badges) *Dispatcher {
+	d := &Dispatcher{
+		devices:     devices,
+		providers:   make(map[string]domain.PushProvider, len(providers)),
+		badges:      badges,
+		MaxAttempts: defaultAttempts,
+		Backoff:     defaultBackoff,
+		pending:     make(map[string]Event),
+		queue:       make(chan string, queueSize),
+		sleep:       sleepCtx,
+	}
+	for _, p := range providers {
+		d.providers[p.Platform()] = p
+	}
+	return d
+}
+
+// Enabled 至少配置了一个推送服务
+func (d *Dispatcher) Enabled() bool {
+	return len(d.providers) > 0
+}
+
+// Start 启动 workers 个发送协程，ctx 结束后退出
+func (d *Dispatcher) Start(ctx context.Context, workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case key := <-d.queue:
+					d.mu.Lock()
+					e, ok := d.pending[key]
+					delete(d.pending, key)
+					d.mu.Unlock()
+					if !ok {
+						continue
+					}
+					if err := d.Dispatch(ctx, e); err != nil {
+						hlog.CtxWarnf(ctx, "push to user %d: %v", e.UserID, err)
+					}
+				}
+			}
+		}()
+	}
+}
+
+// Enqueue 排队一条推送；同一用户同一会话还没发出的推送被替换为最新一条
+func (d *Dispatcher) Enqueue(e Event) {
+	if !d.Enabled() {
+		return
+	}
+	key := e.key()
+	d.mu.Lock()
+	_, queued := d.pending[key]
+	d.pending[key] = e
+	d.mu.Unlock()
+	if queued {
+		return
+	}
+	select {
+	case d.queue <- key:
+	default:
+		d.mu.Lock()
+		delete(d.pending, key)
+		d.mu.Unlock()
+		hlog.Warnf("push queue full, dropping notification for user %d", e.UserID)
+	}
+}
+
+// Dispatch 立即推送到用户的全部设备，返回最后一个无法送达的错误
+func (d *Dispatcher) Dispatch(ctx context.Context, e Event) error {
+	devices, err := d.devices.ListByUser(ctx, e.UserID)
+	if err != nil || len(devices) == 0 {
+		return err
+	}
+	badge, err := d.badges.UnreadTotal(ctx, e.UserID)
+	if err != nil {
+		return err
+	}
+	var last error
+	for _, dev := range devices {
+		p, ok := d.providers[dev.Platform]
+		if !ok {
+			continue
+		}
+		n := domain.Notification{
+			Token:       dev.Token,
+			Title:       e.Title,
+			Body:        e.Body,
+			Badge:       int(badge),
+			CollapseKey: e.ConversationID,
+			Data: map[string]string{
+				"conversation_id": e.ConversationID,
+				"message_id":      strconv.FormatUint(e.MessageID, 10),
+				"sender_id":       strconv.FormatUint(uint64(e.SenderID), 10),
+			},
+		}
+		err := d.send(ctx, p, n)
+		if errors.Is(err, domain.ErrTokenInvalid) {
+			if err := d.devices.DeleteToken(ctx, dev.Platform, dev.Token); err != nil {
+				last = err
+			}
+			continue
+		}
+		if err != nil {
+			last = err
+		}
+	}
+	return last
+}
+
+func (d *Dispatcher) send(ctx context.Context, p domain.PushProvider, n domain.Notification) error {
+	backoff := d.Backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = p.Send(ctx, n)
+		var pe *domain.ProviderError
+		if err == nil || !errors.As(err, &pe) || !pe.Retryable || attempt >= d.MaxAttempts {
+			return err
+		}
+		wait := backoff
+		if pe.RetryAfter > wait {
+			wait = pe.RetryAfter
+		}
+		if wait > maxBackoff {
+			wait = maxBackoff
+		}
+		if err := d.sleep(ctx, wait); err != nil {
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,141 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"wsim/user/api/push/domain"
+)
+
+type memDevices struct {
+	mu      sync.Mutex
+	devices []domain.Device
+}
+
+func (m *memDevices) Register(_ context.Context, d *domain.Device) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.devices = append(m.devices, *d)
+	return nil
+}
+
+func (m *memDevices) Unregister(ctx context.Context, _ uint, platform, token string) error {
+	return m.DeleteToken(ctx, platform, token)
+}
+
+func (m *memDevices) ListByUser(_ context.Context, userID uint) ([]domain.Device, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []domain.Device
+	for _, d := range m.devices {
+		if d.UserID == userID {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+func (m *memDevices) DeleteToken(_ context.Context, platform, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, d := range m.devices {
+		if d.Platform == platform && d.Token == token {
+			m.devices = append(m.devices[:i], m.devices[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// fakeProvider 按令牌返回预设的错误序列，记录成功送达的推送
+type fakeProvider struct {
+	mu        sync.Mutex
+	failures  map[string][]error
+	delivered []domain.Notification
+	sent      chan struct{}
+}
+
+func (p *fakeProvider) Platform() string { return domain.PlatformFCM }
+
+func (p *fakeProvider) Send(_ context.Context, n domain.Notification) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if errs := p.failures[n.Token]; len(errs) > 0 {
+		p.failures[n.Token] = errs[1:]
+		return errs[0]
+	}
+	p.delivered = append(p.delivered, n)
+	if p.sent != nil {
+		p.sent <- struct{}{}
+	}
+	return nil
+}
+
+type fixedBadge int64
+
+func (b fixedBadge) UnreadTotal(context.Context, uint) (int64, error) { return int64(b), nil }
+
+func TestDispatchRetriesAndDropsInvalidTokens(t *testing.T) {
+	devices := &memDevices{devices: []domain.Device{
+		{UserID: 1, Platform: domain.PlatformFCM, Token: "flaky"},
+		{UserID: 1, Platform: domain.PlatformFCM, Token: "gone"},
+	}}
+	p := &fakeProvider{failures: map[string][]error{
+		"flaky": {&domain.ProviderError{Status: 503, Retryable: true}, &domain.ProviderError{Status: 429, Retryable: true, RetryAfter: 2 * time.Second}},
+		"gone":  {domain.ErrTokenInvalid},
+	}}
+	d := NewDispatcher(devices, []domain.PushProvider{p}, fixedBadge(7))
+	var waits []time.Duration
+	d.sleep = func(_ context.Context, w time.Duration) error {
+		waits = append(waits, w)
+		return nil
+	}
+
+	if err := d.Dispatch(context.Background(), Event{UserID: 1, ConversationID: "d_1_2", Body: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.delivered) != 1 || p.delivered[0].Badge != 7 || p.delivered[0].CollapseKey != "d_1_2" {
+		t.Fatalf("delivered: %+v", p.delivered)
+	}
+	if len(waits) != 2 || waits[0] != time.Second || waits[1] != 2*time.Second {
+		t.Fatalf("backoff: %v", waits)
+	}
+	left, _ := devices.ListByUser(context.Background(), 1)
+	if len(left) != 1 || left[0].Token != "flaky" {
+		t.Fatalf("invalid token not removed: %+v", left)
+	}
+
+	// 重试次数用尽
+	p.failures["flaky"] = []error{&domain.ProviderError{Retryable: true}, &domain.ProviderError{Retryable: true}, &domain.ProviderError{Retryable: true}}
+	if err := d.Dispatch(context.Background(), Event{UserID: 1}); err == nil {
+		t.Fatal("want error after exhausting attempts")
+	}
+}
+
+func TestEnqueueCollapsesPerConversation(t *testing.T) {
+	devices := &memDevices{devices: []domain.Device{{UserID: 1, Platform: domain.PlatformFCM, Token: "t"}}}
+	p := &fakeProvider{failures: map[string][]error{}, sent: make(chan struct{}, 4)}
+	d := NewDispatcher(devices, []domain.PushProvider{p}, fixedBadge(0))
+
+	// worker 未启动前排队的三条：同一会话只剩最新一条
+	d.Enqueue(Event{UserID: 1, ConversationID: "a", Body: "1"})
+	d.Enqueue(Event{UserID: 1, ConversationID: "a", Body: "2"})
+	d.Enqueue(Event{UserID: 1, ConversationID: "b", Body: "3"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d.Start(ctx, 1)
+	for i := 0; i < 2; i++ {
+		select {
+		case <-p.sent:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for push")
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.delivered) != 2 || p.delivered[0].Body != "2" || p.delivered[1].Body != "3" {
+		t.Fatalf("delivered: %+v", p.delivered)
+	}
+}
@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"wsim/user/api/push/domain"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+// ErrForbidden 不是该会话的成员
+var ErrForbidden = errors.New("forbidden")
+
+const minutesPerDay = 24 * 60
+
+// Membership 判断用户是否在会话中，由消息服务实现
+type Membership interface {
+	IsMember(ctx context.Context, conversationID string, userID uint) (bool, error)
+}
+
+// PreferenceNotifier 设置变更后同步到用户的其他设备
+type PreferenceNotifier interface {
+	PreferenceChanged(ctx context.Context, p *domain.Preference) error
+}
+
+// PreferencePatch 局部更新，nil 表示不修改
+type PreferencePatch struct {
+	// MutedUntil 传过去的时间即取消静音
+	MutedUntil   *time.Time
+	MentionsOnly *bool
+	DND          *domain.DNDSchedule
+}
+
+type PreferenceService struct {
+	prefs    domain.PreferenceRepository
+	members  Membership
+	notifier PreferenceNotifier
+	now      func() time.Time
+}
+
+func NewPreferenceService(prefs domain.PreferenceRepository, members Membership, notifier PreferenceNotifier) *PreferenceService {
+	return &PreferenceService{prefs: prefs, members: members, notifier: notifier, now: time.Now}
+}
+
+// List 用户级与各会话的设置
+func (s *PreferenceService) List(ctx context.Context, userID uint) ([]domain.Preference, error) {
+	return s.prefs.List(ctx, userID)
+}
+
+// Update 修改用户级（conversationID 为空）或会话级设置，并同步到用户的全部在线设备
+func (s *PreferenceService) Update(ctx context.Context, userID uint, conversationID string, patch PreferencePatch) (*domain.Preference, error) {
+	if userID == 0 {
+		return nil, ErrBadRequest
+	}
+	if patch.DND != nil {
+		if conversationID != "" || !validDND(*patch.DND) {
+			return nil, ErrBadRequest
+		}
+	}
+	if conversationID != "" {
+		ok, err := s.members.IsMember(ctx, conversationID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrForbidden
+		}
+	}
+	global, conv, err := s.prefs.Get(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	p := global
+	if conversationID != "" {
+		p = conv
+	}
+	if p == nil {
+		p = &domain.Preference{UserID: userID, ConversationID: conversationID}
+	}
+	if patch.MutedUntil != nil {
+		p.MutedUntil = time.Time{}
+		if patch.MutedUntil.After(s.now()) {
+			p.MutedUntil = *patch.MutedUntil
+		}
+	}
+	if patch.MentionsOnly != nil {
+		p.MentionsOnly = *patch.MentionsOnly
+	}
+	if patch.DND != nil {
+		p.DND = *patch.DND
+	}
+	if err := s.prefs.Save(ctx, p); err != nil {
+		return nil, err
+	}
+	if err := s.notifier.PreferenceChanged(ctx, p); err != nil {
+		// 同步失败不影响保存，其他设备下次拉取设置时得到最新值
+		hlog.CtxWarnf(ctx, "notify preference change for user %d: %v", userID, err)
+	}
+	return p, nil
+}
+
+// ShouldNotify 一条会话消息是否要提醒 userID；mentioned 为消息是否 @ 了该用户
+func (s *PreferenceService) ShouldNotify(ctx context.Context, userID uint, conversationID string, mentioned bool) (bool, error) {
+	global, conv, err := s.prefs.Get(ctx, userID, conversationID)
+	if err != nil {
+		return false, err
+	}
+	return domain.ShouldNotify(s.now(), global, conv, mentioned), nil
+}
+
+func validDND(d domain.DNDSchedule) bool {
+	if d.Start < 0 || d.Start >= minutesPerDay || d.End < 0 || d.End >= minutesPerDay {
+		return false
+	}
+	_, err := time.LoadLocation(d.TimeZone)
+	return err == nil
+}
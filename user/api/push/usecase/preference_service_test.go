@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"wsim/user/api/push/domain"
+)
+
+type prefKey struct {
+	userID uint
+	convID string
+}
+
+type memPrefs map[prefKey]domain.Preference
+
+func (m memPrefs) Get(_ context.Context, userID uint, convID string) (global, conv *domain.Preference, err error) {
+	if p, ok := m[prefKey{userID, ""}]; ok {
+		global = &p
+	}
+	if p, ok := m[prefKey{userID, convID}]; ok && convID != "" {
+		conv = &p
+	}
+	return global, conv, nil
+}
+
+func (m memPrefs) List(context.Context, uint) ([]domain.Preference, error) { return nil, nil }
+
+func (m memPrefs) Save(_ context.Context, p *domain.Preference) error {
+	m[prefKey{p.UserID, p.ConversationID}] = *p
+	return nil
+}
+
+// memberOf 只有列出的会话可以设置
+type memberOf string
+
+func (c memberOf) IsMember(_ context.Context, convID string, _ uint) (bool, error) {
+	return convID == string(c), nil
+}
+
+type nopPrefNotifier struct{}
+
+func (nopPrefNotifier) PreferenceChanged(context.Context, *domain.Preference) error { return nil }
+
+func TestMentionOverridesMute(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := NewPreferenceService(memPrefs{}, memberOf("c"), nopPrefNotifier{})
+	s.now = func() time.Time { return now }
+	update := func(conv string, p PreferencePatch) {
+		t.Helper()
+		if _, err := s.Update(ctx, 1, conv, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(name string, mentioned, want bool) {
+		t.Helper()
+		if got, err := s.ShouldNotify(ctx, 1, "c", mentioned); err != nil || got != want {
+			t.Fatalf("%s: got %v %v", name, got, err)
+		}
+	}
+
+	if _, err := s.Update(ctx, 1, "other", PreferencePatch{}); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("non-member: got %v", err)
+	}
+	until := now.Add(time.Hour)
+	update("c", PreferencePatch{MutedUntil: &until})
+	check("muted", false, false)
+	check("muted, mentioned", true, true)
+
+	past := now.Add(-time.Second)
+	on := true
+	update("c", PreferencePatch{MutedUntil: &past})
+	update("", PreferencePatch{MentionsOnly: &on})
+	check("mentions only", false, false)
+	check("mentions only, mentioned", true, true)
+
+	// 免打扰时段内被 @ 也不提醒
+	update("", PreferencePatch{DND: &domain.DNDSchedule{Enabled: true, Start: 11 * 60, End: 13 * 60}})
+	check("dnd, mentioned", true, false)
+}
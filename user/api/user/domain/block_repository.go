@@ -0,0 +1,14 @@
+package domain
+
+import "context"
+
+// BlockRepository 拉黑关系仓储
+type BlockRepository interface {
+	// Block 幂等：重复拉黑不报错
+	Block(ctx context.Context, blockerID, blockedID uint) error
+	// Unblock 幂等：未拉黑时不报错
+	Unblock(ctx context.Context, blockerID, blockedID uint) error
+	ListBlocked(ctx context.Context, blockerID uint) ([]Block, error)
+	// BlockersOf 返回 blockerIDs 中拉黑了 blockedID 的用户
+	BlockersOf(ctx context.Context, blockedID uint, blockerIDs []uint) ([]uint, error)
+}
@@ -1,8 +1,41 @@
 package domain
 
+import "time"
+
 // User 领域实体（不包含 ORM 细节）
 type User struct {
 	ID           uint
 	Username     string
 	PasswordHash string
+	// Email 可选，用于接收密码重置邮件
+	Email       string
+	LastLoginAt time.Time
+	LastLoginIP string
+}
+
+// Profile 用户资料，对其他用户可见
+type Profile struct {
+	UserID      uint
+	Username    string
+	DisplayName string
+	// Avatar 头像引用（媒体 ID 或 URL），不存二进制
+	Avatar     string
+	Bio        string
+	StatusText string
+	UpdatedAt  time.Time
+}
+
+// ProfilePatch 资料局部更新，nil 表示不修改
+type ProfilePatch struct {
+	DisplayName *string
+	Avatar      *string
+	Bio         *string
+	StatusText  *string
+}
+
+// Block 拉黑关系：BlockerID 拉黑了 BlockedID
+type Block struct {
+	BlockerID uint
+	BlockedID uint
+	CreatedAt time.Time
 }
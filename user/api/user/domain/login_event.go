@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// LoginResult 一次登录尝试的结果
+type LoginResult string
+
+const (
+	LoginSucceeded LoginResult = "success"
+	LoginFailed    LoginResult = "failed"
+	// LoginLocked 账号处于锁定期，未校验密码
+	LoginLocked LoginResult = "locked"
+	// LoginThrottled 失败过多需等待，未校验密码
+	LoginThrottled LoginResult = "throttled"
+	// ResetRequested 请求密码重置邮件，与登录共用审计和限流窗口
+	ResetRequested LoginResult = "reset_requested"
+)
+
+// LoginEvent 登录记录，成功与失败都记；UserID 为 0 表示用户名不存在
+// （重置请求不查账号，UserID 总是 0）。
+// Device 为客户端自报的设备名
+type LoginEvent struct {
+	ID        uint
+	UserID    uint
+	Username  string
+	IP        string
+	UserAgent string
+	Device    string
+	Result    LoginResult
+	CreatedAt time.Time
+}
+
+// LoginEventFilter 查询审计记录，空字段不过滤
+type LoginEventFilter struct {
+	UserID   uint
+	Username string
+	IP       string
+	Limit    int
+}
+
+type LoginEventRepository interface {
+	Record(ctx context.Context, e *LoginEvent) error
+	// FailuresByUsername/FailuresByIP since 之后的失败次数及最近一次失败时间
+	FailuresByUsername(ctx context.Context, username string, since time.Time) (int, time.Time, error)
+	FailuresByIP(ctx context.Context, ip string, since time.Time) (int, time.Time, error)
+	// ResetRequestsByUsername/ResetRequestsByIP since 之后的密码重置请求次数及最近一次请求时间
+	ResetRequestsByUsername(ctx context.Context, username string, since time.Time) (int, time.Time, error)
+	ResetRequestsByIP(ctx context.Context, ip string, since time.Time) (int, time.Time, error)
+	// List 按时间倒序
+	List(ctx context.Context, f LoginEventFilter) ([]LoginEvent, error)
+}
+
+// Lockout 账号锁定状态，按用户名记录（不存在的用户名同样会被锁，避免暴露账号是否存在）。
+// ResetAt 之前的失败不再计数：登录成功或管理员解锁时更新
+type Lockout struct {
+	Username    string
+	LockedUntil time.Time
+	ResetAt     time.Time
+}
+
+type LockoutRepository interface {
+	// Get 没有记录时返回零值
+	Get(ctx context.Context, username string) (*Lockout, error)
+	Lock(ctx context.Context, username string, until time.Time) error
+	// Reset 解除锁定并从 at 起重新计数
+	Reset(ctx context.Context, username string, at time.Time) error
+}
@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrResetTokenInvalid 重置令牌不存在、已过期或已使用
+var ErrResetTokenInvalid = errors.New("password reset token invalid")
+
+// PasswordReset 一次性的密码重置令牌，库里只存哈希
+type PasswordReset struct {
+	ID        uint
+	UserID    uint
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    time.Time
+}
+
+type PasswordResetRepository interface {
+	Create(ctx context.Context, r *PasswordReset) error
+	// FindByHash 不存在时返回 ErrResetTokenInvalid
+	FindByHash(ctx context.Context, hash string) (*PasswordReset, error)
+	// MarkUsed 仅当未使用时标记，返回是否标记成功（并发提交时只有一个成功）
+	MarkUsed(ctx context.Context, id uint, at time.Time) (bool, error)
+	// InvalidateUser 作废用户所有未使用的重置令牌
+	InvalidateUser(ctx context.Context, userID uint, at time.Time) error
+}
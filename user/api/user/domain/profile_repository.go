@@ -0,0 +1,20 @@
+package domain
+
+import "context"
+
+// ProfileRepository 用户资料仓储
+type ProfileRepository interface {
+	GetProfile(ctx context.Context, userID uint) (*Profile, error)
+	// GetProfiles 批量查询，不存在的 ID 直接忽略
+	GetProfiles(ctx context.Context, userIDs []uint) ([]Profile, error)
+	UpdateProfile(ctx context.Context, userID uint, patch ProfilePatch) (*Profile, error)
+}
+
+// ContactRepository 联系人关系（单向：OwnerID 把 ContactID 加为联系人）
+type ContactRepository interface {
+	AddContact(ctx context.Context, ownerID, contactID uint) error
+	RemoveContact(ctx context.Context, ownerID, contactID uint) error
+	ListContacts(ctx context.Context, ownerID uint) ([]uint, error)
+	// ListWatchers 返回把 userID 加为联系人的用户，用于推送资料/状态变更
+	ListWatchers(ctx context.Context, userID uint) ([]uint, error)
+}
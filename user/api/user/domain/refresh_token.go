@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrRefreshTokenInvalid 刷新令牌不存在、已过期或已吊销
+	ErrRefreshTokenInvalid = errors.New("refresh token invalid")
+	// ErrRefreshTokenReused 已轮换过的刷新令牌被再次使用，视为泄露，整个令牌族已吊销
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// RefreshToken 刷新令牌，库里只存哈希。一次登录签发的令牌及其轮换出的后继属于同一族（FamilyID），
+// 每个令牌只能用一次：用过即记 UsedAt 并签发后继
+type RefreshToken struct {
+	ID        uint
+	UserID    uint
+	FamilyID  string
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    time.Time
+	RevokedAt time.Time
+}
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, t *RefreshToken) error
+	// FindByHash 不存在时返回 ErrRefreshTokenInvalid
+	FindByHash(ctx context.Context, hash string) (*RefreshToken, error)
+	// MarkUsed 仅当令牌未用过且未吊销时标记为已用，返回是否标记成功（并发刷新时只有一个成功）
+	MarkUsed(ctx context.Context, id uint, at time.Time) (bool, error)
+	// RevokeFamily 吊销整族仍有效的令牌
+	RevokeFamily(ctx context.Context, familyID string, at time.Time) error
+	// RevokeUser 吊销用户全部仍有效的令牌
+	RevokeUser(ctx context.Context, userID uint, at time.Time) error
+}
@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrTokenInvalid 访问令牌格式、签名或有效期不对
+	ErrTokenInvalid = errors.New("token invalid")
+	// ErrTokenRevoked 访问令牌已注销，或签发后用户的全部会话被吊销
+	ErrTokenRevoked = errors.New("token revoked")
+)
+
+// TokenClaims 访问令牌携带的身份。ID 即 jti，用于单个令牌的注销；
+// Epoch 为签发时用户的令牌纪元，吊销全部会话时纪元加一，旧纪元的令牌一律失效
+type TokenClaims struct {
+	ID        string
+	UserID    uint
+	Username  string
+	Epoch     int64
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// RevocationRepository 访问令牌的吊销状态
+type RevocationRepository interface {
+	// Epoch 用户当前令牌纪元，从未吊销过为 0
+	Epoch(ctx context.Context, userID uint) (int64, error)
+	// BumpEpoch 纪元加一并返回新值
+	BumpEpoch(ctx context.Context, userID uint) (int64, error)
+	// RevokeToken 记录注销的 jti；过期后记录可以清理
+	RevokeToken(ctx context.Context, tokenID string, userID uint, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+}
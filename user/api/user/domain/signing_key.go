@@ -0,0 +1,16 @@
+package domain
+
+import (
+	"crypto"
+	"errors"
+)
+
+// ErrNoSigningKey 没有配置签名私钥，用户服务拒绝启动
+var ErrNoSigningKey = errors.New("no token signing key configured")
+
+// PublicKey 令牌验签公钥，ID 即令牌头里的 kid；Algorithm 为 EdDSA 或 RS256
+type PublicKey struct {
+	ID        string
+	Algorithm string
+	Key       crypto.PublicKey
+}
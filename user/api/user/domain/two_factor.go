@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrTwoFactorNotEnabled 账号未开启两步验证（或尚未用首个验证码确认）
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication not enabled")
+	// ErrTwoFactorEnabled 已开启，需先关闭才能重新绑定
+	ErrTwoFactorEnabled = errors.New("two-factor authentication already enabled")
+	// ErrTwoFactorCodeInvalid 验证码或恢复码错误
+	ErrTwoFactorCodeInvalid = errors.New("invalid two-factor code")
+	// ErrChallengeInvalid 登录挑战不存在、已过期、已使用或尝试次数用尽
+	ErrChallengeInvalid = errors.New("login challenge invalid")
+)
+
+// TwoFactor 用户的 TOTP 配置。EnabledAt 为零表示密钥已生成、尚未确认
+type TwoFactor struct {
+	UserID uint
+	// Secret base32 编码的共享密钥，验证时需要原文，不能只存哈希
+	Secret string
+	// LastStep 最近一次通过验证的时间步，同一验证码不能重复使用
+	LastStep  int64
+	CreatedAt time.Time
+	EnabledAt time.Time
+}
+
+func (t *TwoFactor) Enabled() bool { return !t.EnabledAt.IsZero() }
+
+type TwoFactorRepository interface {
+	// Get 没有记录时返回 ErrTwoFactorNotEnabled
+	Get(ctx context.Context, userID uint) (*TwoFactor, error)
+	// SavePending 写入待确认的密钥，覆盖之前未确认的；已开启时返回 ErrTwoFactorEnabled
+	SavePending(ctx context.Context, t *TwoFactor) error
+	// Enable 确认开启并写入恢复码（只存哈希）
+	Enable(ctx context.Context, userID uint, at time.Time, recoveryHashes []string) error
+	// Disable 删除密钥与恢复码
+	Disable(ctx context.Context, userID uint) error
+	// UseStep 仅当 step 大于上次使用的时间步时记录，返回是否记录成功（并发提交时只有一个成功）
+	UseStep(ctx context.Context, userID uint, step int64) (bool, error)
+	// ReplaceRecoveryCodes 旧恢复码全部作废，换成新的
+	ReplaceRecoveryCodes(ctx context.Context, userID uint, recoveryHashes []string) error
+	// UseRecoveryCode 仅当恢复码存在且未使用时标记，返回是否成功
+	UseRecoveryCode(ctx context.Context, userID uint, hash string, at time.Time) (bool, error)
+	// RecoveryCodesLeft 未使用的恢复码数量
+	RecoveryCodesLeft(ctx context.Context, userID uint) (int, error)
+}
+
+// LoginChallenge 密码校验通过、等待第二步验证的登录，库里只存令牌哈希
+type LoginChallenge struct {
+	ID        uint
+	UserID    uint
+	TokenHash string
+	Attempts  int
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    time.Time
+}
+
+type LoginChallengeRepository interface {
+	Create(ctx context.Context, c *LoginChallenge) error
+	// FindByHash 不存在时返回 ErrChallengeInvalid
+	FindByHash(ctx context.Context, hash string) (*LoginChallenge, error)
+	// Fail 记一次验证失败
+	Fail(ctx context.Context, id uint) error
+	// MarkUsed 仅当未使用时标记，返回是否标记成功
+	MarkUsed(ctx context.Context, id uint, at time.Time) (bool, error)
+}
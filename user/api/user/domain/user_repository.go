@@ -1,11 +1,16 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // UserRepository 仓储接口：领域层只定义“需要什么”，不关心“怎么存”
 type UserRepository interface {
 	Create(ctx context.Context, u *User) error
 	FindByUsername(ctx context.Context, username string) (*User, error)
+	FindByID(ctx context.Context, id uint) (*User, error)
+	UpdatePassword(ctx context.Context, id uint, passwordHash string) error
+	// RecordLogin 更新最近登录时间与 IP
+	RecordLogin(ctx context.Context, id uint, at time.Time, ip string) error
 }
-
-
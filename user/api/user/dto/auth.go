@@ -3,21 +3,49 @@ package dto
 type LoginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	// Device 客户端自报的设备名，出现在登录记录里
+	Device string `json:"device"`
 }
 
+// LoginResponse 开启两步验证的账号只返回 challenge，令牌在 /user/login/2fa 之后签发
 type LoginResponse struct {
-	ID    uint   `json:"id"`
-	Token string `json:"token"`
+	ID           uint   `json:"id"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// TwoFactorRequired 为 true 时带 challenge 及其过期时间（Unix 秒）
+	TwoFactorRequired  bool   `json:"two_factor_required,omitempty"`
+	Challenge          string `json:"challenge,omitempty"`
+	ChallengeExpiresAt int64  `json:"challenge_expires_at,omitempty"`
 }
 
 type RegisterRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	// Email 可选，用于找回密码
+	Email  string `json:"email"`
+	Device string `json:"device"`
 }
 
 type RegisterResponse struct {
-	ID    uint   `json:"id"`
-	Token string `json:"token"`
+	ID           uint   `json:"id"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
 
+type ForgotPasswordRequest struct {
+	Username string `json:"username"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
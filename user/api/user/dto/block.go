@@ -0,0 +1,16 @@
+package dto
+
+import "time"
+
+type BlockRequest struct {
+	UserID uint `json:"user_id"`
+}
+
+type BlockedUser struct {
+	UserID    uint      `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type BlockListResponse struct {
+	Users []BlockedUser `json:"users"`
+}
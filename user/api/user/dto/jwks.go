@@ -0,0 +1,66 @@
+package dto
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+
+	"wsim/user/api/user/domain"
+)
+
+// JWK 公钥的 JSON Web Key 表示（RFC 7517），只用到 OKP(Ed25519) 与 RSA 两类
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+var errUnsupportedKey = errors.New("unsupported jwk")
+
+// FromPublicKey 公钥转 JWK；不支持的类型返回错误
+func FromPublicKey(k domain.PublicKey) (JWK, error) {
+	b64 := base64.RawURLEncoding.EncodeToString
+	switch pub := k.Key.(type) {
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Kid: k.ID, Use: "sig", Alg: k.Algorithm, Crv: "Ed25519", X: b64(pub)}, nil
+	case *rsa.PublicKey:
+		return JWK{Kty: "RSA", Kid: k.ID, Use: "sig", Alg: k.Algorithm, N: b64(pub.N.Bytes()), E: b64(big.NewInt(int64(pub.E)).Bytes())}, nil
+	}
+	return JWK{}, errUnsupportedKey
+}
+
+// PublicKey JWK 转回公钥，供只持有公钥的验签方使用
+func (j JWK) PublicKey() (domain.PublicKey, error) {
+	dec := base64.RawURLEncoding.DecodeString
+	switch {
+	case j.Kty == "OKP" && j.Crv == "Ed25519":
+		x, err := dec(j.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return domain.PublicKey{}, errUnsupportedKey
+		}
+		return domain.PublicKey{ID: j.Kid, Algorithm: "EdDSA", Key: ed25519.PublicKey(x)}, nil
+	case j.Kty == "RSA":
+		n, err := dec(j.N)
+		if err != nil {
+			return domain.PublicKey{}, errUnsupportedKey
+		}
+		e, err := dec(j.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return domain.PublicKey{}, errUnsupportedKey
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		return domain.PublicKey{ID: j.Kid, Algorithm: "RS256", Key: pub}, nil
+	}
+	return domain.PublicKey{}, errUnsupportedKey
+}
@@ -0,0 +1,35 @@
+package dto
+
+import (
+	"time"
+
+	"wsim/user/api/user/domain"
+)
+
+type LoginEvent struct {
+	ID        uint      `json:"id"`
+	UserID    uint      `json:"user_id"`
+	Username  string    `json:"username"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Device    string    `json:"device"`
+	Result    string    `json:"result"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type LoginEventListResponse struct {
+	Events []LoginEvent `json:"events"`
+}
+
+func FromLoginEvent(e *domain.LoginEvent) LoginEvent {
+	return LoginEvent{
+		ID:        e.ID,
+		UserID:    e.UserID,
+		Username:  e.Username,
+		IP:        e.IP,
+		UserAgent: e.UserAgent,
+		Device:    e.Device,
+		Result:    string(e.Result),
+		CreatedAt: e.CreatedAt,
+	}
+}
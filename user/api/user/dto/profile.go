@@ -0,0 +1,37 @@
+package dto
+
+import "time"
+
+type Profile struct {
+	UserID      uint      `json:"user_id"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name"`
+	Avatar      string    `json:"avatar"`
+	Bio         string    `json:"bio"`
+	StatusText  string    `json:"status_text"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// UpdateProfileRequest PATCH 语义：未出现的字段不修改
+type UpdateProfileRequest struct {
+	DisplayName *string `json:"display_name"`
+	Avatar      *string `json:"avatar"`
+	Bio         *string `json:"bio"`
+	StatusText  *string `json:"status_text"`
+}
+
+type BatchProfilesRequest struct {
+	UserIDs []uint `json:"user_ids"`
+}
+
+type BatchProfilesResponse struct {
+	Profiles []Profile `json:"profiles"`
+}
+
+type ContactRequest struct {
+	UserID uint `json:"user_id"`
+}
+
+type ContactListResponse struct {
+	UserIDs []uint `json:"user_ids"`
+}
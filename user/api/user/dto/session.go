@@ -0,0 +1,14 @@
+package dto
+
+// LogoutRequest 可选带上刷新令牌，一并吊销
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// SessionRevoked 会话吊销事件，经 pubsub 发给网关：
+// TokenID 非空时只断开该令牌的连接，否则断开令牌纪元小于 Epoch 的全部连接
+type SessionRevoked struct {
+	UserID  uint   `json:"user_id"`
+	TokenID string `json:"token_id,omitempty"`
+	Epoch   int64  `json:"epoch,omitempty"`
+}
@@ -0,0 +1,34 @@
+package dto
+
+type TwoFactorStatusResponse struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// TwoFactorSetupResponse uri 用于生成二维码，secret 供手动输入
+type TwoFactorSetupResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password"`
+	// Code 验证码或恢复码
+	Code string `json:"code"`
+}
+
+// RecoveryCodesResponse 恢复码只在生成时返回一次
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// CompleteLoginRequest 两步登录的第二步；code 可以是验证码或恢复码
+type CompleteLoginRequest struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code"`
+	Device    string `json:"device"`
+}
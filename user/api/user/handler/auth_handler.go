@@ -3,8 +3,11 @@ package handler
 import (
 	"context"
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
+	"wsim/user/api/identity"
 	"wsim/user/api/user/domain"
 	"wsim/user/api/user/dto"
 	"wsim/user/api/user/usecase"
@@ -27,14 +30,12 @@ func (h *AuthHandler) Register(ctx context.Context, c *app.RequestContext) {
 		c.JSON(http.StatusBadRequest, utils.H{"error": err.Error()})
 		return
 	}
-	ip := c.ClientIP()
-	ctx = context.WithValue(ctx, "x-forwarded-for", ip)
-	res, err := h.auth.Register(ctx, req.Username, req.Password)
+	res, err := h.auth.Register(ctx, req.Username, req.Password, req.Email, clientInfo(c, req.Device))
 	if err != nil {
-		h.writeErr(c, err)
+		writeErr(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, dto.RegisterResponse{ID: res.UserID, Token: res.Token})
+	c.JSON(http.StatusOK, dto.RegisterResponse{ID: res.UserID, Token: res.Token, RefreshToken: res.RefreshToken})
 }
 
 func (h *AuthHandler) Login(ctx context.Context, c *app.RequestContext) {
@@ -43,22 +44,228 @@ func (h *AuthHandler) Login(ctx context.Context, c *app.RequestContext) {
 		c.JSON(http.StatusBadRequest, utils.H{"error": err.Error()})
 		return
 	}
-	res, err := h.auth.Login(ctx, req.Username, req.Password)
+	res, err := h.auth.Login(ctx, req.Username, req.Password, clientInfo(c, req.Device))
 	if err != nil {
-		h.writeErr(c, err)
+		writeErr(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, dto.LoginResponse{ID: res.UserID, Token: res.Token})
+	c.JSON(http.StatusOK, toLoginResponse(res))
 }
 
-func (h *AuthHandler) writeErr(c *app.RequestContext, err error) {
+// Refresh POST {refresh_token}：轮换刷新令牌并签发新的访问令牌
+func (h *AuthHandler) Refresh(ctx context.Context, c *app.RequestContext) {
+	var req dto.RefreshRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{"error": err.Error()})
+		return
+	}
+	res, err := h.auth.Refresh(ctx, req.RefreshToken)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.LoginResponse{ID: res.UserID, Token: res.Token, RefreshToken: res.RefreshToken})
+}
+
+// Logout POST {refresh_token?}：注销本次请求所用的访问令牌
+func (h *AuthHandler) Logout(ctx context.Context, c *app.RequestContext) {
+	var req dto.LogoutRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{"error": err.Error()})
+		return
+	}
+	claims, err := identity.Claims(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	if err := h.auth.Logout(ctx, claims, req.RefreshToken); err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ChangePassword POST {old_password, new_password}：其他会话全部失效，返回当前客户端的新令牌
+func (h *AuthHandler) ChangePassword(ctx context.Context, c *app.RequestContext) {
+	uid, err := currentUserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	var req dto.ChangePasswordRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{"error": err.Error()})
+		return
+	}
+	res, err := h.auth.ChangePassword(ctx, uid, req.OldPassword, req.NewPassword, clientInfo(c, ""))
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.LoginResponse{ID: res.UserID, Token: res.Token, RefreshToken: res.RefreshToken})
+}
+
+// ForgotPassword POST {username}：无论账号是否存在都返回 202；同一用户名或 IP 请求过多返回 429
+func (h *AuthHandler) ForgotPassword(ctx context.Context, c *app.RequestContext) {
+	var req dto.ForgotPasswordRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{"error": err.Error()})
+		return
+	}
+	if err := h.auth.RequestPasswordReset(ctx, req.Username, clientInfo(c, "")); err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+// ResetPassword POST {token, new_password}
+func (h *AuthHandler) ResetPassword(ctx context.Context, c *app.RequestContext) {
+	var req dto.ResetPasswordRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{"error": err.Error()})
+		return
+	}
+	if err := h.auth.ResetPassword(ctx, req.Token, req.NewPassword); err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeSessions 管理员吊销某用户的全部会话
+func (h *AuthHandler) RevokeSessions(ctx context.Context, c *app.RequestContext) {
+	if _, err := identity.AdminID(c); err != nil {
+		writeErr(c, err)
+		return
+	}
+	uid, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil || uid == 0 {
+		writeErr(c, usecase.ErrBadRequest)
+		return
+	}
+	if err := h.auth.RevokeAll(ctx, uint(uid)); err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// LoginHistory GET ?limit= 当前用户最近的登录记录
+func (h *AuthHandler) LoginHistory(ctx context.Context, c *app.RequestContext) {
+	uid, err := currentUserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	events, err := h.auth.LoginHistory(ctx, uid, limit)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toLoginEventList(events))
+}
+
+// Unlock 管理员解除账号的登录锁定
+func (h *AuthHandler) Unlock(ctx context.Context, c *app.RequestContext) {
+	if _, err := identity.AdminID(c); err != nil {
+		writeErr(c, err)
+		return
+	}
+	uid, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil || uid == 0 {
+		writeErr(c, usecase.ErrBadRequest)
+		return
+	}
+	if err := h.auth.Unlock(ctx, uint(uid)); err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// LoginEvents GET ?user_id=&username=&ip=&limit= 管理员查看登录审计
+func (h *AuthHandler) LoginEvents(ctx context.Context, c *app.RequestContext) {
+	if _, err := identity.AdminID(c); err != nil {
+		writeErr(c, err)
+		return
+	}
+	uid, _ := strconv.ParseUint(c.Query("user_id"), 10, 64)
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	events, err := h.auth.LoginEvents(ctx, domain.LoginEventFilter{
+		UserID:   uint(uid),
+		Username: c.Query("username"),
+		IP:       c.Query("ip"),
+		Limit:    limit,
+	})
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toLoginEventList(events))
+}
+
+func toLoginResponse(res *usecase.AuthResult) dto.LoginResponse {
+	if res.Challenge != "" {
+		return dto.LoginResponse{
+			ID:                 res.UserID,
+			TwoFactorRequired:  true,
+			Challenge:          res.Challenge,
+			ChallengeExpiresAt: res.ChallengeExpiresAt.Unix(),
+		}
+	}
+	return dto.LoginResponse{ID: res.UserID, Token: res.Token, RefreshToken: res.RefreshToken}
+}
+
+func toLoginEventList(events []domain.LoginEvent) dto.LoginEventListResponse {
+	res := dto.LoginEventListResponse{Events: make([]dto.LoginEvent, 0, len(events))}
+	for i := range events {
+		res.Events = append(res.Events, dto.FromLoginEvent(&events[i]))
+	}
+	return res
+}
+
+func clientInfo(c *app.RequestContext, device string) usecase.ClientInfo {
+	return usecase.ClientInfo{IP: c.ClientIP(), UserAgent: string(c.UserAgent()), Device: device}
+}
+
+// writeErr 把领域/应用层错误映射为 HTTP 状态码，各 handler 共用
+func writeErr(c *app.RequestContext, err error) {
+	var retry *usecase.RetryError
 	switch {
+	case errors.As(err, &retry):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retry.RetryAfter.Seconds()))))
+		msg := "too many login attempts"
+		if errors.Is(err, usecase.ErrAccountLocked) {
+			msg = "account temporarily locked"
+		} else if errors.Is(err, usecase.ErrResetThrottled) {
+			msg = "too many password reset requests"
+		}
+		c.JSON(http.StatusTooManyRequests, utils.H{"error": msg})
 	case errors.Is(err, usecase.ErrBadRequest):
 		c.JSON(http.StatusBadRequest, utils.H{"error": "bad request"})
+	case errors.Is(err, ErrUnauthorized), errors.Is(err, domain.ErrTokenInvalid), errors.Is(err, domain.ErrTokenRevoked):
+		c.JSON(http.StatusUnauthorized, utils.H{"error": "unauthorized"})
+	case errors.Is(err, identity.ErrForbidden):
+		c.JSON(http.StatusForbidden, utils.H{"error": "forbidden"})
+	case errors.Is(err, domain.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, utils.H{"error": "user not found"})
 	case errors.Is(err, domain.ErrUserAlreadyExists):
 		c.JSON(http.StatusConflict, utils.H{"error": "user already exists"})
 	case errors.Is(err, usecase.ErrInvalidCredentials):
 		c.JSON(http.StatusUnauthorized, utils.H{"error": "invalid credentials"})
+	case errors.Is(err, domain.ErrRefreshTokenInvalid), errors.Is(err, domain.ErrRefreshTokenReused):
+		c.JSON(http.StatusUnauthorized, utils.H{"error": "invalid refresh token"})
+	case errors.Is(err, domain.ErrResetTokenInvalid):
+		c.JSON(http.StatusBadRequest, utils.H{"error": "invalid reset token"})
+	case errors.Is(err, domain.ErrTwoFactorCodeInvalid):
+		c.JSON(http.StatusUnauthorized, utils.H{"error": "invalid two-factor code"})
+	case errors.Is(err, domain.ErrChallengeInvalid):
+		c.JSON(http.StatusUnauthorized, utils.H{"error": "invalid login challenge"})
+	case errors.Is(err, domain.ErrTwoFactorNotEnabled), errors.Is(err, domain.ErrTwoFactorEnabled):
+		c.JSON(http.StatusConflict, utils.H{"error": err.Error()})
 	default:
 		c.JSON(http.StatusInternalServerError, utils.H{"error": err.Error()})
 	}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"wsim/user/api/user/dto"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+)
+
+// CompleteLogin POST {challenge, code, device?}：两步登录的第二步
+func (h *AuthHandler) CompleteLogin(ctx context.Context, c *app.RequestContext) {
+	var req dto.CompleteLoginRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{"error": err.Error()})
+		return
+	}
+	res, err := h.auth.CompleteLogin(ctx, req.Challenge, req.Code, clientInfo(c, req.Device))
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toLoginResponse(res))
+}
+
+// TwoFactorStatus GET 当前用户的两步验证状态
+func (h *AuthHandler) TwoFactorStatus(ctx context.Context, c *app.RequestContext) {
+	uid, err := currentUserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	st, err := h.auth.TwoFactorStatus(ctx, uid)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.TwoFactorStatusResponse{Enabled: st.Enabled, RecoveryCodesLeft: st.RecoveryCodesLeft})
+}
+
+// SetupTwoFactor POST 生成待确认的 TOTP 密钥
+func (h *AuthHandler) SetupTwoFactor(ctx context.Context, c *app.RequestContext) {
+	uid, err := currentUserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	setup, err := h.auth.BeginTwoFactor(ctx, uid)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.TwoFactorSetupResponse{Secret: setup.Secret, URI: setup.URI})
+}
+
+// EnableTwoFactor POST {code}：确认绑定，返回恢复码
+func (h *AuthHandler) EnableTwoFactor(ctx context.Context, c *app.RequestContext) {
+	uid, err := currentUserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	var req dto.TwoFactorCodeRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{"error": err.Error()})
+		return
+	}
+	codes, err := h.auth.EnableTwoFactor(ctx, uid, req.Code)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTwoFactor POST {password, code}
+func (h *AuthHandler) DisableTwoFactor(ctx context.Context, c *app.RequestContext) {
+	uid, err := currentUserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	var req dto.DisableTwoFactorRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{"error": err.Error()})
+		return
+	}
+	if err := h.auth.DisableTwoFactor(ctx, uid, req.Password, req.Code, clientInfo(c, "")); err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes POST {code}：旧恢复码作废，返回新的一组
+func (h *AuthHandler) RegenerateRecoveryCodes(ctx context.Context, c *app.RequestContext) {
+	uid, err := currentUserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	var req dto.TwoFactorCodeRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{"error": err.Error()})
+		return
+	}
+	codes, err := h.auth.RegenerateRecoveryCodes(ctx, uid, req.Code, clientInfo(c, ""))
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"wsim/user/api/user/dto"
+	"wsim/user/api/user/usecase"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+)
+
+type BlockHandler struct {
+	blocks *usecase.BlockService
+}
+
+func NewBlockHandler(blocks *usecase.BlockService) *BlockHandler {
+	return &BlockHandler{blocks: blocks}
+}
+
+func (h *BlockHandler) Block(ctx context.Context, c *app.RequestContext) {
+	uid, err := currentUserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	var req dto.BlockRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{"error": err.Error()})
+		return
+	}
+	if err := h.blocks.Block(ctx, uid, req.UserID); err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.H{})
+}
+
+func (h *BlockHandler) Unblock(ctx context.Context, c *app.RequestContext) {
+	uid, err := currentUserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	target, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		writeErr(c, usecase.ErrBadRequest)
+		return
+	}
+	if err := h.blocks.Unblock(ctx, uid, uint(target)); err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.H{})
+}
+
+func (h *BlockHandler) List(ctx context.Context, c *app.RequestContext) {
+	uid, err := currentUserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	bs, err := h.blocks.List(ctx, uid)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	res := dto.BlockListResponse{Users: make([]dto.BlockedUser, 0, len(bs))}
+	for _, b := range bs {
+		res.Users = append(res.Users, dto.BlockedUser{UserID: b.BlockedID, CreatedAt: b.CreatedAt})
+	}
+	c.JSON(http.StatusOK, res)
+}
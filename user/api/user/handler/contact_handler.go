@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"wsim/user/api/user/dto"
+	"wsim/user/api/user/usecase"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+)
+
+type ContactHandler struct {
+	contacts *usecase.ContactService
+}
+
+func NewContactHandler(contacts *usecase.ContactService) *ContactHandler {
+	return &ContactHandler{contacts: contacts}
+}
+
+func (h *ContactHandler) Add(ctx context.Context, c *app.RequestContext) {
+	uid, err := currentUserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	var req dto.ContactRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{"error": err.Error()})
+		return
+	}
+	if err := h.contacts.Add(ctx, uid, req.UserID); err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.H{})
+}
+
+func (h *ContactHandler) Remove(ctx context.Context, c *app.RequestContext) {
+	uid, err := currentUserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	target, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		writeErr(c, usecase.ErrBadRequest)
+		return
+	}
+	if err := h.contacts.Remove(ctx, uid, uint(target)); err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, utils.H{})
+}
+
+func (h *ContactHandler) List(ctx context.Context, c *app.RequestContext) {
+	uid, err := currentUserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	ids, err := h.contacts.List(ctx, uid)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	if ids == nil {
+		ids = []uint{}
+	}
+	c.JSON(http.StatusOK, dto.ContactListResponse{UserIDs: ids})
+}
@@ -0,0 +1,14 @@
+package handler
+
+import (
+	"wsim/user/api/identity"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// ErrUnauthorized 请求未携带有效的调用方身份
+var ErrUnauthorized = identity.ErrUnauthorized
+
+func currentUserID(c *app.RequestContext) (uint, error) {
+	return identity.UserID(c)
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"wsim/user/api/user/dto"
+	"wsim/user/api/user/usecase"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// KeyHandler 发布访问令牌的验签公钥
+type KeyHandler struct {
+	keys usecase.KeyPublisher
+}
+
+func NewKeyHandler(keys usecase.KeyPublisher) *KeyHandler {
+	return &KeyHandler{keys: keys}
+}
+
+// JWKS GET /.well-known/jwks.json
+func (h *KeyHandler) JWKS(ctx context.Context, c *app.RequestContext) {
+	res := dto.JWKS{Keys: []dto.JWK{}}
+	for _, k := range h.keys.PublicKeys() {
+		j, err := dto.FromPublicKey(k)
+		if err != nil {
+			writeErr(c, err)
+			return
+		}
+		res.Keys = append(res.Keys, j)
+	}
+	// 轮换时新公钥须尽快可见，缓存时间不宜过长
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, res)
+}
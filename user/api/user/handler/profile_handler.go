@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"wsim/user/api/user/domain"
+	"wsim/user/api/user/dto"
+	"wsim/user/api/user/usecase"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+)
+
+type ProfileHandler struct {
+	profiles *usecase.ProfileService
+}
+
+func NewProfileHandler(profiles *usecase.ProfileService) *ProfileHandler {
+	return &ProfileHandler{profiles: profiles}
+}
+
+// Me 当前用户资料
+func (h *ProfileHandler) Me(ctx context.Context, c *app.RequestContext) {
+	uid, err := currentUserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	p, err := h.profiles.Get(ctx, uid)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toProfileDTO(p))
+}
+
+func (h *ProfileHandler) Get(ctx context.Context, c *app.RequestContext) {
+	id, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		writeErr(c, usecase.ErrBadRequest)
+		return
+	}
+	p, err := h.profiles.Get(ctx, uint(id))
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toProfileDTO(p))
+}
+
+func (h *ProfileHandler) Update(ctx context.Context, c *app.RequestContext) {
+	uid, err := currentUserID(c)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	var req dto.UpdateProfileRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{"error": err.Error()})
+		return
+	}
+	p, err := h.profiles.Update(ctx, uid, domain.ProfilePatch{
+		DisplayName: req.DisplayName,
+		Avatar:      req.Avatar,
+		Bio:         req.Bio,
+		StatusText:  req.StatusText,
+	})
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toProfileDTO(p))
+}
+
+// Batch 按 ID 批量查询，用于渲染会话列表
+func (h *ProfileHandler) Batch(ctx context.Context, c *app.RequestContext) {
+	var req dto.BatchProfilesRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.H{"error": err.Error()})
+		return
+	}
+	ps, err := h.profiles.BatchGet(ctx, req.UserIDs)
+	if err != nil {
+		writeErr(c, err)
+		return
+	}
+	res := dto.BatchProfilesResponse{Profiles: make([]dto.Profile, 0, len(ps))}
+	for i := range ps {
+		res.Profiles = append(res.Profiles, toProfileDTO(&ps[i]))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func toProfileDTO(p *domain.Profile) dto.Profile {
+	return dto.Profile{
+		UserID:      p.UserID,
+		Username:    p.Username,
+		DisplayName: p.DisplayName,
+		Avatar:      p.Avatar,
+		Bio:         p.Bio,
+		StatusText:  p.StatusText,
+		UpdatedAt:   p.UpdatedAt,
+	}
+}
@@ -0,0 +1,50 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"wsim/pkg/pubsub"
+	"wsim/user/api/user/domain"
+	"wsim/user/api/user/dto"
+
+	"gorm.io/gorm"
+)
+
+// PgNotifier 通过 PostgreSQL NOTIFY 把用户侧数据变更广播给网关
+type PgNotifier struct {
+	db *gorm.DB
+}
+
+func NewPgNotifier(db *gorm.DB) *PgNotifier {
+	return &PgNotifier{db: db}
+}
+
+func (n *PgNotifier) BlockListChanged(ctx context.Context, userID uint) error {
+	return pubsub.Publish(ctx, n.db, pubsub.ChannelBlockList, strconv.FormatUint(uint64(userID), 10))
+}
+
+func (n *PgNotifier) ProfileChanged(ctx context.Context, p *domain.Profile) error {
+	data, err := json.Marshal(dto.Profile{
+		UserID:      p.UserID,
+		Username:    p.Username,
+		DisplayName: p.DisplayName,
+		Avatar:      p.Avatar,
+		Bio:         p.Bio,
+		StatusText:  p.StatusText,
+		UpdatedAt:   p.UpdatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	return pubsub.Publish(ctx, n.db, pubsub.ChannelProfile, string(data))
+}
+
+func (n *PgNotifier) SessionsRevoked(ctx context.Context, userID uint, tokenID string, epoch int64) error {
+	data, err := json.Marshal(dto.SessionRevoked{UserID: userID, TokenID: tokenID, Epoch: epoch})
+	if err != nil {
+		return err
+	}
+	return pubsub.Publish(ctx, n.db, pubsub.ChannelSessions, string(data))
+}
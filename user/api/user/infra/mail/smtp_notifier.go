@@ -0,0 +1,132 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"wsim/user/api/user/domain"
+)
+
+// ErrNotConfigured 未配置 SMTP_ADDR，无法发送邮件
+var ErrNotConfigured = errors.New("mail: smtp not configured")
+
+const (
+	dialTimeout = 10 * time.Second
+	sendTimeout = 30 * time.Second
+)
+
+// SMTPNotifier 通过 SMTP 发送密码重置邮件。服务器支持 STARTTLS 时自动启用；
+// 配置了用户名时用 PLAIN 认证（net/smtp 只允许在 TLS 或本机连接上使用）
+type SMTPNotifier struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+	// ResetURL 重置页面地址，令牌以 token 查询参数附上；为空时邮件里直接给出令牌
+	ResetURL string
+}
+
+// NewFromEnv SMTP_ADDR（host:port）、SMTP_FROM、SMTP_USERNAME、SMTP_PASSWORD、PASSWORD_RESET_URL；
+// 未配置 SMTP_ADDR 时发送一律返回 ErrNotConfigured
+func NewFromEnv() (*SMTPNotifier, error) {
+	n := &SMTPNotifier{
+		Addr:     os.Getenv("SMTP_ADDR"),
+		From:     os.Getenv("SMTP_FROM"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		ResetURL: os.Getenv("PASSWORD_RESET_URL"),
+	}
+	if n.Addr != "" && n.From == "" {
+		return nil, errors.New("mail: SMTP_FROM is required")
+	}
+	return n, nil
+}
+
+func (n *SMTPNotifier) SendPasswordReset(ctx context.Context, u *domain.User, token string, expiresAt time.Time) error {
+	if n.Addr == "" {
+		return ErrNotConfigured
+	}
+	if u.Email == "" {
+		return errors.New("mail: user has no email")
+	}
+	var body strings.Builder
+	fmt.Fprintf(&body, "%s，你好：\r\n\r\n我们收到了重置账号密码的请求。", u.Username)
+	if n.ResetURL != "" {
+		fmt.Fprintf(&body, "请打开下面的链接设置新密码：\r\n\r\n%s\r\n", resetLink(n.ResetURL, token))
+	} else {
+		fmt.Fprintf(&body, "重置令牌：\r\n\r\n%s\r\n", token)
+	}
+	fmt.Fprintf(&body, "\r\n有效期至 %s，只能使用一次。如果不是你本人操作，请忽略这封邮件。\r\n",
+		expiresAt.UTC().Format("2006-01-02 15:04 MST"))
+	return n.send(ctx, u.Email, mime.BEncoding.Encode("UTF-8", "重置密码"), body.String())
+}
+
+func resetLink(base, token string) string {
+	sep := "?"
+	if strings.Contains(base, "?") {
+		sep = "&"
+	}
+	return base + sep + "token=" + url.QueryEscape(token)
+}
+
+func (n *SMTPNotifier) send(ctx context.Context, to, subject, body string) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\n", n.From, to, subject, time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\nContent-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(body)
+
+	d := net.Dialer{Timeout: dialTimeout}
+	conn, err := d.DialContext(ctx, "tcp", n.Addr)
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(sendTimeout)
+	if dl, ok := ctx.Deadline(); ok && dl.Before(deadline) {
+		deadline = dl
+	}
+	conn.SetDeadline(deadline)
+	host, _, _ := net.SplitHostPort(n.Addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if n.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", n.Username, n.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(n.From); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg.Bytes()); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
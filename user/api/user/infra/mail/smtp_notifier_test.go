@@ -0,0 +1,112 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"wsim/user/api/user/domain"
+)
+
+// smtpSink 最小的本地 SMTP 服务：接受一封邮件，把信封与正文送到 got
+type smtpSink struct {
+	ln  net.Listener
+	got chan sinkMail
+}
+
+type sinkMail struct {
+	from, to, data string
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpSink{ln: ln, got: make(chan sinkMail, 1)}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *smtpSink) serve() {
+	conn, err := s.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 sink ESMTP")
+	var m sinkMail
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimRight(line, "\r\n")
+		switch verb := strings.ToUpper(strings.SplitN(cmd, " ", 2)[0]); verb {
+		case "EHLO", "HELO":
+			reply("250 sink")
+		case "MAIL":
+			m.from = cmd
+			reply("250 ok")
+		case "RCPT":
+			m.to = cmd
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			m.data = data.String()
+			reply("250 queued")
+			s.got <- m
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestSendPasswordReset(t *testing.T) {
+	sink := newSMTPSink(t)
+	n := &SMTPNotifier{Addr: sink.ln.Addr().String(), From: "noreply@example.com", ResetURL: "https://im.example.com/reset"}
+	u := &domain.User{ID: 1, Username: "alice", Email: "alice@example.com"}
+	if err := n.SendPasswordReset(context.Background(), u, "tok-123", time.Now().Add(30*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case m := <-sink.got:
+		if !strings.Contains(m.from, "<noreply@example.com>") || !strings.Contains(m.to, "<alice@example.com>") {
+			t.Fatalf("envelope: %q %q", m.from, m.to)
+		}
+		if !strings.Contains(m.data, "https://im.example.com/reset?token=tok-123") {
+			t.Fatalf("body missing reset link:\n%s", m.data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail received")
+	}
+}
+
+func TestNotConfigured(t *testing.T) {
+	n := &SMTPNotifier{}
+	err := n.SendPasswordReset(context.Background(), &domain.User{Email: "a@example.com"}, "t", time.Now())
+	if err != ErrNotConfigured {
+		t.Fatalf("got %v", err)
+	}
+}
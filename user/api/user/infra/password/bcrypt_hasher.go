@@ -25,5 +25,3 @@ func (h *BcryptHasher) Hash(password string) (string, error) {
 func (h *BcryptHasher) Compare(hash string, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
-
-
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"wsim/user/api/user/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BlockModel 拉黑关系表；不做软删除，解除拉黑直接删行
+type BlockModel struct {
+	BlockerID uint      `gorm:"primaryKey;autoIncrement:false"`
+	BlockedID uint      `gorm:"primaryKey;autoIncrement:false"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+func (BlockModel) TableName() string { return "user_blocks" }
+
+type PostgresBlockRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresBlockRepository(db *gorm.DB) (*PostgresBlockRepository, error) {
+	if err := db.AutoMigrate(&BlockModel{}); err != nil {
+		return nil, err
+	}
+	return &PostgresBlockRepository{db: db}, nil
+}
+
+func (r *PostgresBlockRepository) Block(ctx context.Context, blockerID, blockedID uint) error {
+	m := &BlockModel{BlockerID: blockerID, BlockedID: blockedID, CreatedAt: time.Now()}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(m).Error
+}
+
+func (r *PostgresBlockRepository) Unblock(ctx context.Context, blockerID, blockedID uint) error {
+	return r.db.WithContext(ctx).
+		Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).
+		Delete(&BlockModel{}).Error
+}
+
+func (r *PostgresBlockRepository) ListBlocked(ctx context.Context, blockerID uint) ([]domain.Block, error) {
+	var ms []BlockModel
+	err := r.db.WithContext(ctx).
+		Where("blocker_id = ?", blockerID).
+		Order("created_at DESC").
+		Find(&ms).Error
+	if err != nil {
+		return nil, err
+	}
+	out := make([]domain.Block, 0, len(ms))
+	for _, m := range ms {
+		out = append(out, domain.Block{
+			BlockerID: m.BlockerID,
+			BlockedID: m.BlockedID,
+			CreatedAt: m.CreatedAt,
+		})
+	}
+	return out, nil
+}
+
+func (r *PostgresBlockRepository) BlockersOf(ctx context.Context, blockedID uint, blockerIDs []uint) ([]uint, error) {
+	if len(blockerIDs) == 0 {
+		return nil, nil
+	}
+	var ids []uint
+	err := r.db.WithContext(ctx).Model(&BlockModel{}).
+		Where("blocked_id = ? AND blocker_id IN ?", blockedID, blockerIDs).
+		Pluck("blocker_id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ContactModel 联系人关系表；contact_id 上的索引用于反查"谁关注了我"
+type ContactModel struct {
+	OwnerID   uint      `gorm:"primaryKey;autoIncrement:false"`
+	ContactID uint      `gorm:"primaryKey;autoIncrement:false;index"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+func (ContactModel) TableName() string { return "user_contacts" }
+
+type PostgresContactRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresContactRepository(db *gorm.DB) (*PostgresContactRepository, error) {
+	if err := db.AutoMigrate(&ContactModel{}); err != nil {
+		return nil, err
+	}
+	return &PostgresContactRepository{db: db}, nil
+}
+
+func (r *PostgresContactRepository) AddContact(ctx context.Context, ownerID, contactID uint) error {
+	m := &ContactModel{OwnerID: ownerID, ContactID: contactID, CreatedAt: time.Now()}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(m).Error
+}
+
+func (r *PostgresContactRepository) RemoveContact(ctx context.Context, ownerID, contactID uint) error {
+	return r.db.WithContext(ctx).
+		Where("owner_id = ? AND contact_id = ?", ownerID, contactID).
+		Delete(&ContactModel{}).Error
+}
+
+func (r *PostgresContactRepository) ListContacts(ctx context.Context, ownerID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).
+		Model(&ContactModel{}).
+		Where("owner_id = ?", ownerID).
+		Order("created_at DESC").
+		Pluck("contact_id", &ids).Error
+	return ids, err
+}
+
+func (r *PostgresContactRepository) ListWatchers(ctx context.Context, userID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).
+		Model(&ContactModel{}).
+		Where("contact_id = ?", userID).
+		Pluck("owner_id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"wsim/user/api/user/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginEventModel 登录审计
+type LoginEventModel struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index:idx_login_events_user,priority:1"`
+	Username  string    `gorm:"type:varchar(64);not null;index:idx_login_events_username,priority:1"`
+	IP        string    `gorm:"type:varchar(45);not null;index:idx_login_events_ip,priority:1"`
+	UserAgent string    `gorm:"type:varchar(255);not null;default:''"`
+	Device    string    `gorm:"type:varchar(64);not null;default:''"`
+	Result    string    `gorm:"type:varchar(16);not null"`
+	CreatedAt time.Time `gorm:"not null;index:idx_login_events_username,priority:2;index:idx_login_events_ip,priority:2;index:idx_login_events_user,priority:2"`
+}
+
+func (LoginEventModel) TableName() string { return "login_events" }
+
+// LockoutModel 账号锁定状态
+type LockoutModel struct {
+	Username    string `gorm:"type:varchar(64);primaryKey"`
+	LockedUntil *time.Time
+	ResetAt     *time.Time
+}
+
+func (LockoutModel) TableName() string { return "account_lockouts" }
+
+type PostgresLoginEventRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresLoginEventRepository(db *gorm.DB) (*PostgresLoginEventRepository, error) {
+	if err := db.AutoMigrate(&LoginEventModel{}, &LockoutModel{}); err != nil {
+		return nil, err
+	}
+	return &PostgresLoginEventRepository{db: db}, nil
+}
+
+func (r *PostgresLoginEventRepository) Record(ctx context.Context, e *domain.LoginEvent) error {
+	m := &LoginEventModel{
+		UserID:    e.UserID,
+		Username:  e.Username,
+		IP:        e.IP,
+		UserAgent: e.UserAgent,
+		Device:    e.Device,
+		Result:    string(e.Result),
+		CreatedAt: e.CreatedAt,
+	}
+	if err := r.db.WithContext(ctx).Create(m).Error; err != nil {
+		return err
+	}
+	e.ID = m.ID
+	return nil
+}
+
+func (r *PostgresLoginEventRepository) FailuresByUsername(ctx context.Context, username string, since time.Time) (int, time.Time, error) {
+	return r.count(ctx, "username = ?", username, domain.LoginFailed, since)
+}
+
+func (r *PostgresLoginEventRepository) FailuresByIP(ctx context.Context, ip string, since time.Time) (int, time.Time, error) {
+	return r.count(ctx, "ip = ?", ip, domain.LoginFailed, since)
+}
+
+func (r *PostgresLoginEventRepository) ResetRequestsByUsername(ctx context.Context, username string, since time.Time) (int, time.Time, error) {
+	return r.count(ctx, "username = ?", username, domain.ResetRequested, since)
+}
+
+func (r *PostgresLoginEventRepository) ResetRequestsByIP(ctx context.Context, ip string, since time.Time) (int, time.Time, error) {
+	return r.count(ctx, "ip = ?", ip, domain.ResetRequested, since)
+}
+
+func (r *PostgresLoginEventRepository) count(ctx context.Context, cond string, v string, result domain.LoginResult, since time.Time) (int, time.Time, error) {
+	var row struct {
+		N    int
+		Last *time.Time
+	}
+	err := r.db.WithContext(ctx).Model(&LoginEventModel{}).
+		Select("COUNT(*) AS n, MAX(created_at) AS last").
+		Where(cond, v).
+		Where("result = ? AND created_at > ?", string(result), since).
+		Scan(&row).Error
+	if err != nil || row.Last == nil {
+		return row.N, time.Time{}, err
+	}
+	return row.N, *row.Last, nil
+}
+
+func (r *PostgresLoginEventRepository) List(ctx context.Context, f domain.LoginEventFilter) ([]domain.LoginEvent, error) {
+	q := r.db.WithContext(ctx).Model(&LoginEventModel{})
+	if f.UserID != 0 {
+		q = q.Where("user_id = ?", f.UserID)
+	}
+	if f.Username != "" {
+		q = q.Where("username = ?", f.Username)
+	}
+	if f.IP != "" {
+		q = q.Where("ip = ?", f.IP)
+	}
+	var ms []LoginEventModel
+	if err := q.Order("created_at DESC, id DESC").Limit(f.Limit).Find(&ms).Error; err != nil {
+		return nil, err
+	}
+	out := make([]domain.LoginEvent, 0, len(ms))
+	for _, m := range ms {
+		out = append(out, domain.LoginEvent{
+			ID:        m.ID,
+			UserID:    m.UserID,
+			Username:  m.Username,
+			IP:        m.IP,
+			UserAgent: m.UserAgent,
+			Device:    m.Device,
+			Result:    domain.LoginResult(m.Result),
+			CreatedAt: m.CreatedAt,
+		})
+	}
+	return out, nil
+}
+
+func (r *PostgresLoginEventRepository) Get(ctx context.Context, username string) (*domain.Lockout, error) {
+	var m LockoutModel
+	if err := r.db.WithContext(ctx).Where("username = ?", username).Limit(1).Find(&m).Error; err != nil {
+		return nil, err
+	}
+	out := &domain.Lockout{Username: username}
+	if m.LockedUntil != nil {
+		out.LockedUntil = *m.LockedUntil
+	}
+	if m.ResetAt != nil {
+		out.ResetAt = *m.ResetAt
+	}
+	return out, nil
+}
+
+func (r *PostgresLoginEventRepository) Lock(ctx context.Context, username string, until time.Time) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "username"}},
+		DoUpdates: clause.AssignmentColumns([]string{"locked_until"}),
+	}).Create(&LockoutModel{Username: username, LockedUntil: &until}).Error
+}
+
+func (r *PostgresLoginEventRepository) Reset(ctx context.Context, username string, at time.Time) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "username"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"locked_until": nil, "reset_at": at}),
+	}).Create(&LockoutModel{Username: username, ResetAt: &at}).Error
+}
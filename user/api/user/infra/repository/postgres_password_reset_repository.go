@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"wsim/user/api/user/domain"
+
+	"gorm.io/gorm"
+)
+
+// PasswordResetModel 密码重置令牌，只存 SHA-256 哈希
+type PasswordResetModel struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"type:char(64);not null;uniqueIndex"`
+	CreatedAt time.Time `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+}
+
+func (PasswordResetModel) TableName() string { return "password_resets" }
+
+type PostgresPasswordResetRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresPasswordResetRepository(db *gorm.DB) (*PostgresPasswordResetRepository, error) {
+	if err := db.AutoMigrate(&PasswordResetModel{}); err != nil {
+		return nil, err
+	}
+	return &PostgresPasswordResetRepository{db: db}, nil
+}
+
+// Create 顺带清理早已过期的记录
+func (r *PostgresPasswordResetRepository) Create(ctx context.Context, p *domain.PasswordReset) error {
+	db := r.db.WithContext(ctx)
+	if err := db.Where("expires_at < ?", p.CreatedAt.Add(-24*time.Hour)).Delete(&PasswordResetModel{}).Error; err != nil {
+		return err
+	}
+	m := &PasswordResetModel{
+		UserID:    p.UserID,
+		TokenHash: p.TokenHash,
+		CreatedAt: p.CreatedAt,
+		ExpiresAt: p.ExpiresAt,
+	}
+	if err := db.Create(m).Error; err != nil {
+		return err
+	}
+	p.ID = m.ID
+	return nil
+}
+
+func (r *PostgresPasswordResetRepository) FindByHash(ctx context.Context, hash string) (*domain.PasswordReset, error) {
+	var m PasswordResetModel
+	tx := r.db.WithContext(ctx).Where("token_hash = ?", hash).Limit(1).Find(&m)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return nil, domain.ErrResetTokenInvalid
+	}
+	out := &domain.PasswordReset{
+		ID:        m.ID,
+		UserID:    m.UserID,
+		TokenHash: m.TokenHash,
+		CreatedAt: m.CreatedAt,
+		ExpiresAt: m.ExpiresAt,
+	}
+	if m.UsedAt != nil {
+		out.UsedAt = *m.UsedAt
+	}
+	return out, nil
+}
+
+func (r *PostgresPasswordResetRepository) MarkUsed(ctx context.Context, id uint, at time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&PasswordResetModel{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", at)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *PostgresPasswordResetRepository) InvalidateUser(ctx context.Context, userID uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&PasswordResetModel{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", at).Error
+}
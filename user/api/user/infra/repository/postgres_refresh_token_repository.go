@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"wsim/user/api/user/domain"
+
+	"gorm.io/gorm"
+)
+
+// RefreshTokenModel 刷新令牌，只存 SHA-256 哈希
+type RefreshTokenModel struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	FamilyID  string    `gorm:"type:varchar(32);not null;index"`
+	TokenHash string    `gorm:"type:char(64);not null;uniqueIndex"`
+	CreatedAt time.Time `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
+
+func (RefreshTokenModel) TableName() string { return "refresh_tokens" }
+
+type PostgresRefreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresRefreshTokenRepository(db *gorm.DB) (*PostgresRefreshTokenRepository, error) {
+	if err := db.AutoMigrate(&RefreshTokenModel{}); err != nil {
+		return nil, err
+	}
+	return &PostgresRefreshTokenRepository{db: db}, nil
+}
+
+func (r *PostgresRefreshTokenRepository) Create(ctx context.Context, t *domain.RefreshToken) error {
+	m := &RefreshTokenModel{
+		UserID:    t.UserID,
+		FamilyID:  t.FamilyID,
+		TokenHash: t.TokenHash,
+		CreatedAt: t.CreatedAt,
+		ExpiresAt: t.ExpiresAt,
+	}
+	if err := r.db.WithContext(ctx).Create(m).Error; err != nil {
+		return err
+	}
+	t.ID = m.ID
+	return nil
+}
+
+func (r *PostgresRefreshTokenRepository) FindByHash(ctx context.Context, hash string) (*domain.RefreshToken, error) {
+	var m RefreshTokenModel
+	tx := r.db.WithContext(ctx).Where("token_hash = ?", hash).Limit(1).Find(&m)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return nil, domain.ErrRefreshTokenInvalid
+	}
+	out := &domain.RefreshToken{
+		ID:        m.ID,
+		UserID:    m.UserID,
+		FamilyID:  m.FamilyID,
+		TokenHash: m.TokenHash,
+		CreatedAt: m.CreatedAt,
+		ExpiresAt: m.ExpiresAt,
+	}
+	if m.UsedAt != nil {
+		out.UsedAt = *m.UsedAt
+	}
+	if m.RevokedAt != nil {
+		out.RevokedAt = *m.RevokedAt
+	}
+	return out, nil
+}
+
+func (r *PostgresRefreshTokenRepository) MarkUsed(ctx context.Context, id uint, at time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&RefreshTokenModel{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", at)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *PostgresRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&RefreshTokenModel{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", at).Error
+}
+
+func (r *PostgresRefreshTokenRepository) RevokeUser(ctx context.Context, userID uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&RefreshTokenModel{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TokenEpochModel 用户令牌纪元，没有记录视为 0
+type TokenEpochModel struct {
+	UserID uint  `gorm:"primaryKey;autoIncrement:false"`
+	Epoch  int64 `gorm:"not null;default:0"`
+}
+
+func (TokenEpochModel) TableName() string { return "token_epochs" }
+
+// RevokedTokenModel 已注销的访问令牌，过期后清理
+type RevokedTokenModel struct {
+	TokenID   string    `gorm:"type:varchar(32);primaryKey"`
+	UserID    uint      `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+func (RevokedTokenModel) TableName() string { return "revoked_tokens" }
+
+type PostgresRevocationRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresRevocationRepository(db *gorm.DB) (*PostgresRevocationRepository, error) {
+	if err := db.AutoMigrate(&TokenEpochModel{}, &RevokedTokenModel{}); err != nil {
+		return nil, err
+	}
+	return &PostgresRevocationRepository{db: db}, nil
+}
+
+func (r *PostgresRevocationRepository) Epoch(ctx context.Context, userID uint) (int64, error) {
+	var epochs []int64
+	err := r.db.WithContext(ctx).Model(&TokenEpochModel{}).
+		Where("user_id = ?", userID).Limit(1).Pluck("epoch", &epochs).Error
+	if err != nil || len(epochs) == 0 {
+		return 0, err
+	}
+	return epochs[0], nil
+}
+
+func (r *PostgresRevocationRepository) BumpEpoch(ctx context.Context, userID uint) (int64, error) {
+	var epoch int64
+	err := r.db.WithContext(ctx).Raw(
+		`INSERT INTO token_epochs (user_id, epoch) VALUES (?, 1)
+		 ON CONFLICT (user_id) DO UPDATE SET epoch = token_epochs.epoch + 1
+		 RETURNING epoch`, userID).Scan(&epoch).Error
+	return epoch, err
+}
+
+// RevokeToken 顺带清理已过期的注销记录：过期令牌本身就无法通过校验
+func (r *PostgresRevocationRepository) RevokeToken(ctx context.Context, tokenID string, userID uint, expiresAt time.Time) error {
+	db := r.db.WithContext(ctx)
+	if err := db.Where("expires_at < ?", time.Now()).Delete(&RevokedTokenModel{}).Error; err != nil {
+		return err
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&RevokedTokenModel{TokenID: tokenID, UserID: userID, ExpiresAt: expiresAt}).Error
+}
+
+func (r *PostgresRevocationRepository) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&RevokedTokenModel{}).Where("token_id = ?", tokenID).Count(&n).Error
+	return n > 0, err
+}
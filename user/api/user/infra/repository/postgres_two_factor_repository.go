@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"wsim/user/api/user/domain"
+
+	"gorm.io/gorm"
+)
+
+// TwoFactorModel 每个用户一行 TOTP 配置
+type TwoFactorModel struct {
+	UserID    uint      `gorm:"primaryKey;autoIncrement:false"`
+	Secret    string    `gorm:"type:varchar(64);not null"`
+	LastStep  int64     `gorm:"not null;default:0"`
+	CreatedAt time.Time `gorm:"not null"`
+	EnabledAt *time.Time
+}
+
+func (TwoFactorModel) TableName() string { return "two_factors" }
+
+// RecoveryCodeModel 一次性恢复码，只存 SHA-256 哈希
+type RecoveryCodeModel struct {
+	ID       uint   `gorm:"primaryKey"`
+	UserID   uint   `gorm:"not null;uniqueIndex:idx_recovery_codes_user_hash,priority:1"`
+	CodeHash string `gorm:"type:char(64);not null;uniqueIndex:idx_recovery_codes_user_hash,priority:2"`
+	UsedAt   *time.Time
+}
+
+func (RecoveryCodeModel) TableName() string { return "recovery_codes" }
+
+// LoginChallengeModel 两步登录的挑战令牌
+type LoginChallengeModel struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"type:char(64);not null;uniqueIndex"`
+	Attempts  int       `gorm:"not null;default:0"`
+	CreatedAt time.Time `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	UsedAt    *time.Time
+}
+
+func (LoginChallengeModel) TableName() string { return "login_challenges" }
+
+// PostgresTwoFactorRepository 同时实现 TwoFactorRepository 与 LoginChallengeRepository
+type PostgresTwoFactorRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresTwoFactorRepository(db *gorm.DB) (*PostgresTwoFactorRepository, error) {
+	if err := db.AutoMigrate(&TwoFactorModel{}, &RecoveryCodeModel{}, &LoginChallengeModel{}); err != nil {
+		return nil, err
+	}
+	return &PostgresTwoFactorRepository{db: db}, nil
+}
+
+func (r *PostgresTwoFactorRepository) Get(ctx context.Context, userID uint) (*domain.TwoFactor, error) {
+	var m TwoFactorModel
+	tx := r.db.WithContext(ctx).Where("user_id = ?", userID).Limit(1).Find(&m)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return nil, domain.ErrTwoFactorNotEnabled
+	}
+	out := &domain.TwoFactor{
+		UserID:    m.UserID,
+		Secret:    m.Secret,
+		LastStep:  m.LastStep,
+		CreatedAt: m.CreatedAt,
+	}
+	if m.EnabledAt != nil {
+		out.EnabledAt = *m.EnabledAt
+	}
+	return out, nil
+}
+
+func (r *PostgresTwoFactorRepository) SavePending(ctx context.Context, t *domain.TwoFactor) error {
+	res := r.db.WithContext(ctx).Exec(`
+		INSERT INTO two_factors (user_id, secret, last_step, created_at) VALUES (?, ?, 0, ?)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_step = 0, created_at = EXCLUDED.created_at
+		WHERE two_factors.enabled_at IS NULL`, t.UserID, t.Secret, t.CreatedAt)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return domain.ErrTwoFactorEnabled
+	}
+	return nil
+}
+
+func (r *PostgresTwoFactorRepository) Enable(ctx context.Context, userID uint, at time.Time, recoveryHashes []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&TwoFactorModel{}).
+			Where("user_id = ? AND enabled_at IS NULL", userID).
+			Update("enabled_at", at)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return domain.ErrTwoFactorEnabled
+		}
+		return replaceRecoveryCodes(tx, userID, recoveryHashes)
+	})
+}
+
+func (r *PostgresTwoFactorRepository) Disable(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&RecoveryCodeModel{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&TwoFactorModel{}).Error
+	})
+}
+
+func (r *PostgresTwoFactorRepository) UseStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&TwoFactorModel{}).
+		Where("user_id = ? AND last_step < ?", userID, step).
+		Update("last_step", step)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *PostgresTwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID uint, recoveryHashes []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, recoveryHashes)
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, hashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&RecoveryCodeModel{}).Error; err != nil {
+		return err
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+	ms := make([]RecoveryCodeModel, 0, len(hashes))
+	for _, h := range hashes {
+		ms = append(ms, RecoveryCodeModel{UserID: userID, CodeHash: h})
+	}
+	return tx.Create(&ms).Error
+}
+
+func (r *PostgresTwoFactorRepository) UseRecoveryCode(ctx context.Context, userID uint, hash string, at time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&RecoveryCodeModel{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", at)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *PostgresTwoFactorRepository) RecoveryCodesLeft(ctx context.Context, userID uint) (int, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&RecoveryCodeModel{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&n).Error
+	return int(n), err
+}
+
+// Create 顺带清理早已过期的挑战
+func (r *PostgresTwoFactorRepository) Create(ctx context.Context, c *domain.LoginChallenge) error {
+	db := r.db.WithContext(ctx)
+	if err := db.Where("expires_at < ?", c.CreatedAt.Add(-24*time.Hour)).Delete(&LoginChallengeModel{}).Error; err != nil {
+		return err
+	}
+	m := &LoginChallengeModel{
+		UserID:    c.UserID,
+		TokenHash: c.TokenHash,
+		CreatedAt: c.CreatedAt,
+		ExpiresAt: c.ExpiresAt,
+	}
+	if err := db.Create(m).Error; err != nil {
+		return err
+	}
+	c.ID = m.ID
+	return nil
+}
+
+func (r *PostgresTwoFactorRepository) FindByHash(ctx context.Context, hash string) (*domain.LoginChallenge, error) {
+	var m LoginChallengeModel
+	tx := r.db.WithContext(ctx).Where("token_hash = ?", hash).Limit(1).Find(&m)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return nil, domain.ErrChallengeInvalid
+	}
+	out := &domain.LoginChallenge{
+		ID:        m.ID,
+		UserID:    m.UserID,
+		TokenHash: m.TokenHash,
+		Attempts:  m.Attempts,
+		CreatedAt: m.CreatedAt,
+		ExpiresAt: m.ExpiresAt,
+	}
+	if m.UsedAt != nil {
+		out.UsedAt = *m.UsedAt
+	}
+	return out, nil
+}
+
+func (r *PostgresTwoFactorRepository) Fail(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&LoginChallengeModel{}).
+		Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+}
+
+func (r *PostgresTwoFactorRepository) MarkUsed(ctx context.Context, id uint, at time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&LoginChallengeModel{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", at)
+	return res.RowsAffected > 0, res.Error
+}
@@ -15,8 +15,14 @@ type UserModel struct {
 	gorm.Model
 	Username     string    `gorm:"type:varchar(64);uniqueIndex;not null"`
 	PasswordHash string    `gorm:"type:varchar(255);not null"`
+	Email        string    `gorm:"type:varchar(255);not null;default:''"`
 	LastLoginAt  time.Time `gorm:"type:timestamp;not null"`
 	LastLoginIP  string    `gorm:"type:varchar(45);not null"`
+	// 资料字段：老数据迁移时补空串
+	DisplayName string `gorm:"type:varchar(64);not null;default:''"`
+	Avatar      string `gorm:"type:varchar(255);not null;default:''"`
+	Bio         string `gorm:"type:varchar(512);not null;default:''"`
+	StatusText  string `gorm:"type:varchar(128);not null;default:''"`
 }
 
 func (UserModel) TableName() string { return "users" }
@@ -39,6 +45,9 @@ func (r *PostgresUserRepository) Create(ctx context.Context, u *domain.User) err
 	m := &UserModel{
 		Username:     u.Username,
 		PasswordHash: u.PasswordHash,
+		Email:        u.Email,
+		LastLoginAt:  u.LastLoginAt,
+		LastLoginIP:  u.LastLoginIP,
 	}
 	err := r.db.WithContext(ctx).Create(m).Error
 	if err != nil {
@@ -48,12 +57,6 @@ func (r *PostgresUserRepository) Create(ctx context.Context, u *domain.User) err
 		}
 		return err
 	}
-	m.LastLoginAt = time.Now()
-	m.LastLoginIP = ctx.Value("x-forwarded-for").(string)
-	err = r.db.WithContext(ctx).Save(m).Error
-	if err != nil {
-		return err
-	}
 	u.ID = m.ID
 	return nil
 }
@@ -63,7 +66,7 @@ func (r *PostgresUserRepository) FindByUsername(ctx context.Context, username st
 	// 用 Find + Limit(1) 避免 ErrRecordNotFound 被当成 error 打日志；
 	// 同时只取需要的字段，避免 SELECT *。
 	tx := r.db.WithContext(ctx).
-		Select("id", "username", "password_hash").
+		Select("id", "username", "password_hash", "email").
 		Where("username = ?", username).
 		Limit(1).
 		Find(&m)
@@ -77,5 +80,118 @@ func (r *PostgresUserRepository) FindByUsername(ctx context.Context, username st
 		ID:           m.ID,
 		Username:     m.Username,
 		PasswordHash: m.PasswordHash,
+		Email:        m.Email,
 	}, nil
 }
+
+func (r *PostgresUserRepository) FindByID(ctx context.Context, id uint) (*domain.User, error) {
+	var m UserModel
+	tx := r.db.WithContext(ctx).
+		Select("id", "username", "password_hash", "email").
+		Where("id = ?", id).
+		Limit(1).
+		Find(&m)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return nil, domain.ErrUserNotFound
+	}
+	return &domain.User{
+		ID:           m.ID,
+		Username:     m.Username,
+		PasswordHash: m.PasswordHash,
+		Email:        m.Email,
+	}, nil
+}
+
+func (r *PostgresUserRepository) UpdatePassword(ctx context.Context, id uint, passwordHash string) error {
+	tx := r.db.WithContext(ctx).Model(&UserModel{}).Where("id = ?", id).Update("password_hash", passwordHash)
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *PostgresUserRepository) RecordLogin(ctx context.Context, id uint, at time.Time, ip string) error {
+	return r.db.WithContext(ctx).Model(&UserModel{}).Where("id = ?", id).
+		Updates(map[string]any{"last_login_at": at, "last_login_ip": ip}).Error
+}
+
+var profileColumns = []string{"id", "username", "display_name", "avatar", "bio", "status_text", "updated_at"}
+
+func toProfile(m *UserModel) *domain.Profile {
+	return &domain.Profile{
+		UserID:      m.ID,
+		Username:    m.Username,
+		DisplayName: m.DisplayName,
+		Avatar:      m.Avatar,
+		Bio:         m.Bio,
+		StatusText:  m.StatusText,
+		UpdatedAt:   m.UpdatedAt,
+	}
+}
+
+func (r *PostgresUserRepository) GetProfile(ctx context.Context, userID uint) (*domain.Profile, error) {
+	var m UserModel
+	tx := r.db.WithContext(ctx).
+		Select(profileColumns).
+		Where("id = ?", userID).
+		Limit(1).
+		Find(&m)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return nil, domain.ErrUserNotFound
+	}
+	return toProfile(&m), nil
+}
+
+func (r *PostgresUserRepository) GetProfiles(ctx context.Context, userIDs []uint) ([]domain.Profile, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	var ms []UserModel
+	err := r.db.WithContext(ctx).
+		Select(profileColumns).
+		Where("id IN ?", userIDs).
+		Find(&ms).Error
+	if err != nil {
+		return nil, err
+	}
+	out := make([]domain.Profile, 0, len(ms))
+	for i := range ms {
+		out = append(out, *toProfile(&ms[i]))
+	}
+	return out, nil
+}
+
+func (r *PostgresUserRepository) UpdateProfile(ctx context.Context, userID uint, patch domain.ProfilePatch) (*domain.Profile, error) {
+	updates := map[string]any{}
+	if patch.DisplayName != nil {
+		updates["display_name"] = *patch.DisplayName
+	}
+	if patch.Avatar != nil {
+		updates["avatar"] = *patch.Avatar
+	}
+	if patch.Bio != nil {
+		updates["bio"] = *patch.Bio
+	}
+	if patch.StatusText != nil {
+		updates["status_text"] = *patch.StatusText
+	}
+	if len(updates) > 0 {
+		tx := r.db.WithContext(ctx).Model(&UserModel{}).Where("id = ?", userID).Updates(updates)
+		if tx.Error != nil {
+			return nil, tx.Error
+		}
+		if tx.RowsAffected == 0 {
+			return nil, domain.ErrUserNotFound
+		}
+	}
+	return r.GetProfile(ctx, userID)
+}
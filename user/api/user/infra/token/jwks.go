@@ -0,0 +1,88 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"wsim/user/api/user/domain"
+	"wsim/user/api/user/dto"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+// 定期刷新公钥的间隔；新 kid 出现时也会按需刷新
+const jwksRefreshInterval = 10 * time.Minute
+
+// JWKSURLFromEnv 用户服务公钥地址，环境变量 JWKS_URL 覆盖
+func JWKSURLFromEnv() string {
+	if v := os.Getenv("JWKS_URL"); v != "" {
+		return v
+	}
+	return "http://127.0.0.1:9091/.well-known/jwks.json"
+}
+
+// NewJWKSVerifier 从用户服务的 JWKS 接口获取公钥并定期刷新，首次获取失败直接返回错误
+func NewJWKSVerifier(ctx context.Context, url string) (*Verifier, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	v := &Verifier{}
+	v.refresh = func() error {
+		keys, err := fetchJWKS(ctx, client, url)
+		if err != nil {
+			return err
+		}
+		v.SetKeys(keys)
+		return nil
+	}
+	if err := v.refresh(); err != nil {
+		return nil, err
+	}
+	go func() {
+		t := time.NewTicker(jwksRefreshInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if err := v.refresh(); err != nil {
+					hlog.CtxWarnf(ctx, "refresh jwks from %s failed: %v", url, err)
+				}
+			}
+		}
+	}()
+	return v, nil
+}
+
+func fetchJWKS(ctx context.Context, client *http.Client, url string) ([]domain.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks: unexpected status %d", resp.StatusCode)
+	}
+	var set dto.JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+	keys := make([]domain.PublicKey, 0, len(set.Keys))
+	for _, j := range set.Keys {
+		// 不认识的密钥类型跳过，不影响其余公钥
+		if k, err := j.PublicKey(); err == nil {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("jwks: no usable keys at %s", url)
+	}
+	return keys, nil
+}
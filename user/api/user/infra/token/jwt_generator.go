@@ -1,40 +1,167 @@
 package token
 
 import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
 	"os"
+	"strings"
 	"time"
 
+	"wsim/user/api/user/domain"
+	"wsim/user/api/user/dto"
+
 	jwt "github.com/golang-jwt/jwt/v4"
 )
 
+// JWTGenerator 用私钥签发访问令牌（Ed25519 用 EdDSA，RSA 用 RS256），令牌头带 kid。
+// 密钥轮换：新私钥放在第一位负责签名，旧私钥保留在后面，只用于验签与发布公钥，直到旧令牌全部过期
 type JWTGenerator struct {
-	Secret   []byte
+	*Verifier
+	signer   crypto.Signer
+	kid      string
+	method   jwt.SigningMethod
+	public   []domain.PublicKey
 	ExpireIn time.Duration
 }
 
-func NewJWTGenerator() *JWTGenerator {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "secret key"
+// NewJWTGenerator 从环境变量 JWT_PRIVATE_KEYS（逗号分隔的 PEM 私钥文件，第一个用于签名）加载密钥；
+// 未配置时返回 domain.ErrNoSigningKey
+func NewJWTGenerator() (*JWTGenerator, error) {
+	var paths []string
+	for _, p := range strings.Split(os.Getenv("JWT_PRIVATE_KEYS"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	keys := make([]crypto.Signer, 0, len(paths))
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		k, err := ParsePrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		keys = append(keys, k)
 	}
-	expStr := os.Getenv("JWT_EXPIRE")
 	exp := time.Hour
-	if expStr != "" {
-		if d, err := time.ParseDuration(expStr); err == nil && d > 0 {
+	if v := os.Getenv("JWT_EXPIRE"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
 			exp = d
 		}
 	}
-	return &JWTGenerator{Secret: []byte(secret), ExpireIn: exp}
+	return NewJWTGeneratorWithKeys(keys, exp)
+}
+
+// NewJWTGeneratorWithKeys keys[0] 签名，全部用于验签
+func NewJWTGeneratorWithKeys(keys []crypto.Signer, expireIn time.Duration) (*JWTGenerator, error) {
+	if len(keys) == 0 {
+		return nil, domain.ErrNoSigningKey
+	}
+	g := &JWTGenerator{signer: keys[0], ExpireIn: expireIn}
+	for i, k := range keys {
+		pub, err := publicKeyOf(k)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			g.kid = pub.ID
+			g.method = jwt.GetSigningMethod(pub.Algorithm)
+		}
+		g.public = append(g.public, pub)
+	}
+	g.Verifier = NewVerifier(g.public)
+	return g, nil
+}
+
+// ParsePrivateKey 解析 PEM 私钥：PKCS#8（Ed25519/RSA）或 PKCS#1（RSA），RSA 至少 2048 位
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		return k, nil
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, errors.New("RSA key shorter than 2048 bits")
+		}
+		return k, nil
+	}
+	return nil, fmt.Errorf("unsupported private key type %T", key)
 }
 
-func (g *JWTGenerator) Generate(userID uint, username string) (string, error) {
+// publicKeyOf 取私钥对应的公钥，kid 为 JWK 指纹（RFC 7638），同一把密钥在各处得到相同的 kid
+func publicKeyOf(k crypto.Signer) (domain.PublicKey, error) {
+	pub := domain.PublicKey{Key: k.Public()}
+	switch k.(type) {
+	case ed25519.PrivateKey:
+		pub.Algorithm = jwt.SigningMethodEdDSA.Alg()
+	case *rsa.PrivateKey:
+		pub.Algorithm = jwt.SigningMethodRS256.Alg()
+	default:
+		return pub, fmt.Errorf("unsupported private key type %T", k)
+	}
+	j, err := dto.FromPublicKey(pub)
+	if err != nil {
+		return pub, err
+	}
+	var canonical string
+	if j.Kty == "OKP" {
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q}`, j.Crv, j.Kty, j.X)
+	} else {
+		canonical = fmt.Sprintf(`{"e":%q,"kty":%q,"n":%q}`, j.E, j.Kty, j.N)
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	pub.ID = base64.RawURLEncoding.EncodeToString(sum[:])
+	return pub, nil
+}
+
+// Generate 签发访问令牌；每个令牌带随机 jti，epoch 为用户当前令牌纪元
+func (g *JWTGenerator) Generate(userID uint, username string, epoch int64) (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
 	now := time.Now()
 	claims := jwt.MapClaims{
+		"jti":      hex.EncodeToString(b[:]),
 		"sub":      userID,
 		"username": username,
+		"ep":       epoch,
 		"iat":      now.Unix(),
 		"exp":      now.Add(g.ExpireIn).Unix(),
 	}
-	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return t.SignedString(g.Secret)
+	t := jwt.NewWithClaims(g.method, claims)
+	t.Header["kid"] = g.kid
+	return t.SignedString(g.signer)
+}
+
+// PublicKeys 全部验签公钥（含轮换下来的旧密钥），用于发布 JWKS
+func (g *JWTGenerator) PublicKeys() []domain.PublicKey {
+	return g.public
 }
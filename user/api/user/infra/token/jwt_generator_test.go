@@ -0,0 +1,151 @@
+package token
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"wsim/user/api/user/domain"
+	"wsim/user/api/user/dto"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+func newEd25519(t *testing.T) crypto.Signer {
+	t.Helper()
+	_, k, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestNoKeyConfigured(t *testing.T) {
+	t.Setenv("JWT_PRIVATE_KEYS", "")
+	if _, err := NewJWTGenerator(); !errors.Is(err, domain.ErrNoSigningKey) {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestRotationKeepsOldKeys(t *testing.T) {
+	oldKey, newKey := newEd25519(t), newEd25519(t)
+	before, err := NewJWTGeneratorWithKeys([]crypto.Signer{oldKey}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := before.Generate(1, "alice", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := NewJWTGeneratorWithKeys([]crypto.Signer{newKey, oldKey}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newToken, err := after.Generate(1, "alice", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tk := range []string{oldToken, newToken} {
+		if c, err := after.Parse(tk); err != nil || c.UserID != 1 || c.Username != "alice" {
+			t.Fatalf("parse after rotation: %+v %v", c, err)
+		}
+	}
+	// 轮换前的验签方不认识新 kid
+	if _, err := before.Parse(newToken); !errors.Is(err, domain.ErrTokenInvalid) {
+		t.Fatalf("unknown kid: got %v", err)
+	}
+	if got := len(after.PublicKeys()); got != 2 {
+		t.Fatalf("published %d keys, want 2", got)
+	}
+}
+
+func TestRS256AndRejectSymmetric(t *testing.T) {
+	rk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewJWTGeneratorWithKeys([]crypto.Signer{rk}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk, err := g.Generate(2, "bob", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := g.Parse(tk)
+	if err != nil || c.UserID != 2 || c.Epoch != 3 {
+		t.Fatalf("parse: %+v %v", c, err)
+	}
+
+	// 用公开的 kid 伪造 HS256 令牌（算法混淆）必须被拒绝
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"jti": "x", "sub": 2, "exp": time.Now().Add(time.Hour).Unix(),
+	})
+	forged.Header["kid"] = g.PublicKeys()[0].ID
+	s, err := forged.SignedString([]byte("anything"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Parse(s); !errors.Is(err, domain.ErrTokenInvalid) {
+		t.Fatalf("forged HS256: got %v", err)
+	}
+}
+
+func TestJWKSVerifier(t *testing.T) {
+	first, second := newEd25519(t), newEd25519(t)
+	g1, err := NewJWTGeneratorWithKeys([]crypto.Signer{first}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g2, err := NewJWTGeneratorWithKeys([]crypto.Signer{second, first}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	published := g1
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		set := dto.JWKS{}
+		for _, k := range published.PublicKeys() {
+			j, err := dto.FromPublicKey(k)
+			if err != nil {
+				t.Error(err)
+			}
+			set.Keys = append(set.Keys, j)
+		}
+		json.NewEncoder(w).Encode(set)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	v, err := NewJWKSVerifier(ctx, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk, _ := g1.Generate(5, "carol", 0)
+	if c, err := v.Parse(tk); err != nil || c.UserID != 5 {
+		t.Fatalf("parse: %+v %v", c, err)
+	}
+
+	// 用户服务轮换后，未知 kid 触发重新拉取
+	mu.Lock()
+	published = g2
+	mu.Unlock()
+	tk, _ = g2.Generate(5, "carol", 0)
+	if _, err := v.Parse(tk); err != nil {
+		t.Fatalf("parse after rotation: %v", err)
+	}
+}
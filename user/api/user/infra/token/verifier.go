@@ -0,0 +1,101 @@
+package token
+
+import (
+	"sync"
+	"time"
+
+	"wsim/user/api/user/domain"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// 未知 kid 触发重新拉取公钥的最小间隔，防止伪造 kid 的请求打爆公钥来源
+const minRefreshInterval = 30 * time.Second
+
+// Verifier 只持有公钥的验签器：按令牌头里的 kid 选公钥，算法须与公钥一致（不接受 HS256 等对称算法）
+type Verifier struct {
+	mu   sync.RWMutex
+	keys map[string]domain.PublicKey
+	// refresh 遇到未知 kid 时重新获取公钥（密钥轮换后），为 nil 表示公钥固定
+	refresh     func() error
+	lastRefresh time.Time
+}
+
+func NewVerifier(keys []domain.PublicKey) *Verifier {
+	v := &Verifier{}
+	v.SetKeys(keys)
+	return v
+}
+
+// SetKeys 整体替换公钥集合
+func (v *Verifier) SetKeys(keys []domain.PublicKey) {
+	m := make(map[string]domain.PublicKey, len(keys))
+	for _, k := range keys {
+		m[k.ID] = k
+	}
+	v.mu.Lock()
+	v.keys = m
+	v.mu.Unlock()
+}
+
+// Parse 校验签名与有效期并取出身份；不检查吊销状态
+func (v *Verifier) Parse(token string) (*domain.TokenClaims, error) {
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg()}))
+	_, err := parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		k, ok := v.lookup(kid)
+		if !ok || k.Algorithm != t.Method.Alg() {
+			return nil, domain.ErrTokenInvalid
+		}
+		return k.Key, nil
+	})
+	if err != nil {
+		return nil, domain.ErrTokenInvalid
+	}
+	return claimsFromMap(claims)
+}
+
+func (v *Verifier) lookup(kid string) (domain.PublicKey, bool) {
+	v.mu.RLock()
+	k, ok := v.keys[kid]
+	v.mu.RUnlock()
+	if ok || kid == "" || v.refresh == nil {
+		return k, ok
+	}
+	v.mu.Lock()
+	due := time.Since(v.lastRefresh) >= minRefreshInterval
+	if due {
+		v.lastRefresh = time.Now()
+	}
+	v.mu.Unlock()
+	if !due || v.refresh() != nil {
+		return k, false
+	}
+	v.mu.RLock()
+	k, ok = v.keys[kid]
+	v.mu.RUnlock()
+	return k, ok
+}
+
+func claimsFromMap(m jwt.MapClaims) (*domain.TokenClaims, error) {
+	jti, _ := m["jti"].(string)
+	sub, _ := m["sub"].(float64)
+	exp, _ := m["exp"].(float64)
+	if jti == "" || sub <= 0 || exp == 0 {
+		return nil, domain.ErrTokenInvalid
+	}
+	c := &domain.TokenClaims{
+		ID:        jti,
+		UserID:    uint(sub),
+		ExpiresAt: time.Unix(int64(exp), 0),
+	}
+	c.Username, _ = m["username"].(string)
+	if ep, ok := m["ep"].(float64); ok {
+		c.Epoch = int64(ep)
+	}
+	if iat, ok := m["iat"].(float64); ok {
+		c.IssuedAt = time.Unix(int64(iat), 0)
+	}
+	return c, nil
+}
@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"wsim/user/api/user/domain"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+// Notifier 把密码重置令牌送达用户（邮件等）
+type Notifier interface {
+	SendPasswordReset(ctx context.Context, u *domain.User, token string, expiresAt time.Time) error
+}
+
+// ChangePassword 已登录用户凭旧密码修改密码。原有会话全部吊销，返回给当前客户端的新会话
+func (s *AuthService) ChangePassword(ctx context.Context, userID uint, oldPassword, newPassword string, client ClientInfo) (*AuthResult, error) {
+	if oldPassword == "" || newPassword == "" {
+		return nil, ErrBadRequest
+	}
+	u, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkPassword(ctx, u, oldPassword, client); err != nil {
+		return nil, err
+	}
+	if err := s.setPassword(ctx, u.ID, newPassword); err != nil {
+		return nil, err
+	}
+	return s.issue(ctx, u, "")
+}
+
+// RequestPasswordReset 受理密码重置请求：按用户名与 IP 限流后立即返回，查账号、
+// 作废旧令牌与发送邮件都在后台进行。用户不存在、未填邮箱或发送失败都只记日志，
+// 调用方得到的结果与耗时都不暴露账号是否存在
+func (s *AuthService) RequestPasswordReset(ctx context.Context, username string, client ClientInfo) error {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return ErrBadRequest
+	}
+	if err := s.guard.ResetRequested(ctx, username, client); err != nil {
+		return err
+	}
+	ctx = context.WithoutCancel(ctx)
+	s.async(func() { s.sendPasswordReset(ctx, username) })
+	return nil
+}
+
+// sendPasswordReset 给账号邮箱发送一次性重置令牌，之前未用的令牌作废
+func (s *AuthService) sendPasswordReset(ctx context.Context, username string) {
+	u, err := s.repo.FindByUsername(ctx, username)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return
+	}
+	if err != nil {
+		hlog.CtxErrorf(ctx, "load user %q for password reset failed: %v", username, err)
+		return
+	}
+	if u.Email == "" {
+		hlog.CtxInfof(ctx, "password reset requested for user %d without email", u.ID)
+		return
+	}
+	now := s.now()
+	if err := s.resets.InvalidateUser(ctx, u.ID, now); err != nil {
+		hlog.CtxErrorf(ctx, "invalidate password resets for user %d failed: %v", u.ID, err)
+		return
+	}
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		hlog.CtxErrorf(ctx, "generate password reset token failed: %v", err)
+		return
+	}
+	token := base64.RawURLEncoding.EncodeToString(b[:])
+	r := &domain.PasswordReset{UserID: u.ID, TokenHash: hashToken(token), CreatedAt: now, ExpiresAt: now.Add(s.ResetTTL)}
+	if err := s.resets.Create(ctx, r); err != nil {
+		hlog.CtxErrorf(ctx, "store password reset for user %d failed: %v", u.ID, err)
+		return
+	}
+	if err := s.notifier.SendPasswordReset(ctx, u, token, r.ExpiresAt); err != nil {
+		hlog.CtxErrorf(ctx, "send password reset to user %d failed: %v", u.ID, err)
+	}
+}
+
+// ResetPassword 用重置令牌设置新密码；令牌只能用一次，成功后吊销该用户全部会话
+func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if token == "" || newPassword == "" {
+		return ErrBadRequest
+	}
+	r, err := s.resets.FindByHash(ctx, hashToken(token))
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	if !r.UsedAt.IsZero() || !now.Before(r.ExpiresAt) {
+		return domain.ErrResetTokenInvalid
+	}
+	ok, err := s.resets.MarkUsed(ctx, r.ID, now)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return domain.ErrResetTokenInvalid
+	}
+	return s.setPassword(ctx, r.UserID, newPassword)
+}
+
+// setPassword 写入新密码并吊销全部会话与未用的重置令牌
+func (s *AuthService) setPassword(ctx context.Context, userID uint, password string) error {
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdatePassword(ctx, userID, hash); err != nil {
+		return err
+	}
+	if err := s.resets.InvalidateUser(ctx, userID, s.now()); err != nil {
+		return err
+	}
+	return s.revokeSessions(ctx, userID)
+}
+
+// checkPassword 已登录时的密码确认（改密码、关闭两步验证）与登录共用防爆破限制：
+// 拿到访问令牌的人不能借这些接口无限猜密码
+func (s *AuthService) checkPassword(ctx context.Context, u *domain.User, password string, client ClientInfo) error {
+	if err := s.guard.Check(ctx, u.ID, u.Username, client); err != nil {
+		return err
+	}
+	if s.hasher.Compare(u.PasswordHash, password) != nil {
+		s.guard.Failed(ctx, u.ID, u.Username, client)
+		return ErrInvalidCredentials
+	}
+	return nil
+}
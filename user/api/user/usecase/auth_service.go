@@ -2,10 +2,19 @@ package usecase
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"net/mail"
+	"os"
 	"strings"
+	"time"
 
 	"wsim/user/api/user/domain"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
 )
 
 type PasswordHasher interface {
@@ -14,22 +23,97 @@ type PasswordHasher interface {
 }
 
 type TokenGenerator interface {
-	Generate(userID uint, username string) (string, error)
+	Generate(userID uint, username string, epoch int64) (string, error)
 }
 
 type AuthService struct {
-	repo   domain.UserRepository
-	hasher PasswordHasher
-	token  TokenGenerator
+	repo    domain.UserRepository
+	hasher  PasswordHasher
+	token   TokenGenerator
+	refresh domain.RefreshTokenRepository
+	// revocations 签发时取令牌纪元，注销/吊销时写入
+	revocations domain.RevocationRepository
+	events      SessionNotifier
+	resets      domain.PasswordResetRepository
+	notifier    Notifier
+	guard       *LoginGuard
+	twoFactor   domain.TwoFactorRepository
+	challenges  domain.LoginChallengeRepository
+	// RefreshTTL 刷新令牌有效期，每次轮换重新计算；环境变量 REFRESH_TOKEN_TTL 覆盖（默认 720h）
+	RefreshTTL time.Duration
+	// ResetTTL 密码重置令牌有效期，环境变量 PASSWORD_RESET_TTL 覆盖（默认 30m）
+	ResetTTL time.Duration
+	// ChallengeTTL 两步登录中挑战令牌的有效期，环境变量 TWO_FACTOR_CHALLENGE_TTL 覆盖（默认 5m）
+	ChallengeTTL time.Duration
+	// Issuer 显示在验证器 App 里的服务名，环境变量 TOTP_ISSUER 覆盖（默认 wsim）
+	Issuer string
+	now    func() time.Time
+	// async 后台执行重置邮件等不必等待的工作，测试中改为同步
+	async func(func())
+}
+
+func NewAuthService(
+	repo domain.UserRepository,
+	hasher PasswordHasher,
+	token TokenGenerator,
+	refresh domain.RefreshTokenRepository,
+	revocations domain.RevocationRepository,
+	events SessionNotifier,
+	resets domain.PasswordResetRepository,
+	notifier Notifier,
+	guard *LoginGuard,
+	twoFactor domain.TwoFactorRepository,
+	challenges domain.LoginChallengeRepository,
+) *AuthService {
+	issuer := os.Getenv("TOTP_ISSUER")
+	if issuer == "" {
+		issuer = "wsim"
+	}
+	return &AuthService{
+		repo:         repo,
+		hasher:       hasher,
+		token:        token,
+		refresh:      refresh,
+		revocations:  revocations,
+		events:       events,
+		resets:       resets,
+		notifier:     notifier,
+		guard:        guard,
+		twoFactor:    twoFactor,
+		challenges:   challenges,
+		RefreshTTL:   durationEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		ResetTTL:     durationEnv("PASSWORD_RESET_TTL", 30*time.Minute),
+		ChallengeTTL: durationEnv("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute),
+		Issuer:       issuer,
+		now:          time.Now,
+		async:        func(f func()) { go f() },
+	}
 }
 
-func NewAuthService(repo domain.UserRepository, hasher PasswordHasher, token TokenGenerator) *AuthService {
-	return &AuthService{repo: repo, hasher: hasher, token: token}
+func durationEnv(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return def
 }
 
+// ClientInfo 发起登录的客户端；Device 为客户端自报的设备名
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+	Device    string
+}
+
+// AuthResult 登录结果；Challenge 非空表示账号开启了两步验证，
+// 此时不签发令牌，客户端须用 Challenge 加验证码调用 CompleteLogin
 type AuthResult struct {
-	UserID uint
-	Token  string
+	UserID             uint
+	Token              string
+	RefreshToken       string
+	Challenge          string
+	ChallengeExpiresAt time.Time
 }
 
 var (
@@ -37,11 +121,20 @@ var (
 	ErrBadRequest         = errors.New("bad request")
 )
 
-func (s *AuthService) Register(ctx context.Context, username, password string) (*AuthResult, error) {
+// Register email 可为空；填写后可用于找回密码。注册即登录，记为一次成功登录
+func (s *AuthService) Register(ctx context.Context, username, password, email string, client ClientInfo) (*AuthResult, error) {
 	username = strings.TrimSpace(username)
 	if username == "" || password == "" {
 		return nil, ErrBadRequest
 	}
+	email = strings.TrimSpace(email)
+	if email != "" {
+		addr, err := mail.ParseAddress(email)
+		if err != nil || addr.Name != "" || len(addr.Address) > 255 {
+			return nil, ErrBadRequest
+		}
+		email = addr.Address
+	}
 
 	if u, err := s.repo.FindByUsername(ctx, username); err == nil && u != nil {
 		return nil, domain.ErrUserAlreadyExists
@@ -57,41 +150,208 @@ func (s *AuthService) Register(ctx context.Context, username, password string) (
 	u := &domain.User{
 		Username:     username,
 		PasswordHash: hash,
+		Email:        email,
+		LastLoginAt:  s.now(),
+		LastLoginIP:  truncate(client.IP, 45),
 	}
 	if err := s.repo.Create(ctx, u); err != nil {
 		return nil, err
 	}
+	s.guard.Succeeded(ctx, u.ID, u.Username, client)
 
-	tk, err := s.token.Generate(u.ID, u.Username)
-	if err != nil {
-		return nil, err
-	}
-
-	return &AuthResult{UserID: u.ID, Token: tk}, nil
+	return s.issue(ctx, u, "")
 }
 
-func (s *AuthService) Login(ctx context.Context, username, password string) (*AuthResult, error) {
+// Login 校验密码前先过防爆破检查；被限制时返回 *RetryError
+func (s *AuthService) Login(ctx context.Context, username, password string, client ClientInfo) (*AuthResult, error) {
 	username = strings.TrimSpace(username)
 	if username == "" || password == "" {
 		return nil, ErrBadRequest
 	}
 
+	// 用户名最长 64 字节，更长的不可能存在，也不进审计
+	if len(username) > 64 {
+		return nil, ErrInvalidCredentials
+	}
+
 	u, err := s.repo.FindByUsername(ctx, username)
+	if err != nil && !errors.Is(err, domain.ErrUserNotFound) {
+		return nil, err
+	}
+	var uid uint
+	if u != nil {
+		uid = u.ID
+	}
+	if err := s.guard.Check(ctx, uid, username, client); err != nil {
+		return nil, err
+	}
+	if u == nil || s.hasher.Compare(u.PasswordHash, password) != nil {
+		s.guard.Failed(ctx, uid, username, client)
+		return nil, ErrInvalidCredentials
+	}
+
+	// 开启了两步验证：密码正确也先不清零失败计数，等第二步通过
+	tf, err := s.twoFactor.Get(ctx, u.ID)
+	switch {
+	case errors.Is(err, domain.ErrTwoFactorNotEnabled):
+	case err != nil:
+		return nil, err
+	case tf.Enabled():
+		return s.challenge(ctx, u)
+	}
+	return s.loggedIn(ctx, u, client)
+}
+
+// loggedIn 登录的全部验证通过：记审计、更新最近登录并签发令牌
+func (s *AuthService) loggedIn(ctx context.Context, u *domain.User, client ClientInfo) (*AuthResult, error) {
+	s.guard.Succeeded(ctx, u.ID, u.Username, client)
+	if err := s.repo.RecordLogin(ctx, u.ID, s.now(), truncate(client.IP, 45)); err != nil {
+		hlog.CtxErrorf(ctx, "update last login of user %d failed: %v", u.ID, err)
+	}
+	return s.issue(ctx, u, "")
+}
+
+// Unlock 管理员解除账号锁定
+func (s *AuthService) Unlock(ctx context.Context, userID uint) error {
+	u, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.guard.Unlock(ctx, u.Username)
+}
+
+// LoginHistory 用户查看自己最近的登录记录（含失败的尝试）
+func (s *AuthService) LoginHistory(ctx context.Context, userID uint, limit int) ([]domain.LoginEvent, error) {
+	return s.guard.Events(ctx, domain.LoginEventFilter{UserID: userID, Limit: limit})
+}
+
+// LoginEvents 登录审计记录
+func (s *AuthService) LoginEvents(ctx context.Context, f domain.LoginEventFilter) ([]domain.LoginEvent, error) {
+	return s.guard.Events(ctx, f)
+}
+
+// Refresh 用刷新令牌换一对新令牌，旧刷新令牌随即作废。
+// 已作废的令牌再次出现说明可能泄露：吊销整个令牌族，持有者都须重新登录
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*AuthResult, error) {
+	if refreshToken == "" {
+		return nil, ErrBadRequest
+	}
+	t, err := s.refresh.FindByHash(ctx, hashToken(refreshToken))
 	if err != nil {
-		if errors.Is(err, domain.ErrUserNotFound) {
-			return nil, ErrInvalidCredentials
+		return nil, err
+	}
+	now := s.now()
+	if !t.RevokedAt.IsZero() || !now.Before(t.ExpiresAt) {
+		return nil, domain.ErrRefreshTokenInvalid
+	}
+	if t.UsedAt.IsZero() {
+		ok, err := s.refresh.MarkUsed(ctx, t.ID, now)
+		if err != nil {
+			return nil, err
 		}
+		if ok {
+			u, err := s.repo.FindByID(ctx, t.UserID)
+			if err != nil {
+				return nil, err
+			}
+			return s.issue(ctx, u, t.FamilyID)
+		}
+		// 并发刷新中输给了另一个请求：同样按重用处理
+	}
+	if err := s.refresh.RevokeFamily(ctx, t.FamilyID, now); err != nil {
 		return nil, err
 	}
-	if err := s.hasher.Compare(u.PasswordHash, password); err != nil {
-		return nil, ErrInvalidCredentials
+	hlog.CtxWarnf(ctx, "refresh token reuse detected for user %d, family %s revoked", t.UserID, t.FamilyID)
+	return nil, domain.ErrRefreshTokenReused
+}
+
+// Logout 注销当前访问令牌；带上刷新令牌时一并吊销其所在的令牌族
+func (s *AuthService) Logout(ctx context.Context, claims *domain.TokenClaims, refreshToken string) error {
+	if err := s.revocations.RevokeToken(ctx, claims.ID, claims.UserID, claims.ExpiresAt); err != nil {
+		return err
+	}
+	if refreshToken != "" {
+		t, err := s.refresh.FindByHash(ctx, hashToken(refreshToken))
+		switch {
+		case errors.Is(err, domain.ErrRefreshTokenInvalid):
+		case err != nil:
+			return err
+		case t.UserID == claims.UserID:
+			if err := s.refresh.RevokeFamily(ctx, t.FamilyID, s.now()); err != nil {
+				return err
+			}
+		}
+	}
+	if err := s.events.SessionsRevoked(ctx, claims.UserID, claims.ID, 0); err != nil {
+		hlog.CtxWarnf(ctx, "notify session revoked for user %d failed: %v", claims.UserID, err)
+	}
+	return nil
+}
+
+// RevokeAll 吊销用户的全部会话：已签发的访问令牌与刷新令牌都失效，在线连接随之断开
+func (s *AuthService) RevokeAll(ctx context.Context, userID uint) error {
+	if _, err := s.repo.FindByID(ctx, userID); err != nil {
+		return err
 	}
+	return s.revokeSessions(ctx, userID)
+}
+
+func (s *AuthService) revokeSessions(ctx context.Context, userID uint) error {
+	epoch, err := s.revocations.BumpEpoch(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.refresh.RevokeUser(ctx, userID, s.now()); err != nil {
+		return err
+	}
+	if err := s.events.SessionsRevoked(ctx, userID, "", epoch); err != nil {
+		hlog.CtxWarnf(ctx, "notify sessions revoked for user %d failed: %v", userID, err)
+	}
+	return nil
+}
 
-	tk, err := s.token.Generate(u.ID, u.Username)
+// issue 签发访问令牌与刷新令牌；familyID 为空表示新登录，开启新的令牌族
+func (s *AuthService) issue(ctx context.Context, u *domain.User, familyID string) (*AuthResult, error) {
+	epoch, err := s.revocations.Epoch(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	tk, err := s.token.Generate(u.ID, u.Username, epoch)
 	if err != nil {
 		return nil, err
 	}
-	return &AuthResult{UserID: u.ID, Token: tk}, nil
+	if familyID == "" {
+		if familyID, err = randomHex(16); err != nil {
+			return nil, err
+		}
+	}
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+	refresh := base64.RawURLEncoding.EncodeToString(b[:])
+	now := s.now()
+	if err := s.refresh.Create(ctx, &domain.RefreshToken{
+		UserID:    u.ID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(refresh),
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.RefreshTTL),
+	}); err != nil {
+		return nil, err
+	}
+	return &AuthResult{UserID: u.ID, Token: tk, RefreshToken: refresh}, nil
 }
 
+func hashToken(t string) string {
+	sum := sha256.Sum256([]byte(t))
+	return hex.EncodeToString(sum[:])
+}
 
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
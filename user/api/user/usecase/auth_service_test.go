@@ -0,0 +1,441 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"wsim/user/api/user/domain"
+)
+
+type memUsers struct {
+	users []domain.User
+}
+
+func (m *memUsers) Create(_ context.Context, u *domain.User) error {
+	u.ID = uint(len(m.users) + 1)
+	m.users = append(m.users, *u)
+	return nil
+}
+
+func (m *memUsers) FindByUsername(_ context.Context, username string) (*domain.User, error) {
+	for i := range m.users {
+		if m.users[i].Username == username {
+			u := m.users[i]
+			return &u, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (m *memUsers) FindByID(_ context.Context, id uint) (*domain.User, error) {
+	for i := range m.users {
+		if m.users[i].ID == id {
+			u := m.users[i]
+			return &u, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (m *memUsers) UpdatePassword(_ context.Context, id uint, hash string) error {
+	for i := range m.users {
+		if m.users[i].ID == id {
+			m.users[i].PasswordHash = hash
+			return nil
+		}
+	}
+	return domain.ErrUserNotFound
+}
+
+func (m *memUsers) RecordLogin(_ context.Context, id uint, at time.Time, ip string) error {
+	for i := range m.users {
+		if m.users[i].ID == id {
+			m.users[i].LastLoginAt, m.users[i].LastLoginIP = at, ip
+			return nil
+		}
+	}
+	return domain.ErrUserNotFound
+}
+
+type memRefresh struct {
+	tokens []domain.RefreshToken
+}
+
+func (m *memRefresh) Create(_ context.Context, t *domain.RefreshToken) error {
+	t.ID = uint(len(m.tokens) + 1)
+	m.tokens = append(m.tokens, *t)
+	return nil
+}
+
+func (m *memRefresh) FindByHash(_ context.Context, hash string) (*domain.RefreshToken, error) {
+	for i := range m.tokens {
+		if m.tokens[i].TokenHash == hash {
+			t := m.tokens[i]
+			return &t, nil
+		}
+	}
+	return nil, domain.ErrRefreshTokenInvalid
+}
+
+func (m *memRefresh) MarkUsed(_ context.Context, id uint, at time.Time) (bool, error) {
+	t := &m.tokens[id-1]
+	if !t.UsedAt.IsZero() || !t.RevokedAt.IsZero() {
+		return false, nil
+	}
+	t.UsedAt = at
+	return true, nil
+}
+
+func (m *memRefresh) RevokeFamily(_ context.Context, familyID string, at time.Time) error {
+	for i := range m.tokens {
+		if m.tokens[i].FamilyID == familyID && m.tokens[i].RevokedAt.IsZero() {
+			m.tokens[i].RevokedAt = at
+		}
+	}
+	return nil
+}
+
+func (m *memRefresh) RevokeUser(_ context.Context, userID uint, at time.Time) error {
+	for i := range m.tokens {
+		if m.tokens[i].UserID == userID && m.tokens[i].RevokedAt.IsZero() {
+			m.tokens[i].RevokedAt = at
+		}
+	}
+	return nil
+}
+
+type plainHasher struct{}
+
+func (plainHasher) Hash(p string) (string, error) { return p, nil }
+
+func (plainHasher) Compare(hash, p string) error {
+	if hash != p {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
+
+// stubTokens 令牌即 "用户:纪元:序号"，Parse 原样拆回
+type stubTokens struct {
+	n int
+}
+
+func (g *stubTokens) Generate(userID uint, _ string, epoch int64) (string, error) {
+	g.n++
+	return fmt.Sprintf("%d:%d:%d", userID, epoch, g.n), nil
+}
+
+func (g *stubTokens) Parse(token string) (*domain.TokenClaims, error) {
+	var uid uint
+	var epoch int64
+	var n int
+	if _, err := fmt.Sscanf(token, "%d:%d:%d", &uid, &epoch, &n); err != nil {
+		return nil, domain.ErrTokenInvalid
+	}
+	return &domain.TokenClaims{ID: token, UserID: uid, Epoch: epoch, ExpiresAt: time.Now().Add(time.Hour)}, nil
+}
+
+type memRevocations struct {
+	epochs  map[uint]int64
+	revoked map[string]bool
+}
+
+func newMemRevocations() *memRevocations {
+	return &memRevocations{epochs: make(map[uint]int64), revoked: make(map[string]bool)}
+}
+
+func (m *memRevocations) Epoch(_ context.Context, userID uint) (int64, error) {
+	return m.epochs[userID], nil
+}
+
+func (m *memRevocations) BumpEpoch(_ context.Context, userID uint) (int64, error) {
+	m.epochs[userID]++
+	return m.epochs[userID], nil
+}
+
+func (m *memRevocations) RevokeToken(_ context.Context, tokenID string, _ uint, _ time.Time) error {
+	m.revoked[tokenID] = true
+	return nil
+}
+
+func (m *memRevocations) IsRevoked(_ context.Context, tokenID string) (bool, error) {
+	return m.revoked[tokenID], nil
+}
+
+type nopNotifier struct{}
+
+func (nopNotifier) SessionsRevoked(context.Context, uint, string, int64) error { return nil }
+
+type memResets struct {
+	resets []domain.PasswordReset
+}
+
+func (m *memResets) Create(_ context.Context, r *domain.PasswordReset) error {
+	r.ID = uint(len(m.resets) + 1)
+	m.resets = append(m.resets, *r)
+	return nil
+}
+
+func (m *memResets) FindByHash(_ context.Context, hash string) (*domain.PasswordReset, error) {
+	for i := range m.resets {
+		if m.resets[i].TokenHash == hash {
+			r := m.resets[i]
+			return &r, nil
+		}
+	}
+	return nil, domain.ErrResetTokenInvalid
+}
+
+func (m *memResets) MarkUsed(_ context.Context, id uint, at time.Time) (bool, error) {
+	r := &m.resets[id-1]
+	if !r.UsedAt.IsZero() {
+		return false, nil
+	}
+	r.UsedAt = at
+	return true, nil
+}
+
+func (m *memResets) InvalidateUser(_ context.Context, userID uint, at time.Time) error {
+	for i := range m.resets {
+		if m.resets[i].UserID == userID && m.resets[i].UsedAt.IsZero() {
+			m.resets[i].UsedAt = at
+		}
+	}
+	return nil
+}
+
+// mailbox 记下发出的重置令牌
+type mailbox struct {
+	tokens map[string]string
+}
+
+func (m *mailbox) SendPasswordReset(_ context.Context, u *domain.User, token string, _ time.Time) error {
+	m.tokens[u.Email] = token
+	return nil
+}
+
+func newTestAuth(refresh *memRefresh, revocations *memRevocations, tokens *stubTokens) *AuthService {
+	login := newMemLogin()
+	tf := newMemTwoFactor()
+	s := NewAuthService(&memUsers{}, plainHasher{}, tokens, refresh, revocations, nopNotifier{},
+		&memResets{}, &mailbox{tokens: make(map[string]string)}, NewLoginGuard(login, login), tf, tf)
+	s.async = func(f func()) { f() }
+	return s
+}
+
+func TestRefreshRotationAndReuse(t *testing.T) {
+	ctx := context.Background()
+	refresh := &memRefresh{}
+	s := newTestAuth(refresh, newMemRevocations(), &stubTokens{})
+
+	first, err := s.Register(ctx, "alice", "password1", "", ClientInfo{IP: "10.0.0.1"})
+	if err != nil || first.RefreshToken == "" {
+		t.Fatalf("register: %+v %v", first, err)
+	}
+	second, err := s.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.UserID != first.UserID {
+		t.Fatalf("refresh did not rotate: %+v", second)
+	}
+
+	// 旧令牌再次出现：整族吊销，连刚轮换出的新令牌也失效
+	if _, err := s.Refresh(ctx, first.RefreshToken); !errors.Is(err, domain.ErrRefreshTokenReused) {
+		t.Fatalf("reuse: got %v", err)
+	}
+	if _, err := s.Refresh(ctx, second.RefreshToken); !errors.Is(err, domain.ErrRefreshTokenInvalid) {
+		t.Fatalf("after revoke: got %v", err)
+	}
+	if _, err := s.Refresh(ctx, "unknown"); !errors.Is(err, domain.ErrRefreshTokenInvalid) {
+		t.Fatalf("unknown: got %v", err)
+	}
+}
+
+func TestRefreshExpired(t *testing.T) {
+	ctx := context.Background()
+	s := newTestAuth(&memRefresh{}, newMemRevocations(), &stubTokens{})
+	res, err := s.Register(ctx, "bob", "password1", "", ClientInfo{IP: "10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return time.Now().Add(s.RefreshTTL + time.Minute) }
+	if _, err := s.Refresh(ctx, res.RefreshToken); !errors.Is(err, domain.ErrRefreshTokenInvalid) {
+		t.Fatalf("expired: got %v", err)
+	}
+}
+
+func TestLogoutAndRevokeAll(t *testing.T) {
+	ctx := context.Background()
+	refresh, revocations, tokens := &memRefresh{}, newMemRevocations(), &stubTokens{}
+	s := newTestAuth(refresh, revocations, tokens)
+	sessions := NewSessionService(tokens, revocations)
+
+	phone, err := s.Register(ctx, "carol", "password1", "", ClientInfo{IP: "10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	laptop, err := s.Login(ctx, "carol", "password1", ClientInfo{IP: "10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 注销只影响本令牌及其刷新令牌
+	claims, err := sessions.Authenticate(ctx, phone.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Logout(ctx, claims, phone.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sessions.Authenticate(ctx, phone.Token); !errors.Is(err, domain.ErrTokenRevoked) {
+		t.Fatalf("logged out token: got %v", err)
+	}
+	if _, err := s.Refresh(ctx, phone.RefreshToken); !errors.Is(err, domain.ErrRefreshTokenInvalid) {
+		t.Fatalf("logged out refresh: got %v", err)
+	}
+	if _, err := sessions.Authenticate(ctx, laptop.Token); err != nil {
+		t.Fatalf("other session: %v", err)
+	}
+
+	// 吊销全部会话后旧令牌失效，新登录不受影响
+	if err := s.RevokeAll(ctx, laptop.UserID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sessions.Authenticate(ctx, laptop.Token); !errors.Is(err, domain.ErrTokenRevoked) {
+		t.Fatalf("revoked token: got %v", err)
+	}
+	if _, err := s.Refresh(ctx, laptop.RefreshToken); !errors.Is(err, domain.ErrRefreshTokenInvalid) {
+		t.Fatalf("revoked refresh: got %v", err)
+	}
+	again, err := s.Login(ctx, "carol", "password1", ClientInfo{IP: "10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sessions.Authenticate(ctx, again.Token); err != nil {
+		t.Fatalf("new login: %v", err)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	ctx := context.Background()
+	revocations, tokens := newMemRevocations(), &stubTokens{}
+	s := newTestAuth(&memRefresh{}, revocations, tokens)
+	sessions := NewSessionService(tokens, revocations)
+
+	old, err := s.Register(ctx, "dave", "password1", "", ClientInfo{IP: "10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ChangePassword(ctx, old.UserID, "wrong", "password2", ClientInfo{IP: "10.0.0.1"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("wrong old password: got %v", err)
+	}
+	res, err := s.ChangePassword(ctx, old.UserID, "password1", "password2", ClientInfo{IP: "10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sessions.Authenticate(ctx, old.Token); !errors.Is(err, domain.ErrTokenRevoked) {
+		t.Fatalf("old session: got %v", err)
+	}
+	if _, err := sessions.Authenticate(ctx, res.Token); err != nil {
+		t.Fatalf("new session: %v", err)
+	}
+	if _, err := s.Login(ctx, "dave", "password2", ClientInfo{IP: "10.0.0.1"}); err != nil {
+		t.Fatalf("login with new password: %v", err)
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	revocations, tokens := newMemRevocations(), &stubTokens{}
+	s := newTestAuth(&memRefresh{}, revocations, tokens)
+	box := s.notifier.(*mailbox)
+
+	if _, err := s.Register(ctx, "erin", "password1", "not an email", ClientInfo{IP: "10.0.0.1"}); !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("bad email: got %v", err)
+	}
+	session, err := s.Register(ctx, "erin", "password1", "erin@example.com", ClientInfo{IP: "10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 不存在的账号同样成功返回，不发邮件
+	if err := s.RequestPasswordReset(ctx, "nobody", ClientInfo{IP: "10.0.0.1"}); err != nil || len(box.tokens) != 0 {
+		t.Fatalf("unknown user: %v %v", err, box.tokens)
+	}
+
+	if err := s.RequestPasswordReset(ctx, "erin", ClientInfo{IP: "10.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	first := box.tokens["erin@example.com"]
+	if err := s.RequestPasswordReset(ctx, "erin", ClientInfo{IP: "10.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	second := box.tokens["erin@example.com"]
+	// 新请求作废旧令牌
+	if err := s.ResetPassword(ctx, first, "password2"); !errors.Is(err, domain.ErrResetTokenInvalid) {
+		t.Fatalf("superseded token: got %v", err)
+	}
+	if err := s.ResetPassword(ctx, second, "password2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ResetPassword(ctx, second, "password3"); !errors.Is(err, domain.ErrResetTokenInvalid) {
+		t.Fatalf("reused token: got %v", err)
+	}
+	if _, err := NewSessionService(tokens, revocations).Authenticate(ctx, session.Token); !errors.Is(err, domain.ErrTokenRevoked) {
+		t.Fatalf("session after reset: got %v", err)
+	}
+	if _, err := s.Login(ctx, "erin", "password2", ClientInfo{IP: "10.0.0.1"}); err != nil {
+		t.Fatalf("login after reset: %v", err)
+	}
+
+	if err := s.RequestPasswordReset(ctx, "erin", ClientInfo{IP: "10.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return time.Now().Add(s.ResetTTL + time.Minute) }
+	if err := s.ResetPassword(ctx, box.tokens["erin@example.com"], "password3"); !errors.Is(err, domain.ErrResetTokenInvalid) {
+		t.Fatalf("expired token: got %v", err)
+	}
+}
+
+func TestPasswordResetThrottled(t *testing.T) {
+	ctx := context.Background()
+	s := newTestAuth(&memRefresh{}, newMemRevocations(), &stubTokens{})
+	s.guard.Policy.ResetAccountLimit, s.guard.Policy.ResetIPLimit = 2, 4
+	box := s.notifier.(*mailbox)
+	if _, err := s.Register(ctx, "frank", "password1", "frank@example.com", ClientInfo{IP: "10.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 按用户名限流，存在与不存在的账号额度相同
+	for _, name := range []string{"frank", "nobody"} {
+		for i := 0; i < 2; i++ {
+			if err := s.RequestPasswordReset(ctx, name, ClientInfo{IP: "10.0.0.2"}); err != nil {
+				t.Fatalf("%s request %d: %v", name, i, err)
+			}
+		}
+		var retry *RetryError
+		if err := s.RequestPasswordReset(ctx, name, ClientInfo{IP: "10.0.0.3"}); !errors.As(err, &retry) || !errors.Is(err, ErrResetThrottled) || retry.RetryAfter <= 0 {
+			t.Fatalf("%s over account limit: got %v", name, err)
+		}
+	}
+	if len(box.tokens) != 1 {
+		t.Fatalf("mails: %v", box.tokens)
+	}
+
+	// 10.0.0.2 已请求 4 次，超过 IP 额度；换个 IP 不受影响
+	if err := s.RequestPasswordReset(ctx, "grace", ClientInfo{IP: "10.0.0.2"}); !errors.Is(err, ErrResetThrottled) {
+		t.Fatalf("over ip limit: got %v", err)
+	}
+	if err := s.RequestPasswordReset(ctx, "grace", ClientInfo{IP: "10.0.0.4"}); err != nil {
+		t.Fatalf("other ip: %v", err)
+	}
+
+	// 窗口过后恢复
+	s.guard.now = func() time.Time { return time.Now().Add(s.guard.Policy.Window) }
+	if err := s.RequestPasswordReset(ctx, "frank", ClientInfo{IP: "10.0.0.2"}); err != nil {
+		t.Fatalf("after window: %v", err)
+	}
+}
@@ -0,0 +1,318 @@
+package usecase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"wsim/user/api/user/domain"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+const (
+	// TOTP 参数与主流验证器 App 的默认值一致：SHA1、6 位、30 秒
+	totpPeriod = 30
+	totpDigits = 6
+	// totpSkew 允许前后各差一个时间步，容忍客户端时钟偏差
+	totpSkew = 1
+	// maxChallengeAttempts 同一挑战最多尝试的验证码次数，用尽须重新输入密码
+	maxChallengeAttempts = 5
+	recoveryCodeCount    = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactorSetup 开启两步验证的第一步：把 URI 生成二维码给验证器 App 扫描，
+// Secret 供无法扫码时手动输入
+type TwoFactorSetup struct {
+	Secret string
+	URI    string
+}
+
+// TwoFactorStatus 两步验证状态
+type TwoFactorStatus struct {
+	Enabled           bool
+	RecoveryCodesLeft int
+}
+
+// TwoFactorStatus 当前用户是否开启两步验证及剩余恢复码
+func (s *AuthService) TwoFactorStatus(ctx context.Context, userID uint) (*TwoFactorStatus, error) {
+	tf, err := s.twoFactor.Get(ctx, userID)
+	if errors.Is(err, domain.ErrTwoFactorNotEnabled) {
+		return &TwoFactorStatus{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !tf.Enabled() {
+		return &TwoFactorStatus{}, nil
+	}
+	n, err := s.twoFactor.RecoveryCodesLeft(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &TwoFactorStatus{Enabled: true, RecoveryCodesLeft: n}, nil
+}
+
+// BeginTwoFactor 生成新的 TOTP 密钥，待 EnableTwoFactor 用首个验证码确认后才生效；
+// 重复调用会覆盖之前未确认的密钥
+func (s *AuthService) BeginTwoFactor(ctx context.Context, userID uint) (*TwoFactorSetup, error) {
+	u, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var b [20]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+	secret := totpEncoding.EncodeToString(b[:])
+	if err := s.twoFactor.SavePending(ctx, &domain.TwoFactor{UserID: u.ID, Secret: secret, CreatedAt: s.now()}); err != nil {
+		return nil, err
+	}
+	return &TwoFactorSetup{Secret: secret, URI: otpauthURI(s.Issuer, u.Username, secret)}, nil
+}
+
+// EnableTwoFactor 用验证器 App 上的首个验证码确认绑定，返回一次性恢复码（只在此时明文出现）
+func (s *AuthService) EnableTwoFactor(ctx context.Context, userID uint, code string) ([]string, error) {
+	if code == "" {
+		return nil, ErrBadRequest
+	}
+	tf, err := s.twoFactor.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tf.Enabled() {
+		return nil, domain.ErrTwoFactorEnabled
+	}
+	ok, err := s.matchCode(ctx, tf, code, false)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, domain.ErrTwoFactorCodeInvalid
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactor.Enable(ctx, userID, s.now(), hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTwoFactor 关闭两步验证，需要密码加验证码（或恢复码）
+func (s *AuthService) DisableTwoFactor(ctx context.Context, userID uint, password, code string, client ClientInfo) error {
+	if password == "" || code == "" {
+		return ErrBadRequest
+	}
+	u, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkPassword(ctx, u, password, client); err != nil {
+		return err
+	}
+	if err := s.verifySecondFactor(ctx, u, code, true, client); err != nil {
+		return err
+	}
+	return s.twoFactor.Disable(ctx, userID)
+}
+
+// RegenerateRecoveryCodes 凭验证码换一组新的恢复码，旧的全部作废
+func (s *AuthService) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string, client ClientInfo) ([]string, error) {
+	if code == "" {
+		return nil, ErrBadRequest
+	}
+	u, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifySecondFactor(ctx, u, code, false, client); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactor.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// CompleteLogin 两步登录的第二步：用 Login 返回的挑战令牌加验证码（或恢复码）换取会话。
+// 验证码错误计入登录失败，与密码错误共用防爆破限制
+func (s *AuthService) CompleteLogin(ctx context.Context, challenge, code string, client ClientInfo) (*AuthResult, error) {
+	if challenge == "" || code == "" {
+		return nil, ErrBadRequest
+	}
+	c, err := s.challenges.FindByHash(ctx, hashToken(challenge))
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if !c.UsedAt.IsZero() || !now.Before(c.ExpiresAt) || c.Attempts >= maxChallengeAttempts {
+		return nil, domain.ErrChallengeInvalid
+	}
+	u, err := s.repo.FindByID(ctx, c.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifySecondFactor(ctx, u, code, true, client); err != nil {
+		if errors.Is(err, domain.ErrTwoFactorCodeInvalid) {
+			if err := s.challenges.Fail(ctx, c.ID); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+	ok, err := s.challenges.MarkUsed(ctx, c.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, domain.ErrChallengeInvalid
+	}
+	return s.loggedIn(ctx, u, client)
+}
+
+// challenge 密码校验通过后签发挑战令牌，库里只存哈希
+func (s *AuthService) challenge(ctx context.Context, u *domain.User) (*AuthResult, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b[:])
+	now := s.now()
+	c := &domain.LoginChallenge{UserID: u.ID, TokenHash: hashToken(token), CreatedAt: now, ExpiresAt: now.Add(s.ChallengeTTL)}
+	if err := s.challenges.Create(ctx, c); err != nil {
+		return nil, err
+	}
+	return &AuthResult{UserID: u.ID, Challenge: token, ChallengeExpiresAt: c.ExpiresAt}, nil
+}
+
+// verifySecondFactor 校验已开启账号的验证码，受登录防爆破限制，错误计为一次登录失败
+func (s *AuthService) verifySecondFactor(ctx context.Context, u *domain.User, code string, allowRecovery bool, client ClientInfo) error {
+	if err := s.guard.Check(ctx, u.ID, u.Username, client); err != nil {
+		return err
+	}
+	tf, err := s.twoFactor.Get(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	if !tf.Enabled() {
+		return domain.ErrTwoFactorNotEnabled
+	}
+	ok, err := s.matchCode(ctx, tf, code, allowRecovery)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.guard.Failed(ctx, u.ID, u.Username, client)
+		return domain.ErrTwoFactorCodeInvalid
+	}
+	return nil
+}
+
+// matchCode 6 位数字按 TOTP 校验，每个时间步只能用一次；其余按恢复码校验
+func (s *AuthService) matchCode(ctx context.Context, tf *domain.TwoFactor, code string, allowRecovery bool) (bool, error) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if isDigits(code) && len(code) == totpDigits {
+		step, ok := matchTOTP(tf.Secret, code, s.now())
+		if !ok || step <= tf.LastStep {
+			return false, nil
+		}
+		return s.twoFactor.UseStep(ctx, tf.UserID, step)
+	}
+	if !allowRecovery {
+		return false, nil
+	}
+	ok, err := s.twoFactor.UseRecoveryCode(ctx, tf.UserID, hashToken(normalizeRecoveryCode(code)), s.now())
+	if ok {
+		hlog.CtxInfof(ctx, "user %d used a recovery code", tf.UserID)
+	}
+	return ok, err
+}
+
+// otpauthURI 验证器 App 通用的 Key URI 格式
+func otpauthURI(issuer, username, secret string) string {
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(totpPeriod))
+	return "otpauth://totp/" + url.PathEscape(issuer+":"+username) + "?" + q.Encode()
+}
+
+// totpCode RFC 6238：HOTP(secret, step)，动态截断后取 totpDigits 位
+func totpCode(secret []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	off := sum[len(sum)-1] & 0x0f
+	v := binary.BigEndian.Uint32(sum[off:off+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, v%mod)
+}
+
+// matchTOTP 在当前时间步前后 totpSkew 内查找，返回匹配的时间步
+func matchTOTP(secret, code string, now time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+	cur := now.Unix() / totpPeriod
+	for step := cur - totpSkew; step <= cur+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// newRecoveryCodes 生成恢复码，形如 3f9a1-c02be，返回明文与哈希
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	for i := 0; i < recoveryCodeCount; i++ {
+		var b [5]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return nil, nil, err
+		}
+		h := hex.EncodeToString(b[:])
+		codes = append(codes, h[:5]+"-"+h[5:])
+		hashes = append(hashes, hashToken(h))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode 忽略大小写与分隔符
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
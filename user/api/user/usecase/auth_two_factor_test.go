@@ -0,0 +1,238 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"wsim/user/api/user/domain"
+)
+
+// memTwoFactor 同时实现 TwoFactorRepository 与 LoginChallengeRepository
+type memTwoFactor struct {
+	configs    map[uint]*domain.TwoFactor
+	codes      map[uint]map[string]bool // hash -> used
+	challenges []domain.LoginChallenge
+}
+
+func newMemTwoFactor() *memTwoFactor {
+	return &memTwoFactor{configs: make(map[uint]*domain.TwoFactor), codes: make(map[uint]map[string]bool)}
+}
+
+func (m *memTwoFactor) Get(_ context.Context, userID uint) (*domain.TwoFactor, error) {
+	t, ok := m.configs[userID]
+	if !ok {
+		return nil, domain.ErrTwoFactorNotEnabled
+	}
+	cp := *t
+	return &cp, nil
+}
+
+func (m *memTwoFactor) SavePending(_ context.Context, t *domain.TwoFactor) error {
+	if old, ok := m.configs[t.UserID]; ok && old.Enabled() {
+		return domain.ErrTwoFactorEnabled
+	}
+	cp := *t
+	m.configs[t.UserID] = &cp
+	return nil
+}
+
+func (m *memTwoFactor) Enable(ctx context.Context, userID uint, at time.Time, hashes []string) error {
+	m.configs[userID].EnabledAt = at
+	return m.ReplaceRecoveryCodes(ctx, userID, hashes)
+}
+
+func (m *memTwoFactor) Disable(_ context.Context, userID uint) error {
+	delete(m.configs, userID)
+	delete(m.codes, userID)
+	return nil
+}
+
+func (m *memTwoFactor) UseStep(_ context.Context, userID uint, step int64) (bool, error) {
+	t := m.configs[userID]
+	if t.LastStep >= step {
+		return false, nil
+	}
+	t.LastStep = step
+	return true, nil
+}
+
+func (m *memTwoFactor) ReplaceRecoveryCodes(_ context.Context, userID uint, hashes []string) error {
+	m.codes[userID] = make(map[string]bool)
+	for _, h := range hashes {
+		m.codes[userID][h] = false
+	}
+	return nil
+}
+
+func (m *memTwoFactor) UseRecoveryCode(_ context.Context, userID uint, hash string, _ time.Time) (bool, error) {
+	used, ok := m.codes[userID][hash]
+	if !ok || used {
+		return false, nil
+	}
+	m.codes[userID][hash] = true
+	return true, nil
+}
+
+func (m *memTwoFactor) RecoveryCodesLeft(_ context.Context, userID uint) (int, error) {
+	n := 0
+	for _, used := range m.codes[userID] {
+		if !used {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *memTwoFactor) Create(_ context.Context, c *domain.LoginChallenge) error {
+	c.ID = uint(len(m.challenges) + 1)
+	m.challenges = append(m.challenges, *c)
+	return nil
+}
+
+func (m *memTwoFactor) FindByHash(_ context.Context, hash string) (*domain.LoginChallenge, error) {
+	for i := range m.challenges {
+		if m.challenges[i].TokenHash == hash {
+			c := m.challenges[i]
+			return &c, nil
+		}
+	}
+	return nil, domain.ErrChallengeInvalid
+}
+
+func (m *memTwoFactor) Fail(_ context.Context, id uint) error {
+	m.challenges[id-1].Attempts++
+	return nil
+}
+
+func (m *memTwoFactor) MarkUsed(_ context.Context, id uint, at time.Time) (bool, error) {
+	c := &m.challenges[id-1]
+	if !c.UsedAt.IsZero() {
+		return false, nil
+	}
+	c.UsedAt = at
+	return true, nil
+}
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 附录 B 的 SHA1 测试向量，取后 6 位
+	key := []byte("12345678901234567890")
+	for unix, want := range map[int64]string{59: "287082", 1111111109: "081804", 1234567890: "005924", 2000000000: "279037"} {
+		if got := totpCode(key, unix/totpPeriod); got != want {
+			t.Errorf("T=%d: got %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	ctx := context.Background()
+	s := newTestAuth(&memRefresh{}, newMemRevocations(), &stubTokens{})
+	clock := time.Unix(1700000000, 0)
+	s.now = func() time.Time { return clock }
+	s.guard.now = s.now
+	client := ClientInfo{IP: "10.0.0.8"}
+
+	res, err := s.Register(ctx, "hank", "password1", "", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setup, err := s.BeginTwoFactor(ctx, res.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(setup.URI)
+	if err != nil || u.Scheme != "otpauth" || u.Query().Get("secret") != setup.Secret || !strings.Contains(u.Path, "hank") {
+		t.Fatalf("uri: %s %v", setup.URI, err)
+	}
+	key, _ := totpEncoding.DecodeString(setup.Secret)
+	code := func() string { return totpCode(key, clock.Unix()/totpPeriod) }
+
+	// 未确认前登录不需要第二步
+	if r, err := s.Login(ctx, "hank", "password1", client); err != nil || r.Challenge != "" {
+		t.Fatalf("pending: %+v %v", r, err)
+	}
+	if _, err := s.EnableTwoFactor(ctx, res.UserID, "000000"); !errors.Is(err, domain.ErrTwoFactorCodeInvalid) {
+		t.Fatalf("wrong code: got %v", err)
+	}
+	recovery, err := s.EnableTwoFactor(ctx, res.UserID, code())
+	if err != nil || len(recovery) != recoveryCodeCount {
+		t.Fatalf("enable: %v %v", recovery, err)
+	}
+
+	// 密码正确只拿到挑战，不签发令牌
+	r, err := s.Login(ctx, "hank", "password1", client)
+	if err != nil || r.Challenge == "" || r.Token != "" {
+		t.Fatalf("login: %+v %v", r, err)
+	}
+	// 确认时已用过当前时间步的验证码，不能重放
+	if _, err := s.CompleteLogin(ctx, r.Challenge, code(), client); !errors.Is(err, domain.ErrTwoFactorCodeInvalid) {
+		t.Fatalf("replay: got %v", err)
+	}
+	clock = clock.Add(totpPeriod * time.Second)
+	session, err := s.CompleteLogin(ctx, r.Challenge, code(), client)
+	if err != nil || session.Token == "" {
+		t.Fatalf("complete: %+v %v", session, err)
+	}
+	if _, err := s.CompleteLogin(ctx, r.Challenge, code(), client); !errors.Is(err, domain.ErrChallengeInvalid) {
+		t.Fatalf("challenge reuse: got %v", err)
+	}
+
+	// 恢复码只能用一次，格式不敏感
+	r, _ = s.Login(ctx, "hank", "password1", client)
+	if _, err := s.CompleteLogin(ctx, r.Challenge, strings.ToUpper(recovery[0]), client); err != nil {
+		t.Fatalf("recovery code: %v", err)
+	}
+	clock = clock.Add(time.Minute)
+	r, _ = s.Login(ctx, "hank", "password1", client)
+	if _, err := s.CompleteLogin(ctx, r.Challenge, recovery[0], client); !errors.Is(err, domain.ErrTwoFactorCodeInvalid) {
+		t.Fatalf("recovery reuse: got %v", err)
+	}
+	if st, _ := s.TwoFactorStatus(ctx, res.UserID); !st.Enabled || st.RecoveryCodesLeft != recoveryCodeCount-1 {
+		t.Fatalf("status: %+v", st)
+	}
+
+	// 过期的挑战无效
+	clock = clock.Add(s.ChallengeTTL + time.Minute)
+	if _, err := s.CompleteLogin(ctx, r.Challenge, code(), client); !errors.Is(err, domain.ErrChallengeInvalid) {
+		t.Fatalf("expired: got %v", err)
+	}
+
+	if err := s.DisableTwoFactor(ctx, res.UserID, "password1", recovery[1], client); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := s.Login(ctx, "hank", "password1", client); err != nil || r.Token == "" {
+		t.Fatalf("after disable: %+v %v", r, err)
+	}
+}
+
+func TestTwoFactorChallengeAttempts(t *testing.T) {
+	ctx := context.Background()
+	s := newTestAuth(&memRefresh{}, newMemRevocations(), &stubTokens{})
+	clock := time.Unix(1700000000, 0)
+	s.now = func() time.Time { return clock }
+	s.guard.now = s.now
+	// 放宽账号限制，只看挑战自身的次数上限
+	s.guard.Policy.AccountDelayAfter = 100
+	s.guard.Policy.LockAfter = 100
+	client := ClientInfo{IP: "10.0.0.9"}
+
+	res, _ := s.Register(ctx, "ivy", "password1", "", client)
+	setup, _ := s.BeginTwoFactor(ctx, res.UserID)
+	key, _ := totpEncoding.DecodeString(setup.Secret)
+	if _, err := s.EnableTwoFactor(ctx, res.UserID, totpCode(key, clock.Unix()/totpPeriod)); err != nil {
+		t.Fatal(err)
+	}
+	clock = clock.Add(time.Minute)
+	r, _ := s.Login(ctx, "ivy", "password1", client)
+	for i := 0; i < maxChallengeAttempts; i++ {
+		if _, err := s.CompleteLogin(ctx, r.Challenge, "wrong-code", client); !errors.Is(err, domain.ErrTwoFactorCodeInvalid) {
+			t.Fatalf("attempt %d: got %v", i, err)
+		}
+	}
+	if _, err := s.CompleteLogin(ctx, r.Challenge, totpCode(key, clock.Unix()/totpPeriod), client); !errors.Is(err, domain.ErrChallengeInvalid) {
+		t.Fatalf("exhausted: got %v", err)
+	}
+}
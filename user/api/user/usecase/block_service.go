@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"context"
+
+	"wsim/user/api/user/domain"
+)
+
+// BlockNotifier 拉黑关系变更后通知网关刷新缓存
+type BlockNotifier interface {
+	BlockListChanged(ctx context.Context, userID uint) error
+}
+
+type BlockService struct {
+	users    domain.UserRepository
+	blocks   domain.BlockRepository
+	notifier BlockNotifier
+}
+
+func NewBlockService(users domain.UserRepository, blocks domain.BlockRepository, notifier BlockNotifier) *BlockService {
+	return &BlockService{users: users, blocks: blocks, notifier: notifier}
+}
+
+func (s *BlockService) Block(ctx context.Context, userID, targetID uint) error {
+	if targetID == 0 || targetID == userID {
+		return ErrBadRequest
+	}
+	if _, err := s.users.FindByID(ctx, targetID); err != nil {
+		return err
+	}
+	if err := s.blocks.Block(ctx, userID, targetID); err != nil {
+		return err
+	}
+	return s.notifier.BlockListChanged(ctx, userID)
+}
+
+func (s *BlockService) Unblock(ctx context.Context, userID, targetID uint) error {
+	if targetID == 0 {
+		return ErrBadRequest
+	}
+	if err := s.blocks.Unblock(ctx, userID, targetID); err != nil {
+		return err
+	}
+	return s.notifier.BlockListChanged(ctx, userID)
+}
+
+func (s *BlockService) List(ctx context.Context, userID uint) ([]domain.Block, error) {
+	return s.blocks.ListBlocked(ctx, userID)
+}
@@ -0,0 +1,37 @@
+package usecase
+
+import (
+	"context"
+
+	"wsim/user/api/user/domain"
+)
+
+type ContactService struct {
+	users    domain.UserRepository
+	contacts domain.ContactRepository
+}
+
+func NewContactService(users domain.UserRepository, contacts domain.ContactRepository) *ContactService {
+	return &ContactService{users: users, contacts: contacts}
+}
+
+func (s *ContactService) Add(ctx context.Context, userID, contactID uint) error {
+	if contactID == 0 || contactID == userID {
+		return ErrBadRequest
+	}
+	if _, err := s.users.FindByID(ctx, contactID); err != nil {
+		return err
+	}
+	return s.contacts.AddContact(ctx, userID, contactID)
+}
+
+func (s *ContactService) Remove(ctx context.Context, userID, contactID uint) error {
+	if contactID == 0 {
+		return ErrBadRequest
+	}
+	return s.contacts.RemoveContact(ctx, userID, contactID)
+}
+
+func (s *ContactService) List(ctx context.Context, userID uint) ([]uint, error) {
+	return s.contacts.ListContacts(ctx, userID)
+}
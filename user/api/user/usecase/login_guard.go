@@ -0,0 +1,236 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"wsim/user/api/user/domain"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+var (
+	// ErrAccountLocked 账号因连续失败被临时锁定
+	ErrAccountLocked = errors.New("account temporarily locked")
+	// ErrLoginThrottled 失败过多，需等待后再试
+	ErrLoginThrottled = errors.New("too many login attempts")
+	// ErrResetThrottled 密码重置请求过多
+	ErrResetThrottled = errors.New("too many password reset requests")
+)
+
+// RetryError 带重试时间的限流错误，Unwrap 为 ErrAccountLocked、ErrLoginThrottled 或 ErrResetThrottled
+type RetryError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("%v, retry after %s", e.Err, e.RetryAfter)
+}
+
+func (e *RetryError) Unwrap() error { return e.Err }
+
+// LoginPolicy 登录失败的限制：在 Window 滑动窗口内统计失败次数，
+// 超过 *DelayAfter 后每次尝试前须等待（从 BaseDelay 起翻倍，最多 MaxDelay），
+// 账号失败达到 LockAfter 锁定 LockFor；同一 IP 失败达到 IPLimit 后在窗口内拒绝。
+// 密码重置请求在同一窗口内按用户名最多 ResetAccountLimit 次、按 IP 最多 ResetIPLimit 次
+type LoginPolicy struct {
+	Window            time.Duration
+	AccountDelayAfter int
+	IPDelayAfter      int
+	BaseDelay         time.Duration
+	MaxDelay          time.Duration
+	LockAfter         int
+	LockFor           time.Duration
+	IPLimit           int
+	ResetAccountLimit int
+	ResetIPLimit      int
+}
+
+func DefaultLoginPolicy() LoginPolicy {
+	return LoginPolicy{
+		Window:            15 * time.Minute,
+		AccountDelayAfter: 3,
+		IPDelayAfter:      10,
+		BaseDelay:         time.Second,
+		MaxDelay:          30 * time.Second,
+		LockAfter:         10,
+		LockFor:           15 * time.Minute,
+		IPLimit:           100,
+		ResetAccountLimit: 5,
+		ResetIPLimit:      20,
+	}
+}
+
+// delay 第 n 次失败后下次尝试前的等待
+func (p LoginPolicy) delay(n, after int) time.Duration {
+	if n < after {
+		return 0
+	}
+	d := p.BaseDelay
+	for i := after; i < n && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d
+}
+
+// LoginGuard 登录防爆破：按账号与 IP 限制失败尝试，并记录审计。
+// 被限制的尝试不校验密码，省下 bcrypt 的开销
+type LoginGuard struct {
+	events   domain.LoginEventRepository
+	lockouts domain.LockoutRepository
+	Policy   LoginPolicy
+	now      func() time.Time
+}
+
+func NewLoginGuard(events domain.LoginEventRepository, lockouts domain.LockoutRepository) *LoginGuard {
+	return &LoginGuard{events: events, lockouts: lockouts, Policy: DefaultLoginPolicy(), now: time.Now}
+}
+
+// Check 校验密码前调用：账号锁定或需要等待时返回 *RetryError 并记审计
+func (g *LoginGuard) Check(ctx context.Context, userID uint, username string, client ClientInfo) error {
+	now := g.now()
+	lock, err := g.lockouts.Get(ctx, username)
+	if err != nil {
+		return err
+	}
+	if now.Before(lock.LockedUntil) {
+		g.record(ctx, userID, username, client, domain.LoginLocked)
+		return &RetryError{Err: ErrAccountLocked, RetryAfter: lock.LockedUntil.Sub(now)}
+	}
+	p := g.Policy
+	since := now.Add(-p.Window)
+	accountSince := since
+	if lock.ResetAt.After(accountSince) {
+		accountSince = lock.ResetAt
+	}
+	n, last, err := g.events.FailuresByUsername(ctx, username, accountSince)
+	if err != nil {
+		return err
+	}
+	if wait := last.Add(p.delay(n, p.AccountDelayAfter)).Sub(now); n > 0 && wait > 0 {
+		g.record(ctx, userID, username, client, domain.LoginThrottled)
+		return &RetryError{Err: ErrLoginThrottled, RetryAfter: wait}
+	}
+	if client.IP == "" {
+		return nil
+	}
+	n, last, err = g.events.FailuresByIP(ctx, client.IP, since)
+	if err != nil {
+		return err
+	}
+	wait := last.Add(p.delay(n, p.IPDelayAfter)).Sub(now)
+	if n >= p.IPLimit {
+		wait = p.MaxDelay
+	}
+	if n > 0 && wait > 0 {
+		g.record(ctx, userID, username, client, domain.LoginThrottled)
+		return &RetryError{Err: ErrLoginThrottled, RetryAfter: wait}
+	}
+	return nil
+}
+
+// Failed 记录一次密码错误；账号失败达到阈值时锁定
+func (g *LoginGuard) Failed(ctx context.Context, userID uint, username string, client ClientInfo) {
+	g.record(ctx, userID, username, client, domain.LoginFailed)
+	now := g.now()
+	lock, err := g.lockouts.Get(ctx, username)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "load lockout for %q failed: %v", username, err)
+		return
+	}
+	since := now.Add(-g.Policy.Window)
+	if lock.ResetAt.After(since) {
+		since = lock.ResetAt
+	}
+	n, _, err := g.events.FailuresByUsername(ctx, username, since)
+	if err != nil {
+		hlog.CtxErrorf(ctx, "count login failures for %q failed: %v", username, err)
+		return
+	}
+	if n >= g.Policy.LockAfter {
+		if err := g.lockouts.Lock(ctx, username, now.Add(g.Policy.LockFor)); err != nil {
+			hlog.CtxErrorf(ctx, "lock account %q failed: %v", username, err)
+			return
+		}
+		hlog.CtxWarnf(ctx, "account %q locked after %d failed logins, last from %s", username, n, client.IP)
+	}
+}
+
+// Succeeded 记录登录成功并清零账号的失败计数
+func (g *LoginGuard) Succeeded(ctx context.Context, userID uint, username string, client ClientInfo) {
+	g.record(ctx, userID, username, client, domain.LoginSucceeded)
+	if err := g.lockouts.Reset(ctx, username, g.now()); err != nil {
+		hlog.CtxErrorf(ctx, "reset login failures for %q failed: %v", username, err)
+	}
+}
+
+// ResetRequested 密码重置请求前调用：用户名或 IP 在窗口内请求过多时返回 *RetryError，
+// 否则记一次请求。不查账号，存在与否限额相同
+func (g *LoginGuard) ResetRequested(ctx context.Context, username string, client ClientInfo) error {
+	now := g.now()
+	since := now.Add(-g.Policy.Window)
+	n, last, err := g.events.ResetRequestsByUsername(ctx, username, since)
+	if err != nil {
+		return err
+	}
+	over := n >= g.Policy.ResetAccountLimit
+	if !over && client.IP != "" {
+		if n, last, err = g.events.ResetRequestsByIP(ctx, client.IP, since); err != nil {
+			return err
+		}
+		over = n >= g.Policy.ResetIPLimit
+	}
+	if over {
+		// 最近一次请求滑出窗口时必然恢复额度
+		return &RetryError{Err: ErrResetThrottled, RetryAfter: last.Add(g.Policy.Window).Sub(now)}
+	}
+	g.record(ctx, 0, username, client, domain.ResetRequested)
+	return nil
+}
+
+// Unlock 解除锁定并清零失败计数
+func (g *LoginGuard) Unlock(ctx context.Context, username string) error {
+	return g.lockouts.Reset(ctx, username, g.now())
+}
+
+// Events 审计记录，limit 缺省 50、最多 200
+func (g *LoginGuard) Events(ctx context.Context, f domain.LoginEventFilter) ([]domain.LoginEvent, error) {
+	if f.Limit <= 0 || f.Limit > 200 {
+		f.Limit = 50
+	}
+	return g.events.List(ctx, f)
+}
+
+// record 审计写入失败不影响登录流程本身
+func (g *LoginGuard) record(ctx context.Context, userID uint, username string, client ClientInfo, result domain.LoginResult) {
+	e := &domain.LoginEvent{
+		UserID:    userID,
+		Username:  username,
+		IP:        truncate(client.IP, 45),
+		UserAgent: truncate(client.UserAgent, 255),
+		Device:    truncate(client.Device, 64),
+		Result:    result,
+		CreatedAt: g.now(),
+	}
+	if err := g.events.Record(ctx, e); err != nil {
+		hlog.CtxErrorf(ctx, "record login event failed: %v", err)
+	}
+}
+
+// truncate 按字节截断到 n 以内，不切断 UTF-8 字符
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
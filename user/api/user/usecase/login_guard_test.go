@@ -0,0 +1,234 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"wsim/user/api/user/domain"
+)
+
+// memLogin 同时实现审计与锁定仓储
+type memLogin struct {
+	events   []domain.LoginEvent
+	lockouts map[string]*domain.Lockout
+}
+
+func newMemLogin() *memLogin {
+	return &memLogin{lockouts: make(map[string]*domain.Lockout)}
+}
+
+func (m *memLogin) Record(_ context.Context, e *domain.LoginEvent) error {
+	e.ID = uint(len(m.events) + 1)
+	m.events = append(m.events, *e)
+	return nil
+}
+
+func (m *memLogin) count(result domain.LoginResult, match func(*domain.LoginEvent) bool, since time.Time) (int, time.Time, error) {
+	n, last := 0, time.Time{}
+	for i := range m.events {
+		e := &m.events[i]
+		if e.Result == result && e.CreatedAt.After(since) && match(e) {
+			n++
+			if e.CreatedAt.After(last) {
+				last = e.CreatedAt
+			}
+		}
+	}
+	return n, last, nil
+}
+
+func (m *memLogin) FailuresByUsername(_ context.Context, username string, since time.Time) (int, time.Time, error) {
+	return m.count(domain.LoginFailed, func(e *domain.LoginEvent) bool { return e.Username == username }, since)
+}
+
+func (m *memLogin) FailuresByIP(_ context.Context, ip string, since time.Time) (int, time.Time, error) {
+	return m.count(domain.LoginFailed, func(e *domain.LoginEvent) bool { return e.IP == ip }, since)
+}
+
+func (m *memLogin) ResetRequestsByUsername(_ context.Context, username string, since time.Time) (int, time.Time, error) {
+	return m.count(domain.ResetRequested, func(e *domain.LoginEvent) bool { return e.Username == username }, since)
+}
+
+func (m *memLogin) ResetRequestsByIP(_ context.Context, ip string, since time.Time) (int, time.Time, error) {
+	return m.count(domain.ResetRequested, func(e *domain.LoginEvent) bool { return e.IP == ip }, since)
+}
+
+func (m *memLogin) List(_ context.Context, f domain.LoginEventFilter) ([]domain.LoginEvent, error) {
+	var out []domain.LoginEvent
+	for i := len(m.events) - 1; i >= 0 && len(out) < f.Limit; i-- {
+		e := m.events[i]
+		if (f.UserID == 0 || e.UserID == f.UserID) && (f.Username == "" || e.Username == f.Username) && (f.IP == "" || e.IP == f.IP) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (m *memLogin) Get(_ context.Context, username string) (*domain.Lockout, error) {
+	if l, ok := m.lockouts[username]; ok {
+		cp := *l
+		return &cp, nil
+	}
+	return &domain.Lockout{Username: username}, nil
+}
+
+func (m *memLogin) Lock(_ context.Context, username string, until time.Time) error {
+	l, _ := m.Get(context.Background(), username)
+	l.LockedUntil = until
+	m.lockouts[username] = l
+	return nil
+}
+
+func (m *memLogin) Reset(_ context.Context, username string, at time.Time) error {
+	m.lockouts[username] = &domain.Lockout{Username: username, ResetAt: at}
+	return nil
+}
+
+func TestLoginGuardDelayAndLockout(t *testing.T) {
+	ctx := context.Background()
+	s := newTestAuth(&memRefresh{}, newMemRevocations(), &stubTokens{})
+	clock := time.Now()
+	s.guard.now = func() time.Time { return clock }
+	p := s.guard.Policy
+	if _, err := s.Register(ctx, "frank", "password1", "", ClientInfo{IP: "10.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	// 注册即登录会清零计数，之后的失败须晚于清零时刻
+	clock = clock.Add(time.Second)
+	client := ClientInfo{IP: "10.0.0.2"}
+
+	var retry *RetryError
+	for i := 1; i <= p.LockAfter; i++ {
+		if _, err := s.Login(ctx, "frank", "wrong", client); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("attempt %d: got %v", i, err)
+		}
+		// 超过阈值后紧接着重试会被要求等待，且不校验密码
+		if i >= p.AccountDelayAfter && i < p.LockAfter {
+			if _, err := s.Login(ctx, "frank", "password1", client); !errors.As(err, &retry) || !errors.Is(err, ErrLoginThrottled) {
+				t.Fatalf("attempt %d: expected throttle, got %v", i, err)
+			}
+			if want := p.delay(i, p.AccountDelayAfter); retry.RetryAfter != want {
+				t.Fatalf("attempt %d: retry after %s, want %s", i, retry.RetryAfter, want)
+			}
+			clock = clock.Add(retry.RetryAfter)
+		}
+	}
+
+	// 达到锁定阈值：正确密码也被拒绝，直到锁定期结束或管理员解锁
+	if _, err := s.Login(ctx, "frank", "password1", client); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("locked: got %v", err)
+	}
+	u, _ := s.repo.FindByUsername(ctx, "frank")
+	if err := s.Unlock(ctx, u.ID); err != nil {
+		t.Fatal(err)
+	}
+	// 解锁只清账号计数；该 IP 的失败仍要求短暂等待
+	clock = clock.Add(p.MaxDelay)
+	if _, err := s.Login(ctx, "frank", "password1", client); err != nil {
+		t.Fatalf("after unlock: %v", err)
+	}
+
+	events, err := s.LoginEvents(ctx, domain.LoginEventFilter{Username: "frank"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[domain.LoginResult]int{}
+	for _, e := range events {
+		counts[e.Result]++
+	}
+	if counts[domain.LoginFailed] != p.LockAfter || counts[domain.LoginLocked] != 1 || counts[domain.LoginThrottled] == 0 {
+		t.Fatalf("audit: %v", counts)
+	}
+}
+
+func TestLoginGuardPerIP(t *testing.T) {
+	ctx := context.Background()
+	s := newTestAuth(&memRefresh{}, newMemRevocations(), &stubTokens{})
+	clock := time.Now()
+	s.guard.now = func() time.Time { return clock }
+	s.guard.Policy.IPLimit = 5
+	s.guard.Policy.IPDelayAfter = 5
+
+	// 同一 IP 撞不同账号：账号各自只失败一次，按 IP 限制
+	client := ClientInfo{IP: "10.0.0.3"}
+	for i := 0; i < 5; i++ {
+		clock = clock.Add(time.Minute)
+		if _, err := s.Login(ctx, "user"+string(rune('a'+i)), "x", client); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("attempt %d: got %v", i, err)
+		}
+	}
+	if _, err := s.Login(ctx, "userz", "x", client); !errors.Is(err, ErrLoginThrottled) {
+		t.Fatalf("ip limit: got %v", err)
+	}
+	if _, err := s.Login(ctx, "userz", "x", ClientInfo{IP: "10.0.0.4"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("other ip: got %v", err)
+	}
+	// 窗口滑过后恢复
+	clock = clock.Add(s.guard.Policy.Window)
+	if _, err := s.Login(ctx, "userz", "x", client); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("after window: got %v", err)
+	}
+}
+
+func TestPasswordConfirmationThrottled(t *testing.T) {
+	ctx := context.Background()
+	s := newTestAuth(&memRefresh{}, newMemRevocations(), &stubTokens{})
+	clock := time.Now()
+	s.guard.now = func() time.Time { return clock }
+	res, err := s.Register(ctx, "jack", "password1", "", ClientInfo{IP: "10.0.0.10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock = clock.Add(time.Second)
+	client := ClientInfo{IP: "10.0.0.11"}
+
+	// 持有访问令牌也不能借改密码、关闭两步验证无限猜密码
+	for i := 0; i < s.guard.Policy.AccountDelayAfter; i++ {
+		if _, err := s.ChangePassword(ctx, res.UserID, "wrong", "password2", client); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("attempt %d: got %v", i, err)
+		}
+	}
+	if _, err := s.ChangePassword(ctx, res.UserID, "password1", "password2", client); !errors.Is(err, ErrLoginThrottled) {
+		t.Fatalf("change password: got %v", err)
+	}
+	if err := s.DisableTwoFactor(ctx, res.UserID, "password1", "123456", client); !errors.Is(err, ErrLoginThrottled) {
+		t.Fatalf("disable two-factor: got %v", err)
+	}
+	if _, err := s.Login(ctx, "jack", "password1", client); !errors.Is(err, ErrLoginThrottled) {
+		t.Fatalf("login: got %v", err)
+	}
+}
+
+func TestLoginHistory(t *testing.T) {
+	ctx := context.Background()
+	s := newTestAuth(&memRefresh{}, newMemRevocations(), &stubTokens{})
+	res, err := s.Register(ctx, "gina", "password1", "", ClientInfo{IP: "10.0.0.5", Device: "laptop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Login(ctx, "gina", "wrong", ClientInfo{IP: "10.0.0.6"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("wrong password: got %v", err)
+	}
+	phone := ClientInfo{IP: "10.0.0.7", UserAgent: "wsim-ios/1.0", Device: "phone"}
+	if _, err := s.Login(ctx, "gina", "password1", phone); err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := s.repo.FindByID(ctx, res.UserID)
+	if u.LastLoginIP != phone.IP || u.LastLoginAt.IsZero() {
+		t.Fatalf("last login: %v %q", u.LastLoginAt, u.LastLoginIP)
+	}
+	events, err := s.LoginHistory(ctx, res.UserID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []domain.LoginResult
+	for _, e := range events {
+		results = append(results, e.Result)
+	}
+	if len(events) != 3 || events[0].Device != "phone" || events[0].UserAgent != phone.UserAgent {
+		t.Fatalf("history: %v %+v", results, events)
+	}
+}
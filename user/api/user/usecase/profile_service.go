@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"wsim/user/api/user/domain"
+)
+
+// 批量查询上限，避免一次拉取过多
+const maxBatchProfiles = 100
+
+// 资料字段长度上限（按字符计），与表结构保持一致
+const (
+	maxDisplayNameLen = 64
+	maxAvatarLen      = 255
+	maxBioLen         = 512
+	maxStatusTextLen  = 128
+)
+
+// ProfileNotifier 资料变更后通知网关推送给在线联系人
+type ProfileNotifier interface {
+	ProfileChanged(ctx context.Context, p *domain.Profile) error
+}
+
+type ProfileService struct {
+	profiles domain.ProfileRepository
+	notifier ProfileNotifier
+}
+
+func NewProfileService(profiles domain.ProfileRepository, notifier ProfileNotifier) *ProfileService {
+	return &ProfileService{profiles: profiles, notifier: notifier}
+}
+
+func (s *ProfileService) Get(ctx context.Context, userID uint) (*domain.Profile, error) {
+	return s.profiles.GetProfile(ctx, userID)
+}
+
+func (s *ProfileService) BatchGet(ctx context.Context, userIDs []uint) ([]domain.Profile, error) {
+	if len(userIDs) > maxBatchProfiles {
+		return nil, ErrBadRequest
+	}
+	seen := make(map[uint]struct{}, len(userIDs))
+	ids := make([]uint, 0, len(userIDs))
+	for _, id := range userIDs {
+		if _, ok := seen[id]; ok || id == 0 {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	return s.profiles.GetProfiles(ctx, ids)
+}
+
+func (s *ProfileService) Update(ctx context.Context, userID uint, patch domain.ProfilePatch) (*domain.Profile, error) {
+	if !trimAndCheck(patch.DisplayName, maxDisplayNameLen) ||
+		!trimAndCheck(patch.Avatar, maxAvatarLen) ||
+		!trimAndCheck(patch.Bio, maxBioLen) ||
+		!trimAndCheck(patch.StatusText, maxStatusTextLen) {
+		return nil, ErrBadRequest
+	}
+	p, err := s.profiles.UpdateProfile(ctx, userID, patch)
+	if err != nil {
+		return nil, err
+	}
+	// 推送失败不影响资料更新本身，客户端下次拉取即可拿到最新值
+	_ = s.notifier.ProfileChanged(ctx, p)
+	return p, nil
+}
+
+// trimAndCheck 去掉首尾空白后校验长度；nil 视为合法
+func trimAndCheck(v *string, max int) bool {
+	if v == nil {
+		return true
+	}
+	*v = strings.TrimSpace(*v)
+	return utf8.RuneCountInString(*v) <= max
+}
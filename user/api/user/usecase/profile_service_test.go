@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"wsim/user/api/user/domain"
+)
+
+type memProfiles map[uint]*domain.Profile
+
+func (m memProfiles) GetProfile(_ context.Context, userID uint) (*domain.Profile, error) {
+	p, ok := m[userID]
+	if !ok {
+		return nil, domain.ErrUserNotFound
+	}
+	cp := *p
+	return &cp, nil
+}
+
+func (m memProfiles) GetProfiles(_ context.Context, userIDs []uint) ([]domain.Profile, error) {
+	var out []domain.Profile
+	for _, id := range userIDs {
+		if p, ok := m[id]; ok {
+			out = append(out, *p)
+		}
+	}
+	return out, nil
+}
+
+func (m memProfiles) UpdateProfile(ctx context.Context, userID uint, patch domain.ProfilePatch) (*domain.Profile, error) {
+	p, ok := m[userID]
+	if !ok {
+		return nil, domain.ErrUserNotFound
+	}
+	for dst, v := range map[*string]*string{&p.DisplayName: patch.DisplayName, &p.Avatar: patch.Avatar, &p.Bio: patch.Bio, &p.StatusText: patch.StatusText} {
+		if v != nil {
+			*dst = *v
+		}
+	}
+	return m.GetProfile(ctx, userID)
+}
+
+// profileLog 记下推送过的资料变更
+type profileLog []domain.Profile
+
+func (l *profileLog) ProfileChanged(_ context.Context, p *domain.Profile) error {
+	*l = append(*l, *p)
+	return nil
+}
+
+func TestProfileUpdate(t *testing.T) {
+	ctx := context.Background()
+	profiles := memProfiles{1: {UserID: 1, Username: "alice", Bio: "hi"}}
+	var pushed profileLog
+	s := NewProfileService(profiles, &pushed)
+	str := func(v string) *string { return &v }
+
+	long := strings.Repeat("名", maxDisplayNameLen+1)
+	if _, err := s.Update(ctx, 1, domain.ProfilePatch{DisplayName: &long}); !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("long name: got %v", err)
+	}
+	if len(pushed) != 0 {
+		t.Fatal("rejected update pushed")
+	}
+	// 长度按字符计；首尾空白去掉，未传的字段保持不变
+	p, err := s.Update(ctx, 1, domain.ProfilePatch{DisplayName: str("  " + long[len("名"):] + "  "), StatusText: str("")})
+	if err != nil || p.DisplayName != long[len("名"):] || p.Bio != "hi" || p.StatusText != "" {
+		t.Fatalf("update: %+v %v", p, err)
+	}
+	if len(pushed) != 1 || pushed[0].DisplayName != p.DisplayName {
+		t.Fatalf("pushed: %+v", pushed)
+	}
+	got, err := s.Get(ctx, 1)
+	if err != nil || got.DisplayName != p.DisplayName {
+		t.Fatalf("get: %+v %v", got, err)
+	}
+	if _, err := s.Get(ctx, 2); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Fatalf("unknown user: got %v", err)
+	}
+}
+
+func TestProfileBatchGet(t *testing.T) {
+	ctx := context.Background()
+	s := NewProfileService(memProfiles{1: {UserID: 1}, 2: {UserID: 2}}, &profileLog{})
+
+	// 去重、忽略 0 与不存在的 ID
+	ps, err := s.BatchGet(ctx, []uint{2, 0, 1, 2, 9})
+	if err != nil || len(ps) != 2 || ps[0].UserID != 2 || ps[1].UserID != 1 {
+		t.Fatalf("batch: %+v %v", ps, err)
+	}
+	if _, err := s.BatchGet(ctx, make([]uint, maxBatchProfiles+1)); !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("over limit: got %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package usecase
+
+import (
+	"context"
+
+	"wsim/user/api/user/domain"
+)
+
+// TokenVerifier 校验访问令牌的签名与有效期
+type TokenVerifier interface {
+	Parse(token string) (*domain.TokenClaims, error)
+}
+
+// KeyPublisher 提供验签公钥，供 JWKS 接口发布
+type KeyPublisher interface {
+	PublicKeys() []domain.PublicKey
+}
+
+// SessionNotifier 会话被吊销时通知网关断开对应连接。
+// tokenID 非空表示注销单个令牌；否则 epoch 之前的令牌全部失效
+type SessionNotifier interface {
+	SessionsRevoked(ctx context.Context, userID uint, tokenID string, epoch int64) error
+}
+
+// SessionService 校验访问令牌是否仍然有效，供 HTTP 接口与网关共用
+type SessionService struct {
+	tokens      TokenVerifier
+	revocations domain.RevocationRepository
+}
+
+func NewSessionService(tokens TokenVerifier, revocations domain.RevocationRepository) *SessionService {
+	return &SessionService{tokens: tokens, revocations: revocations}
+}
+
+// Authenticate 校验令牌并检查吊销状态，返回令牌携带的身份
+func (s *SessionService) Authenticate(ctx context.Context, token string) (*domain.TokenClaims, error) {
+	if token == "" {
+		return nil, domain.ErrTokenInvalid
+	}
+	c, err := s.tokens.Parse(token)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Check(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Check 已解析过的令牌是否被注销或随全部会话一起吊销
+func (s *SessionService) Check(ctx context.Context, c *domain.TokenClaims) error {
+	revoked, err := s.revocations.IsRevoked(ctx, c.ID)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return domain.ErrTokenRevoked
+	}
+	epoch, err := s.revocations.Epoch(ctx, c.UserID)
+	if err != nil {
+		return err
+	}
+	if c.Epoch < epoch {
+		return domain.ErrTokenRevoked
+	}
+	return nil
+}
@@ -1,10 +1,32 @@
 package routes
 
 import (
+	"context"
 	"log"
+	"time"
 
 	"wsim/pkg/postgresql"
+	"wsim/user/api/identity"
+	mediahandler "wsim/user/api/media/handler"
+	"wsim/user/api/media/infra/blob"
+	"wsim/user/api/media/infra/imaging"
+	mediarepo "wsim/user/api/media/infra/repository"
+	"wsim/user/api/media/infra/scanner"
+	mediausecase "wsim/user/api/media/usecase"
+	messagehandler "wsim/user/api/message/handler"
+	messagerepo "wsim/user/api/message/infra/repository"
+	messageusecase "wsim/user/api/message/usecase"
+	presencehandler "wsim/user/api/presence/handler"
+	presenceevent "wsim/user/api/presence/infra/event"
+	presencerepo "wsim/user/api/presence/infra/repository"
+	presenceusecase "wsim/user/api/presence/usecase"
+	pushhandler "wsim/user/api/push/handler"
+	pushevent "wsim/user/api/push/infra/event"
+	pushrepo "wsim/user/api/push/infra/repository"
+	pushusecase "wsim/user/api/push/usecase"
 	"wsim/user/api/user/handler"
+	"wsim/user/api/user/infra/event"
+	"wsim/user/api/user/infra/mail"
 	"wsim/user/api/user/infra/password"
 	"wsim/user/api/user/infra/repository"
 	"wsim/user/api/user/infra/token"
@@ -14,18 +36,184 @@ import (
 )
 
 func InitRouter(h *server.Hertz) {
-	repo, err := repository.NewPostgresUserRepository(postgresql.GetDB())
+	db := postgresql.GetDB()
+	repo, err := repository.NewPostgresUserRepository(db)
 	if err != nil {
 		log.Fatalf("init user repository failed: %v", err)
 	}
+	blockRepo, err := repository.NewPostgresBlockRepository(db)
+	if err != nil {
+		log.Fatalf("init block repository failed: %v", err)
+	}
+	contactRepo, err := repository.NewPostgresContactRepository(db)
+	if err != nil {
+		log.Fatalf("init contact repository failed: %v", err)
+	}
+	presenceRepo, err := presencerepo.NewPostgresPresenceRepository(db)
+	if err != nil {
+		log.Fatalf("init presence repository failed: %v", err)
+	}
+	messageRepo, err := messagerepo.NewPostgresMessageRepository(db)
+	if err != nil {
+		log.Fatalf("init message repository failed: %v", err)
+	}
+	reactionRepo, err := messagerepo.NewPostgresReactionRepository(db)
+	if err != nil {
+		log.Fatalf("init reaction repository failed: %v", err)
+	}
+	mediaRepo, err := mediarepo.NewPostgresMediaRepository(db)
+	if err != nil {
+		log.Fatalf("init media repository failed: %v", err)
+	}
+	uploadRepo, err := mediarepo.NewPostgresUploadRepository(db)
+	if err != nil {
+		log.Fatalf("init upload repository failed: %v", err)
+	}
+	reportRepo, err := mediarepo.NewPostgresScanReportRepository(db)
+	if err != nil {
+		log.Fatalf("init scan report repository failed: %v", err)
+	}
+	refreshRepo, err := repository.NewPostgresRefreshTokenRepository(db)
+	if err != nil {
+		log.Fatalf("init refresh token repository failed: %v", err)
+	}
+	revocationRepo, err := repository.NewPostgresRevocationRepository(db)
+	if err != nil {
+		log.Fatalf("init token revocation repository failed: %v", err)
+	}
+	resetRepo, err := repository.NewPostgresPasswordResetRepository(db)
+	if err != nil {
+		log.Fatalf("init password reset repository failed: %v", err)
+	}
+	loginEventRepo, err := repository.NewPostgresLoginEventRepository(db)
+	if err != nil {
+		log.Fatalf("init login event repository failed: %v", err)
+	}
+	twoFactorRepo, err := repository.NewPostgresTwoFactorRepository(db)
+	if err != nil {
+		log.Fatalf("init two-factor repository failed: %v", err)
+	}
+	deviceRepo, err := pushrepo.NewPostgresDeviceRepository(db)
+	if err != nil {
+		log.Fatalf("init push device repository failed: %v", err)
+	}
+	preferenceRepo, err := pushrepo.NewPostgresPreferenceRepository(db)
+	if err != nil {
+		log.Fatalf("init notification preference repository failed: %v", err)
+	}
+	contentScanner, err := scanner.NewFromEnv()
+	if err != nil {
+		log.Fatalf("init content scanner failed: %v", err)
+	}
+	blobs, err := blob.NewFromEnv()
+	if err != nil {
+		log.Fatalf("init blob store failed: %v", err)
+	}
+	mailer, err := mail.NewFromEnv()
+	if err != nil {
+		log.Fatalf("init mail notifier failed: %v", err)
+	}
+	notifier := event.NewPgNotifier(db)
+	tokens, err := token.NewJWTGenerator()
+	if err != nil {
+		log.Fatalf("init token signing keys failed: %v", err)
+	}
 	authSvc := usecase.NewAuthService(
 		repo,
 		password.NewBcryptHasher(0),
-		token.NewJWTGenerator(),
+		tokens,
+		refreshRepo,
+		revocationRepo,
+		notifier,
+		resetRepo,
+		mailer,
+		usecase.NewLoginGuard(loginEventRepo, loginEventRepo),
+		twoFactorRepo,
+		twoFactorRepo,
 	)
+	sessionSvc := usecase.NewSessionService(tokens, revocationRepo)
 	authHandler := handler.NewAuthHandler(authSvc)
+	keyHandler := handler.NewKeyHandler(tokens)
+	blockHandler := handler.NewBlockHandler(usecase.NewBlockService(repo, blockRepo, notifier))
+	profileHandler := handler.NewProfileHandler(usecase.NewProfileService(repo, notifier))
+	contactHandler := handler.NewContactHandler(usecase.NewContactService(repo, contactRepo))
+	mediaAccess := mediausecase.NewAccess(mediaRepo, messageRepo)
+	mediaSvc := mediausecase.NewMediaService(mediaRepo, blobs, mediaAccess, imaging.NewProcessor(), contentScanner, reportRepo)
+	mediausecase.NewThumbnailPipeline(mediaSvc).Start(context.Background(), 2)
+	uploadSvc := mediausecase.NewUploadService(uploadRepo, blobs, mediaSvc)
+	go uploadSvc.RunGC(context.Background(), time.Hour)
+	mediaHandler := mediahandler.NewMediaHandler(mediaSvc)
+	uploadHandler := mediahandler.NewUploadHandler(uploadSvc)
+	reviewHandler := mediahandler.NewReviewHandler(mediaSvc)
+	presenceSvc := presenceusecase.NewPresenceService(presenceRepo, presenceevent.NewPgNotifier(db), blockRepo)
+	messageSvc := messageusecase.NewMessageService(messageRepo, messageRepo, reactionRepo, mediaAccess, presenceSvc)
+	messageHandler := messagehandler.NewMessageHandler(messageSvc)
+	deviceHandler := pushhandler.NewDeviceHandler(pushusecase.NewDeviceService(deviceRepo))
+	preferenceHandler := pushhandler.NewPreferenceHandler(
+		pushusecase.NewPreferenceService(preferenceRepo, messageSvc, pushevent.NewPgNotifier(db)),
+	)
+	presenceHandler := presencehandler.NewPresenceHandler(presenceSvc)
 
 	h.POST("/user/login", authHandler.Login)
+	h.POST("/user/login/2fa", authHandler.CompleteLogin)
 	h.POST("/user/register", authHandler.Register)
-	
+	h.POST("/user/refresh", authHandler.Refresh)
+	h.POST("/user/password/forgot", authHandler.ForgotPassword)
+	h.POST("/user/password/reset", authHandler.ResetPassword)
+	h.GET("/.well-known/jwks.json", keyHandler.JWKS)
+	// 媒体直链（<img src> 等）无法带请求头，只有这里接受 ?token=
+	h.GET("/user/media/:media_id", identity.QueryTokenMiddleware(sessionSvc), mediaHandler.Download)
+
+	// 其余接口一律要求有效的访问令牌
+	authed := h.Group("", identity.Middleware(sessionSvc))
+	authed.POST("/user/logout", authHandler.Logout)
+	authed.POST("/user/password", authHandler.ChangePassword)
+	authed.GET("/user/login-history", authHandler.LoginHistory)
+	authed.GET("/user/2fa", authHandler.TwoFactorStatus)
+	authed.POST("/user/2fa/setup", authHandler.SetupTwoFactor)
+	authed.POST("/user/2fa/enable", authHandler.EnableTwoFactor)
+	authed.POST("/user/2fa/disable", authHandler.DisableTwoFactor)
+	authed.POST("/user/2fa/recovery-codes", authHandler.RegenerateRecoveryCodes)
+
+	authed.GET("/user/blocks", blockHandler.List)
+	authed.POST("/user/blocks", blockHandler.Block)
+	authed.DELETE("/user/blocks/:user_id", blockHandler.Unblock)
+
+	authed.GET("/user/profile", profileHandler.Me)
+	authed.PATCH("/user/profile", profileHandler.Update)
+	authed.GET("/user/profiles/:user_id", profileHandler.Get)
+	authed.POST("/user/profiles/batch", profileHandler.Batch)
+
+	authed.GET("/user/contacts", contactHandler.List)
+	authed.POST("/user/contacts", contactHandler.Add)
+	authed.DELETE("/user/contacts/:user_id", contactHandler.Remove)
+
+	authed.GET("/user/presence", presenceHandler.Query)
+
+	authed.GET("/user/push/devices", deviceHandler.List)
+	authed.POST("/user/push/devices", deviceHandler.Register)
+	authed.DELETE("/user/push/devices", deviceHandler.Unregister)
+	authed.GET("/user/notification-settings", preferenceHandler.List)
+	authed.PATCH("/user/notification-settings", preferenceHandler.UpdateGlobal)
+	authed.PATCH("/user/conversations/:conversation_id/notification-settings", preferenceHandler.UpdateConversation)
+
+	authed.GET("/user/conversations", messageHandler.Conversations)
+	authed.GET("/user/conversations/:conversation_id/messages", messageHandler.History)
+	authed.GET("/user/conversations/:conversation_id/threads/:root_id", messageHandler.Thread)
+
+	authed.POST("/user/media", mediaHandler.Upload)
+	authed.GET("/user/media/:media_id/info", mediaHandler.Info)
+	authed.POST("/user/media/uploads", uploadHandler.Init)
+	authed.GET("/user/media/uploads/:upload_id", uploadHandler.Status)
+	authed.PUT("/user/media/uploads/:upload_id/chunks", uploadHandler.PutChunk)
+	authed.POST("/user/media/uploads/:upload_id/complete", uploadHandler.Complete)
+
+	authed.POST("/admin/users/:user_id/revoke-sessions", authHandler.RevokeSessions)
+	authed.POST("/admin/users/:user_id/unlock", authHandler.Unlock)
+	authed.GET("/admin/login-events", authHandler.LoginEvents)
+
+	authed.GET("/admin/media/reports", reviewHandler.Reports)
+	authed.GET("/admin/media/:media_id", reviewHandler.Download)
+	authed.POST("/admin/media/:media_id/release", reviewHandler.Release)
+	authed.POST("/admin/media/:media_id/reject", reviewHandler.Reject)
 }
@@ -1,22 +1,63 @@
 package main
 
 import (
+	"log"
+	"net"
+	"os"
+	"strings"
+
 	"wsim/pkg/postgresql"
 	"wsim/user/routes"
 	wsutils "wsim/utils"
 
+	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/app/server"
 	"github.com/cloudwego/hertz/pkg/common/hlog"
 )
 
+const maxRequestBodySize = 64 << 20
+
 func main() {
 	postgresql.InitPostgreSQL()
 	logger := wsutils.SetupLogger(hlog.LevelDebug)
 	hlog.SetLogger(logger)
 
-	h := server.New(server.WithHostPorts("0.0.0.0:9091"))
+	h := server.New(
+		server.WithHostPorts("0.0.0.0:9091"),
+		// 媒体上传走普通请求体，默认 4MB 不够
+		server.WithMaxRequestBodySize(maxRequestBodySize),
+	)
+
+	// 登录限流与审计按客户端 IP 计；只有来自受信代理的请求才读 X-Forwarded-For/X-Real-IP
+	h.SetClientIPFunc(app.ClientIPWithOption(app.ClientIPOptions{
+		RemoteIPHeaders: []string{"X-Forwarded-For", "X-Real-IP"},
+		TrustedCIDRs:    trustedProxies(),
+	}))
 
 	routes.InitRouter(h)
 
 	h.Spin()
 }
+
+// trustedProxies 环境变量 TRUSTED_PROXIES：逗号分隔的 CIDR 或 IP；未配置时不信任任何代理，直接取连接地址
+func trustedProxies() []*net.IPNet {
+	var out []*net.IPNet
+	for _, v := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		if !strings.Contains(v, "/") {
+			if ip := net.ParseIP(v); ip != nil && ip.To4() != nil {
+				v += "/32"
+			} else {
+				v += "/128"
+			}
+		}
+		_, cidr, err := net.ParseCIDR(v)
+		if err != nil {
+			log.Fatalf("invalid TRUSTED_PROXIES entry %q: %v", v, err)
+		}
+		out = append(out, cidr)
+	}
+	return out
+}